@@ -0,0 +1,46 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/filesystem"
+)
+
+// RunColumn calls the function of a custom file list column as fn(file),
+// where file is an entry like those of cute.files(), and returns the value to
+// show: a string or a number, or an empty string for nil. Like previewers,
+// the function may use the cute module, but the changes it makes are not
+// applied and cute.run raises an error.
+func RunColumn(env Environment, fn *lua.LFunction, fi filesystem.FileInfo) (string, error) {
+	if env.Config == nil || env.Config.L == nil || fn == nil {
+		return "", fmt.Errorf("lua column: configuration not available")
+	}
+	L := env.Config.L
+
+	call := &luaCall{env: env, discarded: true}
+	defer beginLuaCall(L, call)()
+
+	L.Push(fn)
+	L.Push(fileInfoTable(L, fi))
+	if err := L.PCall(1, 1, nil); err != nil {
+		// Logged once per file, where the traceback is just noise.
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) && apiErr.Object != nil {
+			err = errors.New(apiErr.Object.String())
+		}
+		return "", luaInterrupted(env, err)
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	switch v := ret.(type) {
+	case *lua.LNilType:
+		return "", nil
+	case lua.LString, lua.LNumber:
+		return v.String(), nil
+	}
+	return "", fmt.Errorf("lua column: expected a string or a number, got %s", ret.Type())
+}
@@ -2,16 +2,19 @@ package command
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	lua "github.com/yuin/gopher-lua"
 
 	"cute/config"
+	"cute/filesystem"
 )
 
 // SelectedEntry describes the currently selected file or directory in the UI.
@@ -34,6 +37,11 @@ type Environment struct {
 	Config *config.RuntimeConfig
 	// Selected is the currently selected file or directory, if any.
 	Selected *SelectedEntry
+	// Files are the entries shown in the file list, in order.
+	Files []filesystem.FileInfo
+	// Context, when set, bounds the Lua code a command runs: it is stopped
+	// with an error once the context is done.
+	Context context.Context
 }
 
 // Result captures the outcome of executing a command.
@@ -52,6 +60,85 @@ type Result struct {
 	OpenHelp bool
 	// Quit indicates that the application should exit.
 	Quit bool
+	// Reload indicates that config.lua should be loaded again.
+	Reload bool
+	// ConfigErrors indicates that the UI should list the problems found in
+	// config.lua.
+	ConfigErrors bool
+	// Theme, when non-empty, is the name of the theme to switch to.
+	Theme string
+	// OpenThemePicker indicates that the UI should let the user pick a theme.
+	OpenThemePicker bool
+	// Filter, when non-nil, replaces the file list filter.
+	Filter *string
+	// Notification is a message to show in the status bar.
+	Notification string
+	// Prompt, when non-nil, asks the user for input; see AnswerPrompt.
+	Prompt *Prompt
+	// FileOps lists the file operations the command performed.
+	FileOps []FileOp
+	// Jobs are the processes the command spawned, for the UI to start.
+	Jobs []*Job
+}
+
+// FileOp describes a completed file operation, successful or not.
+type FileOp struct {
+	// Name is the operation, e.g. "rm", "mv" or "chmod".
+	Name string
+	// Paths are the files it was given, resolved against the cwd.
+	Paths []string
+	// Err is the error the operation failed with, if any.
+	Err error
+}
+
+// merge applies the changes of a nested command, run through cute.run, on
+// top of r.
+func (r *Result) merge(o Result) {
+	if o.Output != "" {
+		if r.Output != "" && !strings.HasSuffix(r.Output, "\n") {
+			r.Output += "\n"
+		}
+		r.Output += o.Output
+	}
+	if o.Cwd != "" {
+		r.Cwd = o.Cwd
+	}
+	r.Refresh = r.Refresh || o.Refresh
+	if o.ViewMode != "" {
+		r.ViewMode = o.ViewMode
+	}
+	r.OpenHelp = r.OpenHelp || o.OpenHelp
+	r.Quit = r.Quit || o.Quit
+	r.Reload = r.Reload || o.Reload
+	r.ConfigErrors = r.ConfigErrors || o.ConfigErrors
+	if o.Theme != "" {
+		r.Theme = o.Theme
+	}
+	r.OpenThemePicker = r.OpenThemePicker || o.OpenThemePicker
+	if o.Filter != nil {
+		r.Filter = o.Filter
+	}
+	if o.Notification != "" {
+		r.Notification = o.Notification
+	}
+	if o.Prompt != nil {
+		r.Prompt = o.Prompt
+	}
+	r.FileOps = append(r.FileOps, o.FileOps...)
+	r.Jobs = append(r.Jobs, o.Jobs...)
+}
+
+// Prompt is a question asked by a Lua command through cute.prompt. The UI
+// shows Message with an input prefilled with Default, then passes the answer
+// to AnswerPrompt.
+type Prompt struct {
+	Message string
+	Default string
+
+	// callback is the Lua function that receives the answer, and config
+	// the configuration whose Lua state it belongs to.
+	callback *lua.LFunction
+	config   *config.RuntimeConfig
 }
 
 // Execute parses and executes a single command line within the given
@@ -85,22 +172,19 @@ func Execute(env Environment, input string) (Result, error) {
 		return CmdViewModeDescription(name), nil
 	case "help":
 		return Result{OpenHelp: true}, nil
-	case "touch":
-		return cmdTouch(env, args)
-	case "mkdir":
-		return cmdMkdir(env, args, false)
-	case "mkcd":
-		return cmdMkcd(env, args)
-	case "rm":
-		return cmdRm(env, args)
-	case "mv":
-		return cmdMv(env, args)
-	case "cp":
-		return cmdCp(env, args)
-	case "ln":
-		return cmdLn(env, args)
+	case "touch", "mkdir", "mkcd", "rm", "mv", "cp", "ln":
+		return fileOp(env, name, args)
 	case "quit", "q":
 		return Result{Quit: true}, nil
+	case "reload":
+		return Result{Reload: true}, nil
+	case "config-errors":
+		return Result{ConfigErrors: true}, nil
+	case "theme":
+		if len(args) == 0 {
+			return Result{OpenThemePicker: true}, nil
+		}
+		return Result{Theme: args[0]}, nil
 	default:
 		// Try Lua-defined commands from the runtime configuration.
 		if env.Config != nil {
@@ -115,6 +199,67 @@ func Execute(env Environment, input string) (Result, error) {
 	}
 }
 
+// builtinCommands are the names of the commands Execute runs itself.
+var builtinCommands = []string{
+	"cd", "ll", "ls", "ld", "lf", "help", "touch", "mkdir", "mkcd", "rm", "mv",
+	"cp", "ln", "quit", "q", "reload", "config-errors", "theme",
+}
+
+// RunsLua reports whether Execute runs the command line with a Lua command
+// of the configuration.
+func RunsLua(env Environment, input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] == "sh" || slices.Contains(builtinCommands, fields[0]) {
+		return false
+	}
+	return env.Config.Command(fields[0]) != nil
+}
+
+// RunsShell reports whether Execute runs the command line with the shell,
+// either through "sh" or because it names no other command.
+func RunsShell(env Environment, input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	if fields[0] == "sh" {
+		return true
+	}
+	return !slices.Contains(builtinCommands, fields[0]) && env.Config.Command(fields[0]) == nil
+}
+
+// fileOp runs one of the built-in commands that change files and records it
+// in the result, so the on_file_op hook can be told about it.
+func fileOp(env Environment, name string, args []string) (Result, error) {
+	var (
+		res Result
+		err error
+	)
+	switch name {
+	case "touch":
+		res, err = cmdTouch(env, args)
+	case "mkdir":
+		res, err = cmdMkdir(env, args, false)
+	case "mkcd":
+		res, err = cmdMkcd(env, args)
+	case "rm":
+		res, err = cmdRm(env, args)
+	case "mv":
+		res, err = cmdMv(env, args)
+	case "cp":
+		res, err = cmdCp(env, args)
+	case "ln":
+		res, err = cmdLn(env, args)
+	}
+
+	op := FileOp{Name: name, Err: err}
+	for _, a := range args {
+		op.Paths = append(op.Paths, expandPath(a, env.Cwd))
+	}
+	res.FileOps = append(res.FileOps, op)
+	return res, err
+}
+
 // cmdCd implements "cd <directory>" semantics without changing the process-wide
 // working directory. Instead, it validates and returns the new directory path.
 func cmdCd(env Environment, args []string) (Result, error) {
@@ -335,22 +480,38 @@ func cmdLn(env Environment, args []string) (Result, error) {
 //   - args is an array-like table (1-based) containing any additional CLI
 //     arguments typed after the command name.
 //
-// The function must return either:
+// The function may return either:
 //   - a table mapping to Result fields:
 //     { output = "...", cwd = "...", refresh = true,
 //     view_mode = "ll", open_help = false, quit = false }
 //   - or a string, which is treated as Result.Output.
+//
+// It can also act through the cute module (see lua_api.go); those changes
+// are combined with the returned ones.
 func executeLuaCommand(env Environment, fn *lua.LFunction, args []string) (Result, error) {
 	if env.Config == nil || env.Config.L == nil || fn == nil {
 		return Result{}, fmt.Errorf("lua command: configuration not available")
 	}
 
 	L := env.Config.L
+	ctx := contextTable(L, env)
 
-	// Push the function to call.
-	L.Push(fn)
+	// Build args table.
+	argTable := L.NewTable()
+	for i, a := range args {
+		argTable.RawSetInt(i+1, lua.LString(a))
+	}
+
+	res, err := callLua(env, fn, ctx, argTable)
+	if err != nil {
+		res.merge(Result{Output: err.Error()})
+	}
+	return res, err
+}
 
-	// Build ctx table.
+// contextTable builds the ctx table passed to commands and hooks, which
+// describes the current directory and selected entry.
+func contextTable(L *lua.LState, env Environment) *lua.LTable {
 	ctx := L.NewTable()
 	ctx.RawSetString("cwd", lua.LString(env.Cwd))
 	if env.Selected != nil {
@@ -359,18 +520,61 @@ func executeLuaCommand(env Environment, fn *lua.LFunction, args []string) (Resul
 		ctx.RawSetString("type", lua.LString(env.Selected.Type))
 		ctx.RawSetString("is_dir", lua.LBool(env.Selected.IsDir))
 	}
-	L.Push(ctx)
+	return ctx
+}
 
-	// Build args table.
-	argTable := L.NewTable()
-	for i, a := range args {
-		argTable.RawSetInt(i+1, lua.LString(a))
+// RunFunction calls a Lua function bound to a key with the ctx table, like a
+// command without arguments.
+func RunFunction(env Environment, fn *lua.LFunction) (Result, error) {
+	if env.Config == nil || env.Config.L == nil || fn == nil {
+		return Result{}, fmt.Errorf("lua function: configuration not available")
+	}
+
+	res, err := callLua(env, fn, contextTable(env.Config.L, env))
+	if err != nil {
+		res.merge(Result{Output: err.Error()})
 	}
-	L.Push(argTable)
+	return res, err
+}
 
-	// Call fn(ctx, args) -> 1 result.
-	if err := L.PCall(2, 1, nil); err != nil {
-		return Result{Output: err.Error()}, err
+// AnswerPrompt passes the user's answer to a prompt to the Lua function that
+// asked for it, as a string, or nil when the prompt was cancelled. The
+// function may return a result like a command does.
+func AnswerPrompt(env Environment, p *Prompt, answer string, ok bool) (Result, error) {
+	if env.Config == nil || env.Config.L == nil || p == nil || p.callback == nil {
+		return Result{}, fmt.Errorf("lua prompt: configuration not available")
+	}
+	if p.config != env.Config {
+		return Result{}, fmt.Errorf("lua prompt: the configuration was reloaded")
+	}
+
+	var arg lua.LValue = lua.LNil
+	if ok {
+		arg = lua.LString(answer)
+	}
+	res, err := callLua(env, p.callback, arg)
+	if err != nil {
+		res.merge(Result{Output: err.Error()})
+	}
+	return res, err
+}
+
+// callLua calls fn with args on behalf of a command, making the cute module
+// available to it, and returns the changes made through the module together
+// with those described by fn's return value.
+func callLua(env Environment, fn *lua.LFunction, args ...lua.LValue) (Result, error) {
+	L := env.Config.L
+
+	call := &luaCall{env: env}
+	defer beginLuaCall(L, call)()
+
+	// Call fn(args...) -> 1 result.
+	L.Push(fn)
+	for _, arg := range args {
+		L.Push(arg)
+	}
+	if err := L.PCall(len(args), 1, nil); err != nil {
+		return call.res, luaInterrupted(env, err)
 	}
 
 	ret := L.Get(-1)
@@ -378,14 +582,11 @@ func executeLuaCommand(env Environment, fn *lua.LFunction, args []string) (Resul
 
 	switch v := ret.(type) {
 	case *lua.LTable:
-		return luaTableToResult(v), nil
+		call.res.merge(luaTableToResult(v))
 	case lua.LString:
-		return Result{Output: string(v)}, nil
-	case *lua.LNilType:
-		return Result{}, nil
-	default:
-		return Result{}, nil
+		call.res.merge(Result{Output: string(v)})
 	}
+	return call.res, nil
 }
 
 // luaTableToResult converts a Lua table into a Result. Recognized keys:
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/config"
+)
+
+// HasHook reports whether any function is registered for the hook, so
+// callers can skip building an environment for hooks nobody listens to.
+func HasHook(env Environment, name string) bool {
+	return env.Config != nil && env.Config.L != nil && len(env.Config.Hooks(name)) > 0
+}
+
+// RunHook calls the functions registered for the hook with the command ctx
+// table. Like commands, they can use the cute module and return a result;
+// their changes are combined. A failing function does not stop the others,
+// and its error, prefixed with the hook name, is part of the returned error.
+func RunHook(env Environment, name string) (Result, error) {
+	if !HasHook(env, name) {
+		return Result{}, nil
+	}
+	return runHook(env, name, contextTable(env.Config.L, env))
+}
+
+// RunFileOpHook calls the on_file_op functions with the ctx table and a
+// table describing op.
+func RunFileOpHook(env Environment, op FileOp) (Result, error) {
+	if !HasHook(env, config.HookFileOp) {
+		return Result{}, nil
+	}
+
+	L := env.Config.L
+	tbl := L.NewTable()
+	tbl.RawSetString("name", lua.LString(op.Name))
+	paths := L.CreateTable(len(op.Paths), 0)
+	for i, p := range op.Paths {
+		paths.RawSetInt(i+1, lua.LString(p))
+	}
+	tbl.RawSetString("paths", paths)
+	if op.Err != nil {
+		tbl.RawSetString("error", lua.LString(op.Err.Error()))
+	}
+
+	return runHook(env, config.HookFileOp, contextTable(L, env), tbl)
+}
+
+func runHook(env Environment, name string, args ...lua.LValue) (Result, error) {
+	var (
+		res  Result
+		errs []error
+	)
+	for _, fn := range env.Config.Hooks(name) {
+		r, err := callHook(env, fn, args...)
+		res.merge(r)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	}
+	return res, errors.Join(errs...)
+}
+
+// callHook calls a single hook function. Errors raised by the function are
+// reported without their Lua stack trace, which would not fit the status bar.
+func callHook(env Environment, fn *lua.LFunction, args ...lua.LValue) (Result, error) {
+	res, err := callLua(env, fn, args...)
+
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) && apiErr.Object != nil {
+		err = errors.New(apiErr.Object.String())
+	}
+	return res, err
+}
@@ -0,0 +1,309 @@
+package command
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/config"
+)
+
+// jobWaitDelay is how long a job waits for its output to close once its
+// process exited or was killed, in case a program it started still holds it.
+const jobWaitDelay = time.Second
+
+// maxJobLine bounds the length of a line of job output; longer lines are
+// split into lines of this length.
+const maxJobLine = 1 << 20
+
+// Job is a process started by a Lua command with cute.spawn. It runs in the
+// background, past the end of the command, and its output is read line by
+// line: the UI shows it in the preview pane when Output is set, and passes
+// each line, and the exit status, to the Lua callbacks of the job.
+type Job struct {
+	ID int
+	// Label is the command line of the job, for messages.
+	Label  string
+	Output bool
+
+	args []string
+	dir  string
+
+	onStdout *lua.LFunction
+	onStderr *lua.LFunction
+	onExit   *lua.LFunction
+	// config is the configuration whose Lua state the callbacks belong to.
+	config *config.RuntimeConfig
+
+	cancel context.CancelFunc
+	events chan JobEvent
+}
+
+// JobEvent is a line of output of a job, or its exit.
+type JobEvent struct {
+	Job *Job
+	// Stream is "stdout" or "stderr" for a line, or empty once the job
+	// exited.
+	Stream string
+	Line   string
+	// Code is the exit code of the job, or -1 when it could not run or was
+	// killed; Err says why.
+	Code int
+	Err  error
+}
+
+// Exited reports whether the event is the exit of the job.
+func (e JobEvent) Exited() bool {
+	return e.Stream == ""
+}
+
+// jobs are the jobs running, by ID, so cute.kill can find them.
+var (
+	jobsMu    sync.Mutex
+	jobs      = map[int]*Job{}
+	lastJobID int
+)
+
+// newJob returns a job running args in dir, not started yet.
+func newJob(args []string, label, dir string) *Job {
+	jobsMu.Lock()
+	lastJobID++
+	id := lastJobID
+	jobsMu.Unlock()
+
+	return &Job{ID: id, Label: label, Output: true, args: args, dir: dir}
+}
+
+// Start starts the process of the job. Its events are then read with Next.
+func (j *Job) Start() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, j.args[0], j.args[1:]...)
+	cmd.Dir = j.dir
+	cmd.WaitDelay = jobWaitDelay
+	setProcessGroup(cmd)
+
+	// The output is copied into pipes of our own, so Wait can run alongside
+	// the readers and close the output once WaitDelay is over.
+	stdout, stdoutW := io.Pipe()
+	stderr, stderrW := io.Pipe()
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+
+	j.cancel = cancel
+	j.events = make(chan JobEvent)
+	jobsMu.Lock()
+	jobs[j.ID] = j
+	jobsMu.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go j.readLines(&wg, "stdout", stdout)
+	go j.readLines(&wg, "stderr", stderr)
+
+	go func() {
+		err := cmd.Wait()
+		stdoutW.Close()
+		stderrW.Close()
+		wg.Wait()
+
+		jobsMu.Lock()
+		delete(jobs, j.ID)
+		jobsMu.Unlock()
+		cancel()
+
+		code := cmd.ProcessState.ExitCode()
+		var exitErr *exec.ExitError
+		switch {
+		case errors.As(err, &exitErr) && code >= 0:
+			// A non-zero exit code says it all.
+			err = nil
+		case errors.Is(err, exec.ErrWaitDelay):
+			// The job exited; a program it left running kept its output
+			// open.
+			err = nil
+		}
+		j.events <- JobEvent{Job: j, Code: code, Err: err}
+		close(j.events)
+	}()
+	return nil
+}
+
+// readLines sends the lines read from r as events of the stream, splitting
+// lines longer than maxJobLine.
+func (j *Job) readLines(wg *sync.WaitGroup, stream string, r io.Reader) {
+	defer wg.Done()
+
+	br := bufio.NewReader(r)
+	var line []byte
+	for {
+		chunk, more, err := br.ReadLine()
+		if err != nil {
+			// The output was closed, after the last line.
+			if len(line) > 0 {
+				j.events <- JobEvent{Job: j, Stream: stream, Line: string(line)}
+			}
+			return
+		}
+		line = append(line, chunk...)
+		if !more || len(line) >= maxJobLine {
+			j.events <- JobEvent{Job: j, Stream: stream, Line: string(line)}
+			line = line[:0]
+		}
+	}
+}
+
+// Next waits for the next event of a started job. It reports false once the
+// exit of the job was returned.
+func (j *Job) Next() (JobEvent, bool) {
+	ev, ok := <-j.events
+	return ev, ok
+}
+
+// Stop kills the process of the job, which then exits as usual.
+func (j *Job) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+}
+
+// StopJobs kills every running job, e.g. when the file manager exits.
+func StopJobs() {
+	jobsMu.Lock()
+	defer jobsMu.Unlock()
+	for _, j := range jobs {
+		j.Stop()
+	}
+}
+
+// HasCallback reports whether the job has a Lua callback for the event in
+// the configuration rc. The callbacks of a job started before the
+// configuration was reloaded are dropped, as their Lua state is closed; the
+// job itself keeps running.
+func (e JobEvent) HasCallback(rc *config.RuntimeConfig) bool {
+	return e.Job.config == rc && e.callback() != nil
+}
+
+func (e JobEvent) callback() *lua.LFunction {
+	switch e.Stream {
+	case "stdout":
+		return e.Job.onStdout
+	case "stderr":
+		return e.Job.onStderr
+	}
+	return e.Job.onExit
+}
+
+// RunJobCallback calls the Lua callback of the job for the event: on_stdout
+// or on_stderr with the line, or on_exit with the exit code and, when the
+// job could not run or was killed, the error. Like a command, the callback
+// may use the cute module and return a result.
+func RunJobCallback(env Environment, ev JobEvent) (Result, error) {
+	fn := ev.callback()
+	if env.Config == nil || env.Config.L == nil || fn == nil {
+		return Result{}, fmt.Errorf("lua job: configuration not available")
+	}
+	if ev.Job.config != env.Config {
+		return Result{}, fmt.Errorf("lua job: the configuration was reloaded")
+	}
+
+	if !ev.Exited() {
+		return callHook(env, fn, lua.LString(ev.Line))
+	}
+	args := []lua.LValue{lua.LNumber(ev.Code)}
+	if ev.Err != nil {
+		args = append(args, lua.LString(ev.Err.Error()))
+	}
+	return callHook(env, fn, args...)
+}
+
+// luaSpawn implements cute.spawn(cmd[, opts]): cmd is a command line for
+// the shell or a list of arguments, and opts a table of cwd, output,
+// on_stdout, on_stderr and on_exit. It returns the ID of the job, which is
+// started once the command returns.
+func luaSpawn(L *lua.LState) int {
+	call := currentLuaCall(L, "spawn")
+	if call.env.Config.Restricted {
+		L.RaiseError("cute.spawn is not available in restricted mode")
+	}
+
+	var args []string
+	label := ""
+	switch v := L.CheckAny(1).(type) {
+	case lua.LString:
+		// Shell command lines run the way the command bar runs them.
+		args = []string{"bash", "-lc", string(v)}
+		label = string(v)
+	case *lua.LTable:
+		for i := 1; i <= v.Len(); i++ {
+			args = append(args, lua.LVAsString(v.RawGetInt(i)))
+		}
+		if len(args) == 0 {
+			L.ArgError(1, "empty list of arguments")
+		}
+		label = strings.Join(args, " ")
+	default:
+		L.ArgError(1, fmt.Sprintf("expected a string or a list, got %s", v.Type()))
+	}
+
+	job := newJob(args, label, call.env.Cwd)
+	job.config = call.env.Config
+
+	if opts := L.OptTable(2, nil); opts != nil {
+		opts.ForEach(func(k, v lua.LValue) {
+			key := lua.LVAsString(k)
+			switch key {
+			case "cwd":
+				job.dir = expandPath(lua.LVAsString(v), call.env.Cwd)
+			case "output":
+				job.Output = lua.LVAsBool(v)
+			case "on_stdout", "on_stderr", "on_exit":
+				fn, ok := v.(*lua.LFunction)
+				if !ok {
+					L.ArgError(2, fmt.Sprintf("%s must be a function, got %s", key, v.Type()))
+				}
+				switch key {
+				case "on_stdout":
+					job.onStdout = fn
+				case "on_stderr":
+					job.onStderr = fn
+				default:
+					job.onExit = fn
+				}
+			default:
+				L.ArgError(2, fmt.Sprintf("unknown option %q (expected cwd, output, on_stdout, on_stderr or on_exit)", key))
+			}
+		})
+	}
+
+	call.res.Jobs = append(call.res.Jobs, job)
+	L.Push(lua.LNumber(job.ID))
+	return 1
+}
+
+// luaKill implements cute.kill(id), which stops a job. It reports whether
+// the job was running.
+func luaKill(L *lua.LState) int {
+	id := L.CheckInt(1)
+
+	jobsMu.Lock()
+	j := jobs[id]
+	jobsMu.Unlock()
+
+	if j != nil {
+		j.Stop()
+	}
+	L.Push(lua.LBool(j != nil))
+	return 1
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package command
+
+import "os/exec"
+
+// setProcessGroup does nothing where process groups are not available;
+// cancelling a job kills its process only.
+func setProcessGroup(cmd *exec.Cmd) {}
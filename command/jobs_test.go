@@ -0,0 +1,87 @@
+package command
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// runTestJob runs a shell command line as a job and returns its events,
+// stopping it after stop when that is not zero.
+func runTestJob(t *testing.T, line string, stop time.Duration) []JobEvent {
+	t.Helper()
+	j := newJob([]string{"bash", "-c", line}, line, t.TempDir())
+	if err := j.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if stop > 0 {
+		time.AfterFunc(stop, j.Stop)
+	}
+
+	done := make(chan []JobEvent)
+	go func() {
+		var events []JobEvent
+		for {
+			ev, ok := j.Next()
+			if !ok {
+				done <- events
+				return
+			}
+			events = append(events, ev)
+		}
+	}()
+
+	select {
+	case events := <-done:
+		return events
+	case <-time.After(5 * time.Second):
+		j.Stop()
+		t.Fatalf("%s: no exit after 5s", line)
+		return nil
+	}
+}
+
+func TestJobStopKillsPipeline(t *testing.T) {
+	events := runTestJob(t, "sleep 30 | cat", 100*time.Millisecond)
+	last := events[len(events)-1]
+	if !last.Exited() || last.Code != -1 || last.Err == nil {
+		t.Errorf("exit = code %d, err %v; want a killed job", last.Code, last.Err)
+	}
+}
+
+func TestJobSplitsLongLines(t *testing.T) {
+	events := runTestJob(t, "echo a; head -c 2500000 /dev/zero | tr '\\0' x; echo; echo after; echo err >&2; printf end; exit 3", 0)
+
+	var stdout []int
+	var lines []string
+	for _, ev := range events {
+		switch ev.Stream {
+		case "stdout":
+			stdout = append(stdout, len(ev.Line))
+			if len(ev.Line) < 10 {
+				lines = append(lines, ev.Line)
+			}
+		case "stderr":
+			if ev.Line != "err" {
+				t.Errorf("stderr line %q; want %q", ev.Line, "err")
+			}
+		}
+	}
+	wantLengths := []int{1, maxJobLine, maxJobLine, 2500000 - 2*maxJobLine, 5, 3}
+	if len(stdout) != len(wantLengths) {
+		t.Fatalf("stdout line lengths = %v; want %v", stdout, wantLengths)
+	}
+	for i := range wantLengths {
+		if stdout[i] != wantLengths[i] {
+			t.Fatalf("stdout line lengths = %v; want %v", stdout, wantLengths)
+		}
+	}
+	if got := strings.Join(lines, ","); got != "a,after,end" {
+		t.Errorf("short lines = %s; want a,after,end", got)
+	}
+
+	last := events[len(events)-1]
+	if !last.Exited() || last.Code != 3 || last.Err != nil {
+		t.Errorf("exit = code %d, err %v; want code 3", last.Code, last.Err)
+	}
+}
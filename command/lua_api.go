@@ -0,0 +1,303 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/config"
+	"cute/filesystem"
+	"cute/theming"
+)
+
+// The cute module lets Lua commands drive the file manager directly instead
+// of describing everything in their return value:
+//
+//	local cute = require("cute")
+//
+//	cute.cwd()                   -- current directory
+//	cute.files()                 -- entries of the file list, as filtered
+//	cute.selection()             -- selected entries
+//	cute.cd(path)                -- change directory; true or nil, err
+//	cute.set_filter(text)        -- set the file list filter ("" clears it)
+//	cute.notify(message)         -- show a message in the status bar
+//	cute.prompt(message, fn[, default])
+//	                             -- ask for input; fn(text) runs once the
+//	                             -- user answers, fn(nil) if they cancel
+//	cute.run(line)               -- run a command line as if typed in the
+//	                             -- command bar; its output or nil, err
+//	                             -- (not available in restricted mode)
+//	cute.spawn(cmd[, opts])      -- start a process in the background, from
+//	                             -- a command line or a list of arguments;
+//	                             -- returns its job ID (not available in
+//	                             -- restricted mode)
+//	cute.kill(id)                -- stop a job; true if it was running
+//	cute.refresh()               -- re-list the current directory
+//	cute.style(spec, text)       -- text styled for the terminal, with a spec
+//	                             -- like "#ff8800+bold" (see StyleFromSpec)
+//
+// Entries are tables with name, path, is_dir, type, size, permissions, user,
+// group and modified fields.
+//
+// The output of a job streams into the preview pane unless opts.output is
+// false. opts.on_stdout(line) and opts.on_stderr(line) are called for each
+// line, and opts.on_exit(code[, err]) once it exits; opts.cwd sets the
+// directory it runs in.
+//
+// Calls only have an effect while a command or hook runs; the changes they
+// make are applied together with its own result once it returns, which is
+// also when spawned jobs start. cute.style and cute.kill can be used at any
+// time.
+func init() {
+	config.RegisterModule("cute", loadLuaAPI)
+}
+
+// luaCall is a Lua function running on behalf of a command: the environment
+// it runs in and the result its calls to the cute module build up.
+type luaCall struct {
+	env   Environment
+	res   Result
+	stale bool // env.Files no longer matches the directory
+
+	// discarded marks the calls of previewers and custom columns, whose
+	// result is not applied. cute.run, which acts at once, is refused.
+	discarded bool
+}
+
+// luaCalls maps each Lua state to the call running in it.
+var (
+	luaCallsMu sync.Mutex
+	luaCalls   = map[*lua.Global]*luaCall{}
+)
+
+// beginLuaCall makes call the running call of L. The returned function
+// restores the previous one, which matters when cute.run starts another Lua
+// command from within a command.
+//
+// The outermost call bounds L by the context of its environment; calls made
+// from within it run under the same one.
+func beginLuaCall(L *lua.LState, call *luaCall) (end func()) {
+	luaCallsMu.Lock()
+	prev := luaCalls[L.G]
+	luaCalls[L.G] = call
+	luaCallsMu.Unlock()
+
+	bounded := prev == nil && call.env.Context != nil
+	if bounded {
+		L.SetContext(call.env.Context)
+	}
+
+	return func() {
+		if bounded {
+			L.RemoveContext()
+		}
+		luaCallsMu.Lock()
+		if prev != nil {
+			luaCalls[L.G] = prev
+		} else {
+			delete(luaCalls, L.G)
+		}
+		luaCallsMu.Unlock()
+	}
+}
+
+// luaInterrupted replaces the error Lua raises when it is stopped because
+// the context of env is done with one saying why, which wraps the context's
+// error. Other errors are returned as they are.
+func luaInterrupted(env Environment, err error) error {
+	if env.Context == nil {
+		return err
+	}
+	switch ctxErr := env.Context.Err(); ctxErr {
+	case context.DeadlineExceeded:
+		return fmt.Errorf("lua: stopped after running too long (%w)", ctxErr)
+	case context.Canceled:
+		return fmt.Errorf("lua: %w", ctxErr)
+	}
+	return err
+}
+
+// currentLuaCall returns the call running in L, raising a Lua error when the
+// cute module is used outside of a command.
+func currentLuaCall(L *lua.LState, fn string) *luaCall {
+	luaCallsMu.Lock()
+	call := luaCalls[L.G]
+	luaCallsMu.Unlock()
+
+	if call == nil {
+		L.RaiseError("cute.%s can only be used while a command or hook runs", fn)
+	}
+	return call
+}
+
+func loadLuaAPI(L *lua.LState) int {
+	mod := L.SetFuncs(L.NewTable(), map[string]lua.LGFunction{
+		"cwd":        luaCwd,
+		"files":      luaFiles,
+		"selection":  luaSelection,
+		"cd":         luaCd,
+		"set_filter": luaSetFilter,
+		"notify":     luaNotify,
+		"prompt":     luaPrompt,
+		"run":        luaRun,
+		"spawn":      luaSpawn,
+		"kill":       luaKill,
+		"refresh":    luaRefresh,
+		"style":      luaStyle,
+	})
+	L.Push(mod)
+	return 1
+}
+
+func luaStyle(L *lua.LState) int {
+	style := theming.StyleFromSpec(L.CheckString(1))
+	L.Push(lua.LString(style.Render(L.CheckString(2))))
+	return 1
+}
+
+func luaCwd(L *lua.LState) int {
+	call := currentLuaCall(L, "cwd")
+	L.Push(lua.LString(call.env.Cwd))
+	return 1
+}
+
+func luaFiles(L *lua.LState) int {
+	call := currentLuaCall(L, "files")
+
+	files := call.env.Files
+	if call.stale {
+		// The command changed directory or files, so list them again.
+		listed, err := filesystem.ListDirectory(call.env.Cwd)
+		if err != nil {
+			L.Push(lua.LNil)
+			L.Push(lua.LString(err.Error()))
+			return 2
+		}
+		files = listed
+	}
+
+	tbl := L.CreateTable(len(files), 0)
+	for i, fi := range files {
+		tbl.RawSetInt(i+1, fileInfoTable(L, fi))
+	}
+	L.Push(tbl)
+	return 1
+}
+
+func luaSelection(L *lua.LState) int {
+	call := currentLuaCall(L, "selection")
+
+	tbl := L.NewTable()
+	// The selection belongs to the directory the command started in.
+	if sel := call.env.Selected; sel != nil && filepath.Dir(sel.Path) == filepath.Clean(call.env.Cwd) {
+		entry := L.NewTable()
+		for _, fi := range call.env.Files {
+			if fi.Path == sel.Path {
+				entry = fileInfoTable(L, fi)
+				break
+			}
+		}
+		entry.RawSetString("name", lua.LString(sel.Name))
+		entry.RawSetString("path", lua.LString(sel.Path))
+		entry.RawSetString("is_dir", lua.LBool(sel.IsDir))
+		entry.RawSetString("type", lua.LString(sel.Type))
+		tbl.RawSetInt(1, entry)
+	}
+	L.Push(tbl)
+	return 1
+}
+
+func luaCd(L *lua.LState) int {
+	call := currentLuaCall(L, "cd")
+
+	res, err := cmdCd(call.env, []string{L.CheckString(1)})
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+
+	call.res.Cwd = res.Cwd
+	call.env.Cwd = res.Cwd
+	call.stale = true
+	L.Push(lua.LTrue)
+	return 1
+}
+
+func luaSetFilter(L *lua.LState) int {
+	call := currentLuaCall(L, "set_filter")
+
+	filter := L.OptString(1, "")
+	call.res.Filter = &filter
+	return 0
+}
+
+func luaNotify(L *lua.LState) int {
+	call := currentLuaCall(L, "notify")
+
+	call.res.Notification = L.CheckString(1)
+	return 0
+}
+
+func luaPrompt(L *lua.LState) int {
+	call := currentLuaCall(L, "prompt")
+
+	call.res.Prompt = &Prompt{
+		Message:  L.CheckString(1),
+		callback: L.CheckFunction(2),
+		Default:  L.OptString(3, ""),
+		config:   call.env.Config,
+	}
+	return 0
+}
+
+func luaRun(L *lua.LState) int {
+	call := currentLuaCall(L, "run")
+	if call.env.Config.Restricted {
+		L.RaiseError("cute.run is not available in restricted mode")
+	}
+	if call.discarded {
+		L.RaiseError("cute.run is not available in previewers and custom columns")
+	}
+
+	res, err := Execute(call.env, L.CheckString(1))
+	call.res.merge(res)
+	if res.Cwd != "" {
+		call.env.Cwd = res.Cwd
+		call.stale = true
+	}
+
+	if err != nil {
+		L.Push(lua.LNil)
+		L.Push(lua.LString(err.Error()))
+		return 2
+	}
+	L.Push(lua.LString(res.Output))
+	return 1
+}
+
+func luaRefresh(L *lua.LState) int {
+	call := currentLuaCall(L, "refresh")
+
+	call.res.Refresh = true
+	call.stale = true
+	return 0
+}
+
+// fileInfoTable converts a file list entry into a Lua table.
+func fileInfoTable(L *lua.LState, fi filesystem.FileInfo) *lua.LTable {
+	tbl := L.CreateTable(0, 9)
+	tbl.RawSetString("name", lua.LString(fi.Name))
+	tbl.RawSetString("path", lua.LString(fi.Path))
+	tbl.RawSetString("is_dir", lua.LBool(fi.IsDir))
+	tbl.RawSetString("type", lua.LString(fi.Type))
+	tbl.RawSetString("size", lua.LString(fi.Size))
+	tbl.RawSetString("permissions", lua.LString(fi.Permissions))
+	tbl.RawSetString("user", lua.LString(fi.User))
+	tbl.RawSetString("group", lua.LString(fi.Group))
+	tbl.RawSetString("modified", lua.LString(fi.DateModified))
+	return tbl
+}
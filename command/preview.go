@@ -0,0 +1,148 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/filesystem"
+)
+
+// Preview is what a Lua previewer asks the preview pane to show: Text, which
+// may be styled with cute.style, or the output of a command, given either as
+// Args to run directly or as a Shell command line.
+type Preview struct {
+	Text  string
+	Args  []string
+	Shell string
+}
+
+// IsCommand reports whether the preview is the output of a command.
+func (p Preview) IsCommand() bool {
+	return len(p.Args) > 0 || p.Shell != ""
+}
+
+// Cmd returns the command of the preview, run in dir and killed when ctx is
+// done. Shell command lines run the way the command bar runs them.
+func (p Preview) Cmd(ctx context.Context, dir string) *exec.Cmd {
+	var cmd *exec.Cmd
+	if len(p.Args) > 0 {
+		cmd = exec.CommandContext(ctx, p.Args[0], p.Args[1:]...)
+	} else {
+		cmd = exec.CommandContext(ctx, "bash", "-lc", p.Shell)
+	}
+	cmd.Dir = dir
+	return cmd
+}
+
+// PreviewArgs describes the file a Lua previewer is called for and the size
+// of the preview pane.
+type PreviewArgs struct {
+	File     filesystem.FileInfo
+	MimeType string
+	Width    int
+	Height   int
+}
+
+// RunPreviewer calls a Lua previewer as fn(ctx, file), where ctx is the
+// usual command context with the width and height of the preview pane added,
+// and file is an entry like those of cute.files() with its mime type. It
+// reports false when the previewer returns nil, leaving the file to the
+// built-in previews.
+//
+// The previewer may return a string, or a table with either a text field or
+// a command field: a command line for the shell, or a list of arguments.
+// Changes it makes through the cute module are not applied, since previews
+// are rendered whenever the selection moves, and cute.run raises an error.
+func RunPreviewer(env Environment, fn *lua.LFunction, args PreviewArgs) (Preview, bool, error) {
+	if env.Config == nil || env.Config.L == nil || fn == nil {
+		return Preview{}, false, fmt.Errorf("lua previewer: configuration not available")
+	}
+	L := env.Config.L
+
+	call := &luaCall{env: env, discarded: true}
+	defer beginLuaCall(L, call)()
+
+	ctx := contextTable(L, env)
+	ctx.RawSetString("width", lua.LNumber(args.Width))
+	ctx.RawSetString("height", lua.LNumber(args.Height))
+	file := fileInfoTable(L, args.File)
+	file.RawSetString("mime", lua.LString(args.MimeType))
+
+	L.Push(fn)
+	L.Push(ctx)
+	L.Push(file)
+	if err := L.PCall(2, 1, nil); err != nil {
+		// Shown in the preview pane, where the traceback is just noise.
+		var apiErr *lua.ApiError
+		if errors.As(err, &apiErr) && apiErr.Object != nil {
+			err = errors.New(apiErr.Object.String())
+		}
+		return Preview{}, false, luaInterrupted(env, err)
+	}
+	ret := L.Get(-1)
+	L.Pop(1)
+
+	switch v := ret.(type) {
+	case *lua.LNilType:
+		return Preview{}, false, nil
+	case lua.LBool:
+		if !v {
+			return Preview{}, false, nil
+		}
+	case lua.LString:
+		return Preview{Text: string(v)}, true, nil
+	case *lua.LTable:
+		p, ok, err := decodePreview(v)
+		if err == nil && p.IsCommand() && env.Config.Restricted {
+			return Preview{}, false, fmt.Errorf("lua previewer: commands are not available in restricted mode")
+		}
+		return p, ok, err
+	}
+	return Preview{}, false, fmt.Errorf("lua previewer: expected a string or a table, got %s", ret.Type())
+}
+
+// decodePreview reads the table returned by a previewer.
+func decodePreview(tbl *lua.LTable) (Preview, bool, error) {
+	var (
+		p   Preview
+		err error
+	)
+	tbl.ForEach(func(k, v lua.LValue) {
+		if err != nil {
+			return
+		}
+		switch lua.LVAsString(k) {
+		case "text":
+			s, ok := v.(lua.LString)
+			if !ok {
+				err = fmt.Errorf("lua previewer: text must be a string, got %s", v.Type())
+				return
+			}
+			p.Text = string(s)
+		case "command":
+			switch v := v.(type) {
+			case lua.LString:
+				p.Shell = string(v)
+			case *lua.LTable:
+				for i := 1; i <= v.Len(); i++ {
+					p.Args = append(p.Args, lua.LVAsString(v.RawGetInt(i)))
+				}
+				if len(p.Args) == 0 {
+					err = fmt.Errorf("lua previewer: command is an empty list")
+				}
+			default:
+				err = fmt.Errorf("lua previewer: command must be a string or a list, got %s", v.Type())
+			}
+		default:
+			err = fmt.Errorf("lua previewer: unknown field %q (expected text or command)", lua.LVAsString(k))
+		}
+	})
+	if err != nil {
+		return Preview{}, false, err
+	}
+	return p, true, nil
+}
@@ -0,0 +1,48 @@
+package components
+
+import (
+	"strings"
+
+	"cute/tui"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ConfigErrorsModal lists the problems found in config.lua.
+func ConfigErrorsModal(m tui.Model) *lipgloss.Layer {
+	theme := m.GetTheme()
+	width, height := m.GetSize()
+
+	modalWidth := min(max(width*2/3, 30), 100)
+	textWidth := modalWidth - theme.Dialog.PaddingLeft - theme.Dialog.PaddingRight - 2
+
+	var b strings.Builder
+	errs := m.GetConfigErrors()
+	if len(errs) == 0 {
+		b.WriteString("No problems found in config.lua.")
+	}
+	for i, err := range errs {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(ansi.Wrap("• "+err.Error(), textWidth, ""))
+	}
+	b.WriteString("\n\nPress enter or esc to close, or :config-errors to see this again.")
+	content := ansi.Wrap(b.String(), textWidth, "")
+
+	// Grow with the list, but leave the rest of the screen visible.
+	lines := strings.Count(content, "\n") + 1
+	modalHeight := min(lines+theme.Dialog.PaddingTop+theme.Dialog.PaddingBottom, max(height-4, 6))
+
+	fw := FloatingWindow{
+		Content: textView(content),
+		Width:   modalWidth,
+		Height:  modalHeight,
+		Style:   DefaultFloatingStyle(theme),
+		Title:   "Configuration errors",
+	}
+
+	modalContent := fw.View(width, height)
+	return CenterModal(modalContent, width, height)
+}
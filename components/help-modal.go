@@ -23,6 +23,15 @@ func HelpModal(m tui.Model) *lipgloss.Layer {
 	
 	Search:
 		Type in the search bar to filter files by name
+
+	Preview:
+		tab              Focus preview / file list
+		j/k              Scroll line
+		space/b          Scroll page
+		g/G              Go to top / end
+		/                Search, n/N next/previous
+		[ / ]            Previous / next tab
+		h/j/k/l, enter   Edit permissions (Permissions tab)
 	
 	General:
 		?                Toggle this help
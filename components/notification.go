@@ -0,0 +1,24 @@
+package components
+
+import (
+	"cute/tui"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Notification renders the message a Lua command asked to show with
+// cute.notify, or nothing when there is none.
+func Notification(m tui.Model, args tui.ComponentArgs) string {
+	theme := m.GetTheme()
+	notification := m.GetNotification()
+	if notification == "" {
+		return ""
+	}
+
+	return lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.Secondary)).
+		PaddingLeft(1).
+		PaddingRight(1).
+		Height(args.Height).
+		Render(notification)
+}
@@ -8,6 +8,25 @@ import (
 
 func PreviewTabs(m tui.Model, args tui.ComponentArgs) string {
 	theme := m.GetTheme()
+	active := m.GetPreviewTab()
+
+	tabStyle := lipgloss.NewStyle().
+		Foreground(lipgloss.Color(theme.PreviewTab.Foreground)).
+		Background(lipgloss.Color(theme.PreviewTab.Background)).
+		Padding(0, 1)
+
+	activeStyle := tabStyle.
+		Foreground(lipgloss.Color(theme.PreviewTabActive.Foreground)).
+		Background(lipgloss.Color(theme.PreviewTabActive.Background))
+
+	tabs := make([]string, 0, len(tui.PreviewTabOrder))
+	for _, tab := range tui.PreviewTabOrder {
+		if tab == active {
+			tabs = append(tabs, activeStyle.Render(string(tab)))
+		} else {
+			tabs = append(tabs, tabStyle.Render(string(tab)))
+		}
+	}
 
 	return lipgloss.NewStyle().
 		Foreground(lipgloss.Color(theme.SearchBar.Foreground)).
@@ -21,5 +40,5 @@ func PreviewTabs(m tui.Model, args tui.ComponentArgs) string {
 		BorderRight(false).
 		Height(args.Height).
 		Width(args.Width).
-		Render("Tabs")
+		Render(lipgloss.JoinHorizontal(lipgloss.Top, tabs...))
 }
@@ -10,11 +10,17 @@ func Preview(m tui.Model, args tui.ComponentArgs) string {
 	theme := m.GetTheme()
 	previewViewport := m.GetPreviewViewport()
 
+	// Highlight the border while the preview has keyboard focus.
+	border := theme.Preview.Border
+	if m.IsPreviewFocused() {
+		border = theme.Secondary
+	}
+
 	return lipgloss.NewStyle().
 		Background(lipgloss.Color(theme.Preview.Background)).
 		BorderStyle(lipgloss.RoundedBorder()).
 		BorderBackground(lipgloss.Color(theme.Preview.BorderBackground)).
-		BorderForeground(lipgloss.Color(theme.Preview.Border)).
+		BorderForeground(lipgloss.Color(border)).
 		Foreground(lipgloss.Color(theme.Preview.Foreground)).
 		Height(args.Height).
 		Width(args.Width).
@@ -0,0 +1,55 @@
+package components
+
+import (
+	"strings"
+
+	"cute/tui"
+
+	"charm.land/lipgloss/v2"
+)
+
+// ThemePickerModal lists the themes to pick from. It sits at the top right,
+// over the preview, so the file list can be seen in the highlighted theme.
+func ThemePickerModal(m tui.Model) *lipgloss.Layer {
+	theme := m.GetTheme()
+	width, height := m.GetSize()
+	names, index := m.GetThemePicker()
+
+	selected := lipgloss.NewStyle().
+		Background(lipgloss.Color(theme.Selection.Background)).
+		Foreground(lipgloss.Color(theme.Selection.Foreground))
+
+	var b strings.Builder
+	if len(names) == 0 {
+		b.WriteString("No themes found.")
+	}
+	for i, name := range names {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		line := "  " + name
+		if name == m.GetThemeName() {
+			line += " (in use)"
+		}
+		if i == index {
+			line = selected.Render("› " + strings.TrimPrefix(line, "  "))
+		}
+		b.WriteString(line)
+	}
+	b.WriteString("\n\nenter: pick  esc: cancel")
+	content := b.String()
+
+	modalWidth := min(max(lipgloss.Width(content)+theme.Dialog.PaddingLeft+theme.Dialog.PaddingRight+2, 30), width)
+	lines := strings.Count(content, "\n") + 1
+	modalHeight := min(lines+theme.Dialog.PaddingTop+theme.Dialog.PaddingBottom, max(height-4, 6))
+
+	fw := FloatingWindow{
+		Content: textView(content),
+		Width:   modalWidth,
+		Height:  modalHeight,
+		Style:   DefaultFloatingStyle(theme),
+		Title:   "Themes",
+	}
+
+	return lipgloss.NewLayer(fw.View(width, height)).X(max(width-modalWidth-2, 0)).Y(2)
+}
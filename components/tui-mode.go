@@ -17,18 +17,21 @@ func TuiMode(m tui.Model, args tui.ComponentArgs) string {
 	case tui.TuiModeNormal:
 		background = theme.TuiMode.NormalModeBackground
 		foreground = theme.TuiMode.NormalModeForeground
-	case tui.TuiModeCommand:
+	case tui.TuiModeCommand, tui.TuiModePrompt, tui.TuiModeRunning:
 		background = theme.TuiMode.CommandModeBackground
 		foreground = theme.TuiMode.CommandModeForeground
 	case tui.TuiModeFilter:
 		background = theme.TuiMode.FilterModeBackground
 		foreground = theme.TuiMode.FilterModeForeground
-	case tui.TuiModeHelp:
+	case tui.TuiModeHelp, tui.TuiModeConfigErrors, tui.TuiModeThemes:
 		background = theme.TuiMode.HelpModeBackground
 		foreground = theme.TuiMode.HelpModeForeground
 	case tui.TuiModeQuit:
 		background = theme.TuiMode.QuitModeBackground
 		foreground = theme.TuiMode.QuitModeForeground
+	case tui.TuiModePreview, tui.TuiModePreviewSearch, tui.TuiModeOwner:
+		background = theme.TuiMode.PreviewModeBackground
+		foreground = theme.TuiMode.PreviewModeForeground
 	}
 
 	return lipgloss.NewStyle().
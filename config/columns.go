@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// ColumnNames are the built-in columns of the file list, in their default
+// order.
+var ColumnNames = []string{"permissions", "size", "user", "group", "modified", "name"}
+
+// Column is an entry of the columns list in config.lua: a built-in column,
+// given by name alone or by a table that sizes it, or a custom column whose
+// values are computed by a Lua function.
+//
+//	columns = {
+//	  "permissions",
+//	  { name = "size", align = "right" },
+//	  { name = "lines", width = 6, align = "right", fn = function(file) end },
+//	  "modified",
+//	  "name",
+//	}
+type Column struct {
+	// Name is a name from ColumnNames, or the name of a custom column.
+	Name string
+	// Width is the number of cells the column takes. When zero, built-in
+	// columns fit their values and custom columns get a default width. For
+	// the name column, which takes the space left over, it is the width
+	// below which other columns are hidden.
+	Width int
+	// Align is "left", "right" or empty for the column's default.
+	Align string
+	// Fn computes the values of a custom column; it is nil for built-in
+	// columns. It is called with an entry like those of cute.files() and
+	// returns a string, a number or nil.
+	Fn *lua.LFunction
+}
+
+// parseColumns reads the columns list. Entries that cannot be used are
+// skipped and reported. The name column is added at the end when the list
+// leaves it out, since a file list without names is of no use.
+func parseColumns(tbl *lua.LTable) ([]Column, []error) {
+	var (
+		columns []Column
+		errs    []error
+		seen    = map[string]bool{}
+	)
+
+	for i := 1; i <= tbl.Len(); i++ {
+		var (
+			col Column
+			err error
+		)
+		switch v := tbl.RawGetInt(i).(type) {
+		case lua.LString:
+			col.Name = string(v)
+			if !slices.Contains(ColumnNames, col.Name) {
+				err = fmt.Errorf("columns[%d]: unknown column %q%s", i, col.Name, suggest(col.Name, ColumnNames))
+			}
+		case *lua.LTable:
+			col, err = parseColumn(v)
+			if err != nil {
+				err = fmt.Errorf("columns[%d]: %w", i, err)
+			}
+		default:
+			err = fmt.Errorf("columns[%d]: expected a column name or a table, got %s", i, v.Type())
+		}
+		if err == nil && seen[col.Name] {
+			err = fmt.Errorf("columns[%d]: column %q is listed twice", i, col.Name)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		seen[col.Name] = true
+		columns = append(columns, col)
+	}
+
+	if !seen["name"] {
+		columns = append(columns, Column{Name: "name"})
+	}
+	return columns, errs
+}
+
+// parseColumn reads a column given as a table.
+func parseColumn(tbl *lua.LTable) (Column, error) {
+	var (
+		col Column
+		err error
+	)
+	tbl.ForEach(func(k, v lua.LValue) {
+		if err != nil {
+			return
+		}
+		switch field := lua.LVAsString(k); field {
+		case "name":
+			s, ok := v.(lua.LString)
+			if !ok || s == "" {
+				err = fmt.Errorf("name must be a non-empty string, got %s", v.Type())
+				return
+			}
+			col.Name = string(s)
+		case "width":
+			n, ok := v.(lua.LNumber)
+			if !ok || n < 1 || n != lua.LNumber(int(n)) {
+				err = fmt.Errorf("width must be a positive whole number, got %s", v.String())
+				return
+			}
+			col.Width = int(n)
+		case "align":
+			s, ok := v.(lua.LString)
+			if !ok || s != "left" && s != "right" {
+				err = fmt.Errorf("align must be \"left\" or \"right\", got %s", v.String())
+				return
+			}
+			col.Align = string(s)
+		case "fn":
+			fn, ok := v.(*lua.LFunction)
+			if !ok {
+				err = fmt.Errorf("fn must be a function, got %s", v.Type())
+				return
+			}
+			col.Fn = fn
+		default:
+			hint := suggest(field, []string{"name", "width", "align", "fn"})
+			if hint == "" {
+				hint = " (expected name, width, align or fn)"
+			}
+			err = fmt.Errorf("unknown field %q%s", field, hint)
+		}
+	})
+
+	switch {
+	case err != nil:
+		return Column{}, err
+	case col.Name == "":
+		return Column{}, fmt.Errorf("a column needs a name")
+	case col.Fn == nil && !slices.Contains(ColumnNames, col.Name):
+		return Column{}, fmt.Errorf("unknown column %q%s; custom columns need an fn", col.Name, suggest(col.Name, ColumnNames))
+	case col.Fn != nil && slices.Contains(ColumnNames, col.Name):
+		return Column{}, fmt.Errorf("%q is a built-in column; give the custom column another name", col.Name)
+	}
+	return col, nil
+}
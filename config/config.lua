@@ -4,19 +4,66 @@
 --   - the UI theme (via the global `theme` table), and
 --   - user-defined commands (via the global `commands` table).
 --
--- The Go side loads this file once at startup. Commands are invoked from the
--- command bar with access to the currently selected file or directory.
+-- The Go side loads this file at startup and again whenever it changes, or on
+-- :reload. If the changed file fails to load, the previous configuration stays
+-- in use and the error is shown in the status bar. Problems such as unknown
+-- theme keys are listed at startup and by :config-errors. Commands are invoked
+-- from the command bar with access to the currently selected file or directory.
 
 -- Theme -----------------------------------------------------------------------
 --
--- Keys here correspond to the simple key/value overrides understood by the
--- Go theming layer. Any key you omit falls back to the built-in defaults.
+-- theme can name a theme instead of being a table:
+--
+--   theme = "solarized"
+--
+-- The dark (default), light, high-contrast and solarized themes are bundled.
+-- More can be added as themes/<name>.lua next to this file, returning a theme
+-- table; a file named after a bundled theme replaces it. A table can build on
+-- a theme with a base key, and theme files can do the same:
+--
+--   theme = { base = "light", primary = "#005F87" }
+--
+-- :theme <name> switches theme until the next reload, and :theme alone opens a
+-- picker that shows each theme as you move through it.
+--
+-- Any key you omit falls back to the built-in defaults. Colors are written
+-- "#rrggbb", "#rgb" or as an ANSI color number such as "212"; "" means none.
 --
 -- Common keys:
 --   foreground, background, border
 --   selected_foreground, selected_background
 --   directory, regular, symlink, socket, pipe, device, executable
 --   nlink, user, group, size, time
+--
+-- The whole theme can be set with nested tables, whose keys are those of the
+-- Go Theme struct in snake case:
+--   primary, secondary, border_color
+--   command_bar, search_bar       background, foreground, placeholder, border,
+--                                 padding_top, padding_bottom, padding_left,
+--                                 padding_right
+--   file_list, preview,
+--   status_bar                    background, foreground, border,
+--                                 border_background, padding_*
+--   dialog                        background, foreground, border, title,
+--                                 padding_*
+--   current_dir, header, selection, view_mode, preview_tab,
+--   preview_tab_active, search_match,
+--   search_match_current          background, foreground
+--   permissions                   read, write, exec, none
+--   file_type_colors              directory, regular, symlink, ...
+--   field_colors                  nlink, user, group, size, time
+--   tui_mode                      normal_mode_background,
+--                                 normal_mode_foreground, ... for the normal,
+--                                 command, filter, help, quit and preview
+--                                 modes
+--   syntax                        attribute, builtin, comment, constant,
+--                                 function, keyword, number, operator,
+--                                 punctuation, string, tag, type
+--   hex                           offset, null, printable, control, extended
+--
+-- Paddings are numbers of cells. The syntax and hex entries are styles: a
+-- color with attributes such as "#F25D94+bold" (bold, dim, underline,
+-- italic). Unknown keys and invalid values are reported by :config-errors.
 
 theme = {
   foreground = "#F0EDED",
@@ -31,6 +78,103 @@ theme = {
   directory  = "#A8D2FF",
   regular    = "#F0EDED",
   executable = "#FF9BC0",
+
+  -- command_bar = { placeholder = "#A8A7A7", padding_left = 1 },
+  -- tui_mode = { normal_mode_background = "#FF9BC0" },
+  -- syntax = { comment = "#7A7A7A+italic", keyword = "#F25D94+bold" },
+}
+
+-- Image previews --------------------------------------------------------------
+--
+-- How images are drawn in the preview pane. "auto" picks the best protocol the
+-- terminal supports; set one explicitly if detection gets it wrong:
+--   "kitty"      Kitty graphics protocol
+--   "sixel"      Sixel graphics (foot, WezTerm, xterm -ti vt340, ...)
+--   "iterm2"     iTerm2 inline images (iTerm2, WezTerm, ...)
+--   "halfblocks" colored half-block characters, any truecolor terminal
+--   "braille"    colored braille characters, any truecolor terminal
+--   "none"       no image previews
+
+image_preview = "auto"
+
+-- Running Lua -----------------------------------------------------------------
+--
+-- Commands, Lua key bindings and prompt callbacks run in the background: the
+-- status bar shows RUNNING, and esc cancels them. They are stopped after
+-- command_timeout seconds (0 for no limit). Hooks run while the screen is
+-- drawn, so they are stopped after 2 seconds, and previewers, which run
+-- whenever the selection moves, after half a second. Custom columns are
+-- computed a tenth of a second at a time.
+-- A stuck loop is stopped; a program started with os.execute is waited for,
+-- so start long-running programs with cute.spawn instead.
+--
+-- Set CUTE_RESTRICTED=1 in the environment to load a shared configuration in
+-- restricted mode: io, os.execute, os.exit, os.remove, os.rename, os.setenv,
+-- os.tmpname, dofile, loadfile, cute.run and cute.spawn are unavailable,
+-- require only finds modules in this directory, and shell key bindings and
+-- previewer commands are refused.
+
+command_timeout = 30
+
+-- Hooks -----------------------------------------------------------------------
+--
+-- Functions called on events. Each receives the same `ctx` as commands, can
+-- use the `cute` module (see below) and can return a result like a command.
+-- A hook may also be a list of functions, which run in order. Errors are shown
+-- in the status bar, and unknown hook names are reported by :config-errors.
+--
+--   on_startup(ctx)     once the file manager has started
+--   on_quit(ctx)        right before it exits
+--   on_cd(ctx)          after changing directory; ctx.cwd is the new one
+--   on_select(ctx)      when the cursor moves to another entry
+--   on_preview(ctx)     when the preview pane starts showing another file
+--   on_file_op(ctx, op) after a file operation (touch, mkdir, mkcd, rm, mv,
+--                       cp, ln, chmod, chown), where op is
+--                       { name = "rm", paths = { ... }, error = "..." }
+--                       and error is nil when the operation succeeded
+
+hooks = {
+  -- Keep a log of visited directories:
+  --
+  -- on_cd = function(ctx)
+  --   local log = io.open(os.getenv("HOME") .. "/.cache/cute-visited", "a")
+  --   if log then
+  --     log:write(ctx.cwd, "\n")
+  --     log:close()
+  --   end
+  -- end,
+}
+
+-- Keys ------------------------------------------------------------------------
+--
+-- Key bindings per mode: normal, preview, help, quit, select, command, filter,
+-- add_file, mkdir, owner, search and prompt. A key sequence is written as its
+-- keys, either run together ("gg") or separated by spaces ("ctrl+w l"), and is
+-- bound to:
+--
+--   "go_to_start"            a built-in action (replaces its default keys)
+--   ":open"                  a command line, as typed after ":"
+--   "!make"                  a shell command; its output goes to the preview
+--   function(ctx) ... end    a Lua function, called like a command
+--   false                    nothing: removes the default binding
+--   { command = "open", desc = "Open the selection" }
+--                            the same, with a description (action, command,
+--                            shell or fn)
+--
+-- Actions: add_file, auto_complete, cancel, command, directories, down, enter,
+-- files, filter, focus_preview, go_to_end, go_to_start, help, left, list,
+-- next_match, next_tab, page_down, page_up, parent, prev_match, prev_tab,
+-- preview, quit, right, scroll_down, scroll_up, search, select, up.
+--
+-- Bindings that conflict, e.g. "d" and "dd" in the same mode, are reported
+-- when the file is loaded.
+
+keys = {
+  normal = {
+    -- gg = "go_to_start",
+    -- ["ctrl+o"] = ":open",
+    -- J = { command = "jump", desc = "Jump to a directory" },
+  },
 }
 
 -- Commands --------------------------------------------------------------------
@@ -54,10 +198,46 @@ theme = {
 --     --   * or a string, which is treated as `output`.
 --   end
 --
+-- Commands can also drive the file manager through the `cute` module:
+--
+--   cute.cwd()                       current directory
+--   cute.files()                     entries of the file list, as filtered
+--   cute.selection()                 selected entries
+--   cute.cd(path)                    change directory; true or nil, err
+--   cute.set_filter(text)            set the file list filter ("" clears it)
+--   cute.notify(message)             show a message in the status bar
+--   cute.prompt(message, fn, default)
+--                                    ask for input, then call fn(text), or
+--                                    fn(nil) if the prompt is cancelled
+--   cute.run(line)                   run a command line as if typed after ":";
+--                                    returns its output, or nil, err; not in
+--                                    previewers and custom columns
+--   cute.spawn(cmd, opts)            start a process in the background; cmd
+--                                    is a shell command line or a list of
+--                                    arguments; returns the job's ID
+--   cute.kill(id)                    stop a job
+--   cute.refresh()                   re-list the current directory
+--   cute.style(spec, text)           text styled with a spec such as
+--                                    "#ff8800+bold" or "blue+underline"
+--
+-- Entries are tables with name, path, is_dir, type, size, permissions, user,
+-- group and modified fields.
+--
+-- A spawned job starts once the command returns and keeps running after it.
+-- Its output streams into the preview pane line by line, unless
+-- opts.output = false. Its callbacks run once per line or at the end:
+--   on_stdout = function(line) end, on_stderr = function(line) end,
+--   on_exit = function(code, err) end   -- err is set if it could not run
+-- opts.cwd sets the directory it runs in. Without on_exit, the status bar
+-- says how the job ended. A job keeps running when this file is reloaded, but
+-- its callbacks are dropped.
+--
 -- Example usage from the command bar:
 --   :open
 --   :edit some-file.txt
 
+local cute = require("cute")
+
 commands = {}
 
 -- Open the selected file or directory using the system default handler.
@@ -68,7 +248,14 @@ function commands.open(ctx, args)
   end
 
   -- On Linux this uses xdg-open; adjust if needed for other platforms.
-  os.execute(string.format("xdg-open %q &", target))
+  cute.spawn({ "xdg-open", target }, {
+    output = false,
+    on_exit = function(code, err)
+      if code ~= 0 then
+        cute.notify("xdg-open failed: " .. (err or ("exit code " .. code)))
+      end
+    end,
+  })
   return { refresh = false }
 end
 
@@ -89,4 +276,143 @@ function commands.edit(ctx, args)
   return { refresh = false }
 end
 
+-- Run make in the current directory, streaming its output into the preview
+-- and counting warnings as they are printed.
+function commands.make(ctx, args)
+  local warnings = 0
+  cute.spawn({ "make", unpack(args) }, {
+    on_stderr = function(line)
+      if line:find("warning") then
+        warnings = warnings + 1
+      end
+    end,
+    on_exit = function(code)
+      cute.notify(string.format("make exited with code %d, %d warning(s)", code, warnings))
+      cute.refresh()
+    end,
+  })
+end
+
+-- Ask for a directory and jump to it.
+function commands.jump(ctx, args)
+  cute.prompt("Jump to", function(dir)
+    if not dir or dir == "" then
+      return
+    end
+    local ok, err = cute.cd(dir)
+    if not ok then
+      cute.notify(err)
+    end
+  end, ctx.cwd)
+end
+
+-- Show only the files with the given extension, e.g. :only go. Without an
+-- argument the filter is cleared.
+function commands.only(ctx, args)
+  if #args == 0 then
+    cute.set_filter("")
+    return
+  end
+
+  local ext = "." .. args[1]
+  local count = 0
+  for _, entry in ipairs(cute.files()) do
+    if not entry.is_dir and entry.name:sub(-#ext) == ext then
+      count = count + 1
+    end
+  end
+
+  cute.set_filter(ext)
+  cute.notify(string.format("%d %s files", count, args[1]))
+end
+
+-- Previewers ------------------------------------------------------------------
+--
+-- Functions that preview files in the preview pane, keyed by:
+--
+--   extension     pb, ".pb"
+--   glob          "*.sqlite", "Dockerfile*" (matched against the file name)
+--   MIME type     "application/x-sqlite3", or a family such as "text/*"
+--
+-- Extensions are tried first, then globs, then MIME types. Each function is
+-- called as fn(ctx, file): ctx is the same as for commands, with the width and
+-- height of the preview pane; file is an entry like those of cute.files(), with
+-- a mime field. It returns:
+--
+--   "text"                           shown as is; style it with cute.style
+--   { text = "..." }                 the same
+--   { command = { "prog", "arg" } }  the output of a program, run in the file's
+--                                    directory
+--   { command = "shell line" }       the output of a shell command
+--   nil                              the built-in preview
+--
+-- Previewers hold up moving through the list while they run, and are stopped
+-- after half a second; leave slow work to a command, which runs in the
+-- background. Commands are killed after 10 seconds or when the selection
+-- moves on.
+
+previewers = {
+  -- ["*.sqlite"] = function(ctx, file)
+  --   return { command = { "sqlite3", file.path, ".schema" } }
+  -- end,
+  --
+  -- pb = function(ctx, file)
+  --   -- Pass the path as an argument rather than quoting it in the line.
+  --   return { command = { "sh", "-c", 'protoc --decode_raw < "$1"', "sh", file.path } }
+  -- end,
+}
+
+-- Columns ---------------------------------------------------------------------
+--
+-- The columns of the file list, in order. The built-in columns are
+-- permissions, size, user, group, modified and name; leaving columns = nil
+-- shows them all. An entry is a column name, or a table:
+--
+--   name    a built-in column, or the name of a custom column
+--   width   cells taken; by default built-in columns fit their values,
+--           custom columns are 10 wide, and the name column takes what is
+--           left (its width is the least it gets before other columns hide)
+--   align   "left" or "right"
+--   fn      computes a custom column as fn(file), file being an entry like
+--           those of cute.files(); it returns a string, a number or nil
+--
+-- Custom columns are only computed for the rows on screen, and again when the
+-- directory is reloaded. They are computed a tenth of a second at a time
+-- between redraws, so cells are empty until their turn comes, and a value
+-- taking longer than that is left empty. Like previewers, they cannot use
+-- cute.run. When the file list is narrow, group, user, custom columns,
+-- permissions, modified and size are hidden in that order. The name column is
+-- added at the end when it is left out.
+
+-- columns = {
+--   "permissions",
+--   { name = "size", align = "right" },
+--   { name = "lines", width = 6, align = "right", fn = function(file)
+--       if file.is_dir then return nil end
+--       local f = io.open(file.path)
+--       if not f then return nil end
+--       local n = 0
+--       for _ in f:lines() do n = n + 1 end
+--       f:close()
+--       return n
+--   end },
+--   "modified",
+--   "name",
+-- }
 
+-- Plugins ---------------------------------------------------------------------
+--
+-- Every plugins/<name>.lua file, and every plugins/<name>/init.lua, next to
+-- this file is loaded after it, so plugins can be shared in a dotfiles repo.
+-- A plugin defines commands, keys, hooks and previewers tables like this file
+-- does; its commands are run as :<name>.<command>, and this file's keys and
+-- previewers win over the plugin's. The plugin reads its settings from the
+-- settings global, and its name and directory from plugin.name and
+-- plugin.dir. The globals of this file can be read from plugins.
+--
+-- The plugins table passes settings to plugins, or turns them off:
+--
+-- plugins = {
+--   git = { show_untracked = false },
+--   ["preview-extras"] = false,
+-- }
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Error is a problem found in the Lua configuration. Line is zero when the
+// problem cannot be pinned to a line, such as an unknown theme key.
+type Error struct {
+	File    string
+	Line    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	switch {
+	case e.File == "":
+		return e.Message
+	case e.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Message)
+	default:
+		return fmt.Sprintf("%s: %s", e.File, e.Message)
+	}
+}
+
+var (
+	// luaRuntimeError matches "file:line: message", as raised by error()
+	// and failed operations.
+	luaRuntimeError = regexp.MustCompile(`^(.+?):(\d+): (?s:(.*))$`)
+	// luaSyntaxError matches the parser's
+	// "file line:3(column:5) near 'x':   syntax error".
+	luaSyntaxError = regexp.MustCompile(`^(.+?) line:(\d+)\(column:\d+\) near (.*?):\s+(.*)$`)
+	// luaEOFError matches the parser's "file at EOF:   syntax error".
+	luaEOFError = regexp.MustCompile(`^(.+?) at EOF:\s+(.*)$`)
+)
+
+// luaError turns an error from running the Lua file at path into an Error,
+// with the file and line it happened at when Lua reports them. The stack
+// traceback is dropped.
+func luaError(path string, err error) *Error {
+	msg := err.Error()
+	var apiErr *lua.ApiError
+	if errors.As(err, &apiErr) && apiErr.Object != nil {
+		msg = apiErr.Object.String()
+	}
+	msg = strings.TrimSpace(msg)
+
+	if m := luaSyntaxError.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return &Error{File: m[1], Line: line, Message: fmt.Sprintf("%s near %s", m[4], m[3])}
+	}
+	if m := luaEOFError.FindStringSubmatch(msg); m != nil {
+		return &Error{File: m[1], Message: m[2] + " at end of file"}
+	}
+	if m := luaRuntimeError.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[2])
+		return &Error{File: m[1], Line: line, Message: m[3]}
+	}
+	return &Error{File: path, Message: msg}
+}
+
+// suggest returns a hint naming the closest of the known names to name, or
+// an empty string when none is close.
+func suggest(name string, known []string) string {
+	best, bestDist := "", len(name)/2+1
+	for _, k := range known {
+		if d := editDistance(name, k); d < bestDist {
+			best, bestDist = k, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (did you mean %q?)", best)
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// sortErrors sorts errs by message and returns them. Lua tables have no
+// order, so this lists the problems the same way on every load.
+func sortErrors(errs []error) []error {
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return errs
+}
+
+// AddErrors records problems found in the configuration, such as key
+// bindings the UI cannot apply, as Errors in the configuration's file.
+func (rc *RuntimeConfig) AddErrors(errs ...error) {
+	for _, err := range errs {
+		var cfgErr *Error
+		if !errors.As(err, &cfgErr) {
+			err = &Error{File: rc.Path, Message: err.Error()}
+		}
+		rc.Errors = append(rc.Errors, err)
+	}
+}
@@ -0,0 +1,20 @@
+package config
+
+// Hooks that config.lua can register functions for in its hooks table.
+const (
+	// HookStartup runs once the file manager has started.
+	HookStartup = "on_startup"
+	// HookQuit runs right before the file manager exits.
+	HookQuit = "on_quit"
+	// HookCd runs after the current directory changed.
+	HookCd = "on_cd"
+	// HookSelect runs when the cursor moves to another entry.
+	HookSelect = "on_select"
+	// HookPreview runs when the preview pane starts showing another file.
+	HookPreview = "on_preview"
+	// HookFileOp runs after a file operation completed, or failed.
+	HookFileOp = "on_file_op"
+)
+
+// Hooks lists every hook name.
+var Hooks = []string{HookStartup, HookQuit, HookCd, HookSelect, HookPreview, HookFileOp}
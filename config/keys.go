@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// KeyBinding binds a key sequence in one mode of the UI. It comes from an
+// entry of the keys table in config.lua:
+//
+//	keys = {
+//	  normal = {
+//	    gg = "go_to_start",          -- built-in action
+//	    ["ctrl+e"] = ":edit",        -- command line, as typed after ":"
+//	    S = "!make",                 -- shell command
+//	    x = function(ctx) end,       -- Lua function, called like a command
+//	    h = false,                   -- unbind the key
+//	    J = { command = "jump", desc = "Jump to a directory" },
+//	  },
+//	}
+//
+// The table form takes one of action, command, shell or fn, and an optional
+// desc.
+type KeyBinding struct {
+	// Mode is the name of the mode, e.g. "normal" or "preview".
+	Mode string
+	// Keys is the key sequence as written in the configuration.
+	Keys string
+
+	// Exactly one of Action, Command, Shell and Fn is set, unless Unbind is.
+	Action  string
+	Command string
+	Shell   string
+	Fn      *lua.LFunction
+	Unbind  bool
+
+	// Desc describes what the binding does.
+	Desc string
+}
+
+// parseKeyBindings reads the keys table. Entries that cannot be understood
+// are skipped and reported.
+func parseKeyBindings(tbl *lua.LTable) ([]KeyBinding, []error) {
+	var (
+		bindings []KeyBinding
+		errs     []error
+	)
+
+	tbl.ForEach(func(k, v lua.LValue) {
+		mode, ok := k.(lua.LString)
+		if !ok {
+			errs = append(errs, fmt.Errorf("keys: mode names must be strings, got %s", k.Type()))
+			return
+		}
+		modeTbl, ok := v.(*lua.LTable)
+		if !ok {
+			errs = append(errs, fmt.Errorf("keys.%s: expected a table of key bindings, got %s", mode, v.Type()))
+			return
+		}
+
+		modeTbl.ForEach(func(k, v lua.LValue) {
+			keys, ok := k.(lua.LString)
+			if !ok {
+				errs = append(errs, fmt.Errorf("keys.%s: keys must be strings, got %s", mode, k.Type()))
+				return
+			}
+
+			b, err := parseKeyBinding(v)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("keys.%s[%q]: %w", mode, string(keys), err))
+				return
+			}
+			b.Mode = string(mode)
+			b.Keys = string(keys)
+			bindings = append(bindings, b)
+		})
+	})
+
+	// Lua tables have no order; sort so conflicts are reported the same way
+	// on every load.
+	sort.Slice(bindings, func(i, j int) bool {
+		if bindings[i].Mode != bindings[j].Mode {
+			return bindings[i].Mode < bindings[j].Mode
+		}
+		return bindings[i].Keys < bindings[j].Keys
+	})
+
+	return bindings, errs
+}
+
+// parseKeyBinding reads the value of a key binding.
+func parseKeyBinding(v lua.LValue) (KeyBinding, error) {
+	switch v := v.(type) {
+	case lua.LBool:
+		if v {
+			return KeyBinding{}, fmt.Errorf("expected false to unbind the key, got true")
+		}
+		return KeyBinding{Unbind: true}, nil
+
+	case lua.LString:
+		s := string(v)
+		switch {
+		case strings.HasPrefix(s, ":"):
+			return KeyBinding{Command: strings.TrimSpace(s[1:])}, nil
+		case strings.HasPrefix(s, "!"):
+			return KeyBinding{Shell: strings.TrimSpace(s[1:])}, nil
+		default:
+			return KeyBinding{Action: s}, nil
+		}
+
+	case *lua.LFunction:
+		return KeyBinding{Fn: v}, nil
+
+	case *lua.LTable:
+		var (
+			b     KeyBinding
+			err   error
+			count int
+		)
+		v.ForEach(func(k, val lua.LValue) {
+			key, _ := k.(lua.LString)
+			str, isStr := val.(lua.LString)
+			switch string(key) {
+			case "action", "command", "shell", "desc":
+				if !isStr {
+					err = fmt.Errorf("%s must be a string, got %s", key, val.Type())
+					return
+				}
+			}
+			switch string(key) {
+			case "action":
+				b.Action = string(str)
+				count++
+			case "command":
+				b.Command = strings.TrimSpace(string(str))
+				count++
+			case "shell":
+				b.Shell = strings.TrimSpace(string(str))
+				count++
+			case "fn":
+				fn, ok := val.(*lua.LFunction)
+				if !ok {
+					err = fmt.Errorf("fn must be a function, got %s", val.Type())
+					return
+				}
+				b.Fn = fn
+				count++
+			case "desc":
+				b.Desc = string(str)
+			default:
+				err = fmt.Errorf("unknown field %q (expected action, command, shell, fn or desc)", lua.LVAsString(k))
+			}
+		})
+		if err != nil {
+			return KeyBinding{}, err
+		}
+		if count != 1 {
+			return KeyBinding{}, fmt.Errorf("expected exactly one of action, command, shell or fn")
+		}
+		return b, nil
+
+	default:
+		return KeyBinding{}, fmt.Errorf("expected an action name, command, function or table, got %s", v.Type())
+	}
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	lua "github.com/yuin/gopher-lua"
 
@@ -15,15 +19,22 @@ import (
 // The Lua file is expected to define:
 //
 //	theme = {
-//	  -- keys matching the simple key/value theme overrides understood by
-//	  -- theming.LoadThemeFromMap, e.g.:
+//	  -- keys mirroring theming.Theme in snake case, with its sections as
+//	  -- nested tables, or the flat keys of theming.LoadThemeFromMap, e.g.:
 //	  --   foreground = "#F0EDED",
-//	  --   background = "#1E1E1E",
-//	  --   border     = "#F25D94",
 //	  --   directory  = "#A8D2FF",
-//	  --   regular    = "#F0EDED",
+//	  --   status_bar = { background = "#1E1E1E", padding_left = 1 },
+//	  --   syntax     = { keyword = "#F25D94+bold" },
 //	}
 //
+//	-- How image previews are drawn: "auto" (the default), "kitty",
+//	-- "sixel", "iterm2", "halfblocks", "braille" or "none".
+//	image_preview = "auto"
+//
+//	-- Seconds Lua commands may run before they are stopped; 0 for no
+//	-- limit. A running command can also be cancelled with esc.
+//	command_timeout = 30
+//
 //	commands = {
 //	  mycmd = function(ctx, args)
 //	    -- ctx describes the selected file/dir:
@@ -41,22 +52,102 @@ import (
 //	    --         view_mode = \"ll\", open_help = false, quit = false }
 //	    --     (all fields are optional), or
 //	    --   * a string, which is treated as Output.
+//	    --
+//	    -- Commands can also act through the cute module, loaded with
+//	    -- require("cute"); see the command package.
 //	  end,
 //	}
 //
+//	keys = {
+//	  -- Per mode (normal, preview, command, ...), key sequences bound to
+//	  -- a built-in action, a command line (":..."), a shell command
+//	  -- ("!..."), a Lua function, or false to unbind the key. See
+//	  -- KeyBinding for the table form.
+//	  normal = { gg = "go_to_start", ["ctrl+e"] = ":edit" },
+//	}
+//
+//	previewers = {
+//	  -- Functions previewing files, keyed by extension ("pb"), glob on the
+//	  -- file name ("*.sqlite") or MIME type ("text/*"). They return text,
+//	  -- or a command whose output is shown; see the tui package.
+//	  pb = function(ctx, file) return { command = { "protoc", "--decode_raw" } } end,
+//	}
+//
+//	columns = {
+//	  -- The columns of the file list, in order: built-in columns by name,
+//	  -- or tables sizing them or defining custom columns; see Column.
+//	  "permissions", "size", { name = "modified", width = 12 }, "name",
+//	}
+//
+//	hooks = {
+//	  -- Functions (or lists of functions) called on events. Each gets the
+//	  -- same ctx as commands, may use the cute module and may return a
+//	  -- result like a command. on_file_op also gets an op table:
+//	  --   { name = "rm", paths = { ... }, error = "..." }
+//	  on_startup = function(ctx) end,
+//	  on_quit    = function(ctx) end,
+//	  on_cd      = function(ctx) end,
+//	  on_select  = function(ctx) end,
+//	  on_preview = function(ctx) end,
+//	  on_file_op = function(ctx, op) end,
+//	}
+//
 // Command invocation and result decoding are handled by the command package;
 // RuntimeConfig only stores the Lua state and registered functions.
 type RuntimeConfig struct {
 	// L is the Lua state backing this configuration.
 	L *lua.LState
 
+	// Path is the Lua file the configuration was loaded from, or empty when
+	// none was found.
+	Path string
+
 	// Theme is the fully-resolved TUI theme, produced from the Lua "theme"
-	// table (when present) layered over theming.DefaultTheme().
+	// table (when present) layered over theming.DefaultTheme(), or over the
+	// named theme it builds on.
 	Theme theming.Theme
+	// ThemeName is the name of the theme config.lua picks or builds on, or
+	// empty.
+	ThemeName string
+
+	// ImagePreview is the value of the global "image_preview" string, which
+	// overrides the automatically detected image preview protocol. It is
+	// empty when not set.
+	ImagePreview string
+
+	// CommandTimeout is how long Lua commands may run before they are
+	// stopped, from the global "command_timeout" in seconds. Zero means no
+	// limit.
+	CommandTimeout time.Duration
+
+	// Restricted is set when the configuration was loaded in restricted
+	// mode; see RestrictedEnv.
+	Restricted bool
+
+	// KeyBindings are the entries of the global "keys" table, in no
+	// particular order. They are validated against the modes and actions of
+	// the UI when the keymap is built.
+	KeyBindings []KeyBinding
+
+	// Columns are the entries of the global "columns" list, in order, or nil
+	// when config.lua does not set it and the built-in columns are shown.
+	Columns []Column
+
+	// Errors lists the problems found in the configuration, as *Error
+	// values: the error the file failed to load with, or the entries that
+	// were skipped, such as unknown theme keys or malformed key bindings.
+	Errors []error
 
 	// commands maps command names (as typed in the command bar) to the
 	// corresponding Lua function objects.
 	commands map[string]*lua.LFunction
+
+	// hooks maps hook names, such as "on_cd", to the Lua functions
+	// registered for them, in order.
+	hooks map[string][]*lua.LFunction
+
+	// previewers are the Lua functions that preview files; see Previewer.
+	previewers previewers
 }
 
 // Command looks up a user-defined command function by name.
@@ -70,75 +161,267 @@ func (rc *RuntimeConfig) Command(name string) *lua.LFunction {
 	return rc.commands[name]
 }
 
+// Hooks returns the Lua functions registered for the named hook.
+func (rc *RuntimeConfig) Hooks(name string) []*lua.LFunction {
+	if rc == nil {
+		return nil
+	}
+	return rc.hooks[name]
+}
+
 // LoadRuntimeConfig discovers and loads the Lua configuration for the given
 // config directory. It never falls back to TOML; if no Lua file can be found
 // or loaded, a RuntimeConfig with the default theme and no commands is
-// returned.
+// returned, with the load error in Errors.
 //
 // The search order for the Lua file is:
 //
 //  1. <configDir>/config.lua
 //  2. <binaryDir>/config/config.lua
 //  3. ./config/config.lua  (useful during development)
+//
+// The plugins of <configDir>/plugins are loaded after it; see plugin.
 func LoadRuntimeConfig(configDir string) *RuntimeConfig {
-	theme := theming.DefaultTheme()
-	rc := &RuntimeConfig{
-		Theme:    theme,
-		commands: map[string]*lua.LFunction{},
+	rc, err := ReloadRuntimeConfig(configDir)
+	if err != nil {
+		// If the Lua file fails to load, fall back to the default theme and
+		// no commands, but keep the error so it can be shown.
+		rc = defaultRuntimeConfig()
+		rc.Errors = []error{err}
 	}
+	return rc
+}
 
+// ReloadRuntimeConfig loads the Lua configuration like LoadRuntimeConfig, but
+// reports a file that fails to load, as an *Error, instead of falling back to
+// the defaults, so the configuration in use can be kept. The caller closes the
+// replaced configuration.
+func ReloadRuntimeConfig(configDir string) (*RuntimeConfig, error) {
 	path := findLuaConfigPath(configDir)
-	if path == "" {
-		return rc
+	plugins := findPlugins(configDir)
+	if path == "" && len(plugins) == 0 {
+		return defaultRuntimeConfig(), nil
 	}
 
 	L := lua.NewState()
+	for name, loader := range luaModules {
+		L.PreloadModule(name, loader)
+	}
+	isRestricted := restricted()
+	if isRestricted {
+		restrictLua(L, configDir)
+	}
 
-	if err := L.DoFile(path); err != nil {
-		// If the Lua file fails to load, fall back to the default theme and
-		// no commands, but still close the Lua state.
-		L.Close()
-		return rc
+	if path != "" {
+		if err := runLuaFile(L, path, nil); err != nil {
+			L.Close()
+			return nil, err
+		}
 	}
 
-	// Extract theme overrides from global "theme" table, if present.
-	overrides := map[string]string{}
-	if v := L.GetGlobal("theme"); v.Type() == lua.LTTable {
+	rc := defaultRuntimeConfig()
+	rc.Path = path
+
+	var errs []error
+
+	theme, themeName, themeErrs := parseTheme(L.GetGlobal("theme"), configDir, nil)
+	rc.Theme = theme
+	rc.ThemeName = themeName
+	errs = append(errs, themeErrs...)
+
+	if v, ok := L.GetGlobal("image_preview").(lua.LString); ok {
+		rc.ImagePreview = string(v)
+	}
+
+	switch v := L.GetGlobal("command_timeout").(type) {
+	case *lua.LNilType:
+	case lua.LNumber:
+		if v < 0 {
+			errs = append(errs, fmt.Errorf("command_timeout: expected a number of seconds, got %s", v))
+			break
+		}
+		rc.CommandTimeout = time.Duration(float64(v) * float64(time.Second))
+	default:
+		errs = append(errs, fmt.Errorf("command_timeout: expected a number of seconds, got %s", v.Type()))
+	}
+
+	if v := L.GetGlobal("columns"); v.Type() == lua.LTTable {
+		var columnErrs []error
+		rc.Columns, columnErrs = parseColumns(v.(*lua.LTable))
+		errs = append(errs, columnErrs...)
+	}
+
+	errs = append(errs, rc.loadDefinitions(L.GetGlobal, "")...)
+
+	settings, pluginErrs := parsePluginSettings(L.GetGlobal("plugins"), plugins)
+	errs = append(errs, pluginErrs...)
+
+	rc.AddErrors(sortErrors(errs)...)
+
+	// Plugins come after config.lua, so its definitions take precedence.
+	rc.loadPlugins(L, plugins, settings)
+	rc.previewers.sort()
+
+	rc.L = L
+	return rc, nil
+}
+
+// loadDefinitions reads the commands, hooks, previewers and key bindings
+// defined by config.lua or a plugin, looking their tables up with get.
+// Command names are given the prefix, which namespaces those of plugins.
+// Key bindings of keys that are already bound are dropped, so the ones of
+// config.lua win over those of plugins.
+func (rc *RuntimeConfig) loadDefinitions(get func(name string) lua.LValue, prefix string) []error {
+	var errs []error
+
+	// Extract user-defined commands from the "commands" table, if present.
+	if v := get("commands"); v.Type() == lua.LTTable {
 		tbl := v.(*lua.LTable)
 		tbl.ForEach(func(k, v lua.LValue) {
-			ks, ok1 := k.(lua.LString)
-			vs, ok2 := v.(lua.LString)
-			if !ok1 || !ok2 {
+			name, ok := k.(lua.LString)
+			if !ok {
+				errs = append(errs, fmt.Errorf("commands: names must be strings, got %s", k.Type()))
+				return
+			}
+			fn, ok := v.(*lua.LFunction)
+			if !ok {
+				errs = append(errs, fmt.Errorf("commands.%s: expected a function, got %s", name, v.Type()))
 				return
 			}
-			overrides[string(ks)] = string(vs)
+			rc.commands[prefix+string(name)] = fn
 		})
 	}
 
-	if len(overrides) > 0 {
-		rc.Theme = theming.LoadThemeFromMap(overrides)
-	}
-
-	// Extract user-defined commands from global "commands" table, if present.
-	if v := L.GetGlobal("commands"); v.Type() == lua.LTTable {
+	// Extract event hooks from the "hooks" table, if present. Each hook is
+	// a function or a list of functions.
+	if v := get("hooks"); v.Type() == lua.LTTable {
 		tbl := v.(*lua.LTable)
-		commands := map[string]*lua.LFunction{}
 		tbl.ForEach(func(k, v lua.LValue) {
 			name, ok := k.(lua.LString)
 			if !ok {
+				errs = append(errs, fmt.Errorf("hooks: names must be strings, got %s", k.Type()))
 				return
 			}
-			fn, ok := v.(*lua.LFunction)
-			if !ok {
+			if !slices.Contains(Hooks, string(name)) {
+				errs = append(errs, fmt.Errorf("hooks.%s: unknown hook%s", name, suggest(string(name), Hooks)))
 				return
 			}
-			commands[string(name)] = fn
+			switch v := v.(type) {
+			case *lua.LFunction:
+				rc.hooks[string(name)] = append(rc.hooks[string(name)], v)
+			case *lua.LTable:
+				for i := 1; i <= v.Len(); i++ {
+					fn, ok := v.RawGetInt(i).(*lua.LFunction)
+					if !ok {
+						errs = append(errs, fmt.Errorf("hooks.%s[%d]: expected a function, got %s", name, i, v.RawGetInt(i).Type()))
+						continue
+					}
+					rc.hooks[string(name)] = append(rc.hooks[string(name)], fn)
+				}
+			default:
+				errs = append(errs, fmt.Errorf("hooks.%s: expected a function or a list of functions, got %s", name, v.Type()))
+			}
 		})
-		rc.commands = commands
 	}
 
-	rc.L = L
-	return rc
+	if v := get("previewers"); v.Type() == lua.LTTable {
+		errs = append(errs, rc.previewers.add(v.(*lua.LTable))...)
+	}
+
+	if v := get("keys"); v.Type() == lua.LTTable {
+		bindings, keyErrs := parseKeyBindings(v.(*lua.LTable))
+		errs = append(errs, keyErrs...)
+		if rc.Restricted {
+			bindings, keyErrs = restrictKeyBindings(bindings)
+			errs = append(errs, keyErrs...)
+		}
+		for _, b := range bindings {
+			bound := slices.ContainsFunc(rc.KeyBindings, func(o KeyBinding) bool {
+				return o.Mode == b.Mode && o.Keys == b.Keys
+			})
+			if !bound {
+				rc.KeyBindings = append(rc.KeyBindings, b)
+			}
+		}
+	}
+
+	return errs
+}
+
+// runLuaFile runs the Lua file at path in L, with env as its global
+// environment when it is not nil. A file that fails, or never finishes, e.g.
+// one stuck in a loop, is reported as an *Error rather than stopping the
+// file manager.
+func runLuaFile(L *lua.LState, path string, env *lua.LTable) *Error {
+	fn, err := L.LoadFile(path)
+	if err != nil {
+		return luaError(path, err)
+	}
+	if env != nil {
+		fn.Env = env
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+
+	top := L.GetTop()
+	defer L.SetTop(top)
+	L.Push(fn)
+	if err := L.PCall(0, lua.MultRet, nil); err != nil {
+		cfgErr := luaError(path, err)
+		if ctx.Err() == context.DeadlineExceeded {
+			cfgErr.Message = fmt.Sprintf("did not finish loading within %s", loadTimeout)
+		}
+		return cfgErr
+	}
+	return nil
+}
+
+// loadTimeout is how long config.lua, or a plugin, may take to run when it
+// is loaded.
+const loadTimeout = 5 * time.Second
+
+// DefaultCommandTimeout is how long Lua commands may run unless config.lua
+// sets command_timeout.
+const DefaultCommandTimeout = 30 * time.Second
+
+// defaultRuntimeConfig returns the configuration used without a Lua file.
+func defaultRuntimeConfig() *RuntimeConfig {
+	return &RuntimeConfig{
+		Theme:          theming.DefaultTheme(),
+		CommandTimeout: DefaultCommandTimeout,
+		Restricted:     restricted(),
+		commands:       map[string]*lua.LFunction{},
+		hooks:          map[string][]*lua.LFunction{},
+		previewers:     previewers{exts: map[string]*lua.LFunction{}},
+	}
+}
+
+// Close releases the Lua state of the configuration. Its commands, hooks and
+// key bindings cannot be called afterwards.
+func (rc *RuntimeConfig) Close() {
+	if rc != nil && rc.L != nil {
+		rc.L.Close()
+	}
+}
+
+// ConfigPath returns the Lua file LoadRuntimeConfig would load for the given
+// config directory, or an empty string when there is none.
+func ConfigPath(configDir string) string {
+	return findLuaConfigPath(configDir)
+}
+
+// luaModules holds the Lua modules registered with RegisterModule.
+var luaModules = map[string]lua.LGFunction{}
+
+// RegisterModule makes a Lua module available to the configuration, so that
+// config.lua can load it with require(name). Modules are preloaded into every
+// Lua state created by LoadRuntimeConfig; packages that implement them
+// register them from an init function.
+func RegisterModule(name string, loader lua.LGFunction) {
+	luaModules[name] = loader
 }
 
 // findLuaConfigPath returns the first existing Lua config file path following
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// Plugins live in the plugins directory of the config directory, either as
+// a single file or as a directory with an init.lua:
+//
+//	<configDir>/plugins/git.lua
+//	<configDir>/plugins/preview-extras/init.lua
+//
+// A plugin is named after its file or directory. It is loaded after
+// config.lua, in the same Lua state, but with globals of its own: it defines
+// commands, hooks, previewers and keys tables like config.lua does, and reads
+// its settings from the global settings table. Its commands are named
+// "<plugin>.<command>", e.g. :git.blame. The globals of config.lua can still
+// be read.
+//
+// config.lua passes settings to plugins, or disables them, with its plugins
+// table:
+//
+//	plugins = {
+//	  git = { show_untracked = false }, -- settings of the git plugin
+//	  ["preview-extras"] = false,       -- not loaded
+//	}
+type plugin struct {
+	name string
+	path string
+}
+
+// pluginsDir returns the directory plugins are loaded from, or an empty
+// string when there is no config directory.
+func pluginsDir(configDir string) string {
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "plugins")
+}
+
+// findPlugins lists the plugins of the config directory, sorted by name. Of
+// a file and a directory with the same name, the file is used.
+func findPlugins(configDir string) []plugin {
+	dir := pluginsDir(configDir)
+	if dir == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var (
+		plugins []plugin
+		dirs    = map[string]string{}
+	)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if e.IsDir() {
+			if _, err := os.Stat(filepath.Join(path, "init.lua")); err == nil {
+				dirs[e.Name()] = filepath.Join(path, "init.lua")
+			}
+			continue
+		}
+		if name, ok := strings.CutSuffix(e.Name(), ".lua"); ok && name != "" {
+			plugins = append(plugins, plugin{name: name, path: path})
+		}
+	}
+	for name, path := range dirs {
+		if !slices.ContainsFunc(plugins, func(p plugin) bool { return p.name == name }) {
+			plugins = append(plugins, plugin{name: name, path: path})
+		}
+	}
+
+	slices.SortFunc(plugins, func(a, b plugin) int { return strings.Compare(a.name, b.name) })
+	return plugins
+}
+
+// PluginPaths returns the files of the plugins of the config directory, so
+// changes to them can be watched.
+func PluginPaths(configDir string) []string {
+	var paths []string
+	for _, p := range findPlugins(configDir) {
+		paths = append(paths, p.path)
+	}
+	return paths
+}
+
+// parsePluginSettings reads the plugins table of config.lua: the settings
+// table of each plugin, or false for the plugins not to load. Plugins it
+// does not mention get empty settings.
+func parsePluginSettings(v lua.LValue, plugins []plugin) (map[string]lua.LValue, []error) {
+	settings := map[string]lua.LValue{}
+	tbl, ok := v.(*lua.LTable)
+	if !ok {
+		if v != lua.LNil {
+			return settings, []error{fmt.Errorf("plugins: expected a table, got %s", v.Type())}
+		}
+		return settings, nil
+	}
+
+	names := make([]string, len(plugins))
+	for i, p := range plugins {
+		names[i] = p.name
+	}
+
+	var errs []error
+	tbl.ForEach(func(k, v lua.LValue) {
+		name, ok := k.(lua.LString)
+		if !ok {
+			errs = append(errs, fmt.Errorf("plugins: names must be strings, got %s", k.Type()))
+			return
+		}
+		if !slices.Contains(names, string(name)) {
+			errs = append(errs, fmt.Errorf("plugins.%s: no plugin of that name is installed%s", name, suggest(string(name), names)))
+			return
+		}
+		switch v := v.(type) {
+		case *lua.LTable:
+			settings[string(name)] = v
+		case lua.LBool:
+			if v {
+				errs = append(errs, fmt.Errorf("plugins.%s: expected a settings table or false, got true", name))
+				return
+			}
+			settings[string(name)] = v
+		default:
+			errs = append(errs, fmt.Errorf("plugins.%s: expected a settings table or false, got %s", name, v.Type()))
+		}
+	})
+	return settings, errs
+}
+
+// loadPlugins runs the plugins and adds their definitions. A plugin that
+// fails to load is skipped; its error, and the problems found in what it
+// defines, are reported with its file.
+func (rc *RuntimeConfig) loadPlugins(L *lua.LState, plugins []plugin, settings map[string]lua.LValue) {
+	for _, p := range plugins {
+		if settings[p.name] == lua.LFalse {
+			continue
+		}
+
+		env := pluginEnv(L, p, settings[p.name])
+		if err := runLuaFile(L, p.path, env); err != nil {
+			rc.Errors = append(rc.Errors, err)
+			continue
+		}
+
+		for _, err := range sortErrors(rc.loadDefinitions(env.RawGetString, p.name+".")) {
+			rc.Errors = append(rc.Errors, &Error{File: p.path, Message: err.Error()})
+		}
+	}
+}
+
+// pluginEnv returns the table of globals of a plugin. Globals it does not
+// define are looked up in those of config.lua.
+func pluginEnv(L *lua.LState, p plugin, settings lua.LValue) *lua.LTable {
+	env := L.NewTable()
+	meta := L.NewTable()
+	meta.RawSetString("__index", L.Get(lua.GlobalsIndex))
+	L.SetMetatable(env, meta)
+
+	if settings == nil {
+		settings = L.NewTable()
+	}
+	env.RawSetString("settings", settings)
+
+	info := L.NewTable()
+	info.RawSetString("name", lua.LString(p.name))
+	info.RawSetString("dir", lua.LString(filepath.Dir(p.path)))
+	env.RawSetString("plugin", info)
+	return env
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// previewers holds the functions of the previewers table in config.lua, by
+// the kind of key they were registered under:
+//
+//	previewers = {
+//	  pb                         = function(ctx, file) end, -- extension
+//	  ["*.sqlite"]               = function(ctx, file) end, -- glob on the name
+//	  ["application/x-sqlite3"]  = function(ctx, file) end, -- MIME type
+//	  ["text/*"]                 = function(ctx, file) end, -- MIME type family
+//	}
+//
+// See the tui package for what the functions return.
+type previewers struct {
+	// exts maps lower-case extensions, without the dot, to functions.
+	exts map[string]*lua.LFunction
+	// globs are matched against the base name, longest pattern first.
+	globs []patternPreviewer
+	// mimes are matched against the MIME type, exact types before families
+	// such as "text/*".
+	mimes []patternPreviewer
+}
+
+type patternPreviewer struct {
+	pattern string
+	fn      *lua.LFunction
+}
+
+// add reads a previewers table. Entries that cannot be used are skipped and
+// reported. Extensions that already have a previewer keep it; globs and MIME
+// types are only tried in order once sort is called.
+func (p *previewers) add(tbl *lua.LTable) []error {
+	var errs []error
+
+	tbl.ForEach(func(k, v lua.LValue) {
+		key, ok := k.(lua.LString)
+		if !ok {
+			errs = append(errs, fmt.Errorf("previewers: keys must be strings, got %s", k.Type()))
+			return
+		}
+		fn, ok := v.(*lua.LFunction)
+		if !ok {
+			errs = append(errs, fmt.Errorf("previewers[%q]: expected a function, got %s", string(key), v.Type()))
+			return
+		}
+
+		pattern := string(key)
+		switch {
+		case strings.Contains(pattern, "/"):
+			p.mimes = append(p.mimes, patternPreviewer{strings.ToLower(pattern), fn})
+		case strings.ContainsAny(pattern, "*?["):
+			if _, err := filepath.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("previewers[%q]: %w", pattern, err))
+				return
+			}
+			p.globs = append(p.globs, patternPreviewer{pattern, fn})
+		default:
+			ext := strings.ToLower(strings.TrimPrefix(pattern, "."))
+			if p.exts[ext] == nil {
+				p.exts[ext] = fn
+			}
+		}
+	})
+
+	return errs
+}
+
+// sort orders the globs and MIME types in the order they are tried. The
+// sort is stable, so of two equal patterns the one added first wins.
+func (p *previewers) sort() {
+	slices.SortStableFunc(p.globs, func(a, b patternPreviewer) int {
+		return cmp.Or(cmp.Compare(len(b.pattern), len(a.pattern)), strings.Compare(a.pattern, b.pattern))
+	})
+	slices.SortStableFunc(p.mimes, func(a, b patternPreviewer) int {
+		aFamily, bFamily := strings.HasSuffix(a.pattern, "/*"), strings.HasSuffix(b.pattern, "/*")
+		if aFamily != bFamily {
+			if aFamily {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(a.pattern, b.pattern)
+	})
+}
+
+// Previewer returns the Lua function registered to preview the file at
+// path, or nil when there is none. Extensions are tried first, then globs,
+// then MIME types; mimeType is only called when MIME types are registered,
+// since detecting one may read the file.
+func (rc *RuntimeConfig) Previewer(path string, mimeType func() string) *lua.LFunction {
+	if rc == nil {
+		return nil
+	}
+	p := rc.previewers
+
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != "" {
+		if fn := p.exts[strings.ToLower(ext)]; fn != nil {
+			return fn
+		}
+	}
+
+	name := filepath.Base(path)
+	for _, g := range p.globs {
+		if ok, _ := filepath.Match(g.pattern, name); ok {
+			return g.fn
+		}
+	}
+
+	if len(p.mimes) == 0 {
+		return nil
+	}
+	mt, _, _ := strings.Cut(strings.ToLower(mimeType()), ";")
+	mt = strings.TrimSpace(mt)
+	if mt == "" {
+		return nil
+	}
+	for _, m := range p.mimes {
+		family, isFamily := strings.CutSuffix(m.pattern, "/*")
+		if m.pattern == mt || isFamily && strings.HasPrefix(mt, family+"/") {
+			return m.fn
+		}
+	}
+	return nil
+}
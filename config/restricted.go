@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// RestrictedEnv is the environment variable that loads the configuration in
+// restricted mode when set to anything but "" or "0". Restricted mode is
+// meant for configurations shared by a team: their Lua code can drive the
+// file manager, but cannot run programs or read and write files itself, and
+// can only require Lua modules from the config directory.
+const RestrictedEnv = "CUTE_RESTRICTED"
+
+// restrictedOsFuncs are the functions of the os library removed in
+// restricted mode.
+var restrictedOsFuncs = []string{"execute", "exit", "remove", "rename", "setenv", "tmpname"}
+
+// restrictedBaseFuncs are the functions of the base library removed in
+// restricted mode, which run or load any file; a file that fails to parse
+// would also show part of its contents in the error.
+var restrictedBaseFuncs = []string{"dofile", "loadfile"}
+
+// restricted reports whether RestrictedEnv asks for restricted mode.
+func restricted() bool {
+	v := os.Getenv(RestrictedEnv)
+	return v != "" && v != "0"
+}
+
+// restrictLua removes the io library, the functions of the os library that
+// run programs or change files and the base functions that load files from
+// L, before any configuration code runs in it. require only finds Lua modules
+// in configDir, whatever package.path is set to.
+func restrictLua(L *lua.LState, configDir string) {
+	L.SetGlobal(lua.IoLibName, lua.LNil)
+	for _, name := range restrictedBaseFuncs {
+		L.SetGlobal(name, lua.LNil)
+	}
+	if pkg, ok := L.GetGlobal(lua.LoadLibName).(*lua.LTable); ok {
+		if loaded, ok := pkg.RawGetString("loaded").(*lua.LTable); ok {
+			loaded.RawSetString(lua.IoLibName, lua.LNil)
+		}
+		pkg.RawSetString("path", lua.LString(filepath.Join(configDir, "?.lua")))
+		pkg.RawSetString("cpath", lua.LString(""))
+	}
+	// require goes through the searchers kept in the registry, so replacing
+	// the one that reads package.path there also covers a configuration that
+	// changes package.path or package.loaders afterwards.
+	if loaders, ok := L.GetField(L.Get(lua.RegistryIndex), "_LOADERS").(*lua.LTable); ok {
+		loaders.RawSetInt(2, L.NewFunction(func(L *lua.LState) int {
+			return loadConfigModule(L, configDir)
+		}))
+	}
+
+	if osLib, ok := L.GetGlobal(lua.OsLibName).(*lua.LTable); ok {
+		for _, name := range restrictedOsFuncs {
+			osLib.RawSetString(name, lua.LNil)
+		}
+	}
+}
+
+// loadConfigModule is the require searcher of restricted mode: it loads the
+// module named by the first argument from <configDir>/<name>.lua, with the
+// dots of the name as directory separators like package.path. Since every
+// dot is replaced, the name cannot climb out of configDir.
+func loadConfigModule(L *lua.LState, configDir string) int {
+	name := strings.ReplaceAll(L.CheckString(1), ".", string(os.PathSeparator))
+	if configDir == "" {
+		L.Push(lua.LString("no config directory for Lua modules"))
+		return 1
+	}
+	path := filepath.Join(configDir, name+".lua")
+	if _, err := os.Stat(path); err != nil {
+		L.Push(lua.LString("no file '" + path + "'"))
+		return 1
+	}
+	fn, err := L.LoadFile(path)
+	if err != nil {
+		L.RaiseError("%s", err.Error())
+	}
+	L.Push(fn)
+	return 1
+}
+
+// restrictKeyBindings drops the key bindings that run shell commands, which
+// restricted mode does not allow.
+func restrictKeyBindings(bindings []KeyBinding) ([]KeyBinding, []error) {
+	var (
+		kept []KeyBinding
+		errs []error
+	)
+	for _, b := range bindings {
+		if b.Shell != "" {
+			errs = append(errs, fmt.Errorf("keys.%s[%q]: shell commands are not available in restricted mode", b.Mode, b.Keys))
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept, errs
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func TestRestrictLua(t *testing.T) {
+	root := t.TempDir()
+	configDir := filepath.Join(root, "cute")
+	if err := os.MkdirAll(filepath.Join(configDir, "lib"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		filepath.Join(configDir, "mod.lua"):        `return "mod"`,
+		filepath.Join(configDir, "lib", "sub.lua"): `return "sub"`,
+		filepath.Join(root, "outside.lua"):         `return "outside"`,
+	}
+	for path, src := range files {
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		code string
+		ok   bool
+	}{
+		{"io", `assert(io == nil)`, true},
+		{"os.execute", `assert(os.execute == nil)`, true},
+		{"dofile", `assert(dofile == nil and loadfile == nil)`, true},
+		{"loadstring", `assert(loadstring("return 1")() == 1)`, true},
+		{"module", `assert(require("mod") == "mod")`, true},
+		{"nested module", `assert(require("lib.sub") == "sub")`, true},
+		{"parent", `require("..outside")`, false},
+		{"absolute", `require(` + strconv.Quote(filepath.Join(root, "outside")) + `)`, false},
+		{"package.path", `package.path = ` + strconv.Quote(filepath.Join(root, "?.lua")) + `; require("outside")`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			L := lua.NewState()
+			defer L.Close()
+			restrictLua(L, configDir)
+
+			err := L.DoString(tt.code)
+			if tt.ok && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.ok && err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestThemeStateCannotLoadFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outside.lua")
+	if err := os.WriteFile(path, []byte(`return "outside"`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{
+		`return dofile(` + strconv.Quote(path) + `)`,
+		`return loadfile(` + strconv.Quote(path) + `)()`,
+		`return require("outside")`,
+	} {
+		L := newThemeState()
+		if err := L.DoString(code); err == nil {
+			t.Errorf("%s: expected an error", code)
+		}
+		L.Close()
+	}
+}
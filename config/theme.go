@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/theming"
+)
+
+// parseTheme reads the theme of config.lua, or of a theme file: the name of
+// a theme (see LoadNamedTheme), or a table over the default theme or over
+// the theme named by its base key. Its keys mirror theming.Theme, with
+// sections as nested tables; see theming.ThemeSectionKeys. Problems are
+// reported per key, which is then left as it was. It also returns the name
+// of the theme built on, if any. bases are the themes being loaded that
+// build on this one.
+func parseTheme(v lua.LValue, configDir string, bases []string) (theming.Theme, string, []error) {
+	switch v := v.(type) {
+	case *lua.LNilType:
+		return theming.DefaultTheme(), "", nil
+	case lua.LString:
+		theme, errs := loadNamedTheme(configDir, string(v), bases)
+		return theme, string(v), errs
+	case *lua.LTable:
+		theme, name := theming.DefaultTheme(), ""
+		var errs []error
+		switch base := v.RawGetString("base").(type) {
+		case *lua.LNilType:
+		case lua.LString:
+			name = string(base)
+			theme, errs = loadNamedTheme(configDir, name, bases)
+		default:
+			errs = append(errs, fmt.Errorf("theme.base: expected the name of a theme, got %s", base.Type()))
+		}
+		return theme, name, append(errs, parseThemeSection(&theme, v, nil)...)
+	}
+	return theming.DefaultTheme(), "", []error{fmt.Errorf("theme: expected a table or the name of a theme, got %s", v.Type())}
+}
+
+// parseThemeSection sets the keys of the theme table at path.
+func parseThemeSection(theme *theming.Theme, tbl *lua.LTable, path []string) []error {
+	known := theming.ThemeSectionKeys(path)
+	if len(path) == 0 {
+		known = append(known, "base")
+	}
+	name := strings.Join(append([]string{"theme"}, path...), ".")
+
+	var errs []error
+	tbl.ForEach(func(k, v lua.LValue) {
+		key, ok := k.(lua.LString)
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: keys must be strings, got %s", name, k.Type()))
+			return
+		}
+
+		if len(path) == 0 && key == "base" {
+			// Read by parseTheme.
+			return
+		}
+
+		keyPath := append(append([]string(nil), path...), string(key))
+		kind, ok := theming.ThemeKeyKind(keyPath)
+		if !ok {
+			hint := suggest(string(key), known)
+			if hint == "" && len(path) > 0 {
+				hint = fmt.Sprintf(" (expected one of %s)", strings.Join(known, ", "))
+			}
+			errs = append(errs, fmt.Errorf("%s.%s: unknown key%s", name, key, hint))
+			return
+		}
+
+		var err error
+		switch kind {
+		case theming.ThemeSection:
+			section, ok := v.(*lua.LTable)
+			if !ok {
+				err = fmt.Errorf("expected a table, got %s", v.Type())
+				break
+			}
+			errs = append(errs, parseThemeSection(theme, section, keyPath)...)
+		case theming.ThemeNumber:
+			n, ok := v.(lua.LNumber)
+			if !ok || float64(n) != math.Trunc(float64(n)) {
+				err = fmt.Errorf("expected a whole number of cells, got %s", v)
+				break
+			}
+			err = theme.SetNumber(keyPath, int(n))
+		default:
+			s, ok := v.(lua.LString)
+			if !ok {
+				err = fmt.Errorf("expected a %s string, got %s", kind, v.Type())
+				break
+			}
+			err = theme.SetString(keyPath, string(s))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s.%s: %w", name, key, err))
+		}
+	})
+	return errs
+}
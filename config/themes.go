@@ -0,0 +1,152 @@
+package config
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/theming"
+)
+
+// Themes are Lua files returning a theme table like the one of config.lua,
+// named after the file:
+//
+//	-- <configDir>/themes/dusk.lua
+//	return {
+//	  base = "dark",
+//	  primary = "#E37CFF",
+//	  status_bar = { background = "#1E1E1E" },
+//	}
+//
+// A few themes are bundled; a file in the themes directory replaces the
+// bundled theme of the same name. config.lua picks a theme with
+// theme = "name", or builds on one with a base key in its theme table.
+//
+//go:embed themes/*.lua
+var bundledThemes embed.FS
+
+// themesDir returns the directory theme files are loaded from, or an empty
+// string when there is no config directory.
+func themesDir(configDir string) string {
+	if configDir == "" {
+		return ""
+	}
+	return filepath.Join(configDir, "themes")
+}
+
+// ThemeNames returns the names of the bundled themes and of those in the
+// themes directory, sorted.
+func ThemeNames(configDir string) []string {
+	var names []string
+	paths, _ := fs.Glob(bundledThemes, "themes/*.lua")
+	if dir := themesDir(configDir); dir != "" {
+		files, _ := filepath.Glob(filepath.Join(dir, "*.lua"))
+		paths = append(paths, files...)
+	}
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".lua")
+		if name != "" && !strings.HasPrefix(name, ".") && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// LoadNamedTheme loads the theme of the given name over the default theme.
+// Problems in the theme file are reported with its file; an unknown name
+// leaves the default theme.
+func LoadNamedTheme(configDir, name string) (theming.Theme, []error) {
+	return loadNamedTheme(configDir, name, nil)
+}
+
+// loadNamedTheme loads a theme, following the bases named so far by the
+// themes built on it.
+func loadNamedTheme(configDir, name string, bases []string) (theming.Theme, []error) {
+	if slices.Contains(bases, name) {
+		return theming.DefaultTheme(), []error{fmt.Errorf("theme %q is its own base", name)}
+	}
+	bases = append(bases, name)
+
+	var (
+		path string
+		src  io.ReadCloser
+		err  error
+	)
+	if dir := themesDir(configDir); dir != "" && !strings.ContainsAny(name, `/\`) {
+		path = filepath.Join(dir, name+".lua")
+		if f, err := os.Open(path); err == nil {
+			src = f
+		}
+	}
+	if src == nil {
+		path = "themes/" + name + ".lua"
+		src, err = bundledThemes.Open(path)
+		path = "bundled " + path
+	}
+	if err != nil {
+		return theming.DefaultTheme(), []error{fmt.Errorf("theme %q not found%s", name, suggest(name, ThemeNames(configDir)))}
+	}
+	defer src.Close()
+
+	L := newThemeState()
+	defer L.Close()
+
+	fn, err := L.Load(src, path)
+	if err != nil {
+		return theming.DefaultTheme(), []error{luaError(path, err)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	L.Push(fn)
+	if err := L.PCall(0, 1, nil); err != nil {
+		cfgErr := luaError(path, err)
+		if ctx.Err() == context.DeadlineExceeded {
+			cfgErr.Message = fmt.Sprintf("did not finish loading within %s", loadTimeout)
+		}
+		return theming.DefaultTheme(), []error{cfgErr}
+	}
+	L.RemoveContext()
+
+	theme, _, errs := parseTheme(L.Get(-1), configDir, bases)
+	for i, err := range errs {
+		if _, ok := err.(*Error); !ok {
+			errs[i] = &Error{File: path, Message: err.Error()}
+		}
+	}
+	return theme, errs
+}
+
+// newThemeState returns a Lua state for running a theme file, which only
+// describes colors and so gets the libraries that cannot reach outside: the
+// base library without the functions that load files, and no package
+// library for require to search with.
+func newThemeState() *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range restrictedBaseFuncs {
+		L.SetGlobal(name, lua.LNil)
+	}
+	return L
+}
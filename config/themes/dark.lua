@@ -0,0 +1,2 @@
+-- The default theme: light text on the terminal's dark background.
+return {}
@@ -0,0 +1,61 @@
+-- Pure colors on black, for readability over looks.
+return {
+  foreground = "#FFFFFF",
+  background = "#000000",
+  primary = "#FFFF00",
+  secondary = "#00FFFF",
+  border_color = "#FFFFFF",
+
+  command_bar = { background = "#000000", foreground = "#FFFFFF", border = "#FFFF00", placeholder = "#C0C0C0" },
+  search_bar = { background = "#000000", foreground = "#FFFFFF", border = "#FFFFFF", placeholder = "#C0C0C0" },
+  dialog = { background = "#000000", foreground = "#FFFFFF", border = "#FFFF00", title = "#FFFF00" },
+  file_list = { background = "#000000", foreground = "#FFFFFF" },
+  preview = { background = "#000000", foreground = "#FFFFFF", border = "#FFFFFF" },
+  status_bar = { background = "#000000", foreground = "#FFFFFF" },
+  current_dir = { background = "#000000", foreground = "#FFFFFF" },
+  header = { background = "#000000" },
+  view_mode = { background = "#000000", foreground = "#FFFFFF" },
+  selection = { background = "#FFFF00", foreground = "#000000" },
+
+  preview_tab = { background = "#000000", foreground = "#C0C0C0" },
+  preview_tab_active = { background = "#FFFF00", foreground = "#000000" },
+  search_match = { background = "#00FFFF", foreground = "#000000" },
+  search_match_current = { background = "#FFFF00", foreground = "#000000" },
+
+  file_type_colors = {
+    directory = "#00FFFF",
+    executable = "#00FF00",
+    symlink = "#FF00FF",
+    socket = "#FFFF00",
+    pipe = "#FFFF00",
+    device = "#FF00FF",
+    regular = "#FFFFFF",
+  },
+  field_colors = { user = "#FFFF00", group = "#FFFF00", size = "#00FF00", time = "#FFFFFF", nlink = "#FFFFFF" },
+  permissions = { read = "#00FF00", write = "#FFFF00", exec = "#FF5555", none = "#C0C0C0" },
+
+  tui_mode = {
+    normal_mode_background = "#FFFF00", normal_mode_foreground = "#000000",
+    command_mode_background = "#00FFFF", command_mode_foreground = "#000000",
+    filter_mode_background = "#FFFF00", filter_mode_foreground = "#000000",
+    help_mode_background = "#00FF00", help_mode_foreground = "#000000",
+    quit_mode_background = "#FF0000", quit_mode_foreground = "#FFFFFF",
+    preview_mode_background = "#FFFFFF", preview_mode_foreground = "#000000",
+  },
+
+  syntax = {
+    attribute = "#FFFF00",
+    builtin = "#FF00FF",
+    comment = "#C0C0C0+italic",
+    constant = "#FF00FF",
+    ["function"] = "#00FFFF+bold",
+    keyword = "#FFFF00+bold",
+    number = "#FF00FF",
+    operator = "#FFFFFF",
+    punctuation = "#FFFFFF",
+    string = "#00FF00",
+    tag = "#FFFF00",
+    type = "#00FFFF",
+  },
+  hex = { offset = "#C0C0C0", null = "#808080", printable = "#FFFFFF", control = "#FFFF00", extended = "#00FFFF" },
+}
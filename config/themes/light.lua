@@ -0,0 +1,59 @@
+-- Dark text for terminals with a light background.
+return {
+  foreground = "#2E2A2C",
+  primary = "#C2185B",
+  secondary = "#8A6D00",
+  border_color = "#C2185B",
+
+  command_bar = { foreground = "#2E2A2C", border = "#C2185B", placeholder = "#8C8A8B" },
+  search_bar = { foreground = "#2E2A2C", border = "#C2185B", placeholder = "#B8B4B6" },
+  dialog = { foreground = "#2E2A2C", border = "#C2185B", title = "#1565C0" },
+  file_list = { foreground = "#2E2A2C" },
+  preview = { foreground = "#2E2A2C", border = "#C2185B" },
+  status_bar = { foreground = "#2E2A2C" },
+  current_dir = { foreground = "#2E2A2C" },
+  view_mode = { foreground = "#2E2A2C" },
+  selection = { background = "#E4DDE1" },
+
+  preview_tab = { foreground = "#8C8A8B" },
+  preview_tab_active = { background = "#C2185B", foreground = "#FFFFFF" },
+  search_match = { background = "#FFE082", foreground = "#2E2A2C" },
+  search_match_current = { background = "#C2185B", foreground = "#FFFFFF" },
+
+  file_type_colors = {
+    directory = "#1565C0",
+    executable = "#C2185B",
+    symlink = "#7B1FA2",
+    socket = "#8A6D00",
+    pipe = "#00796B",
+    device = "#7B1FA2",
+    regular = "#2E2A2C",
+  },
+  field_colors = { user = "#8A6D00", group = "#8A6D00", size = "#C2185B", time = "#2E2A2C", nlink = "#2E2A2C" },
+  permissions = { read = "#00796B", write = "#7B1FA2", exec = "#C2185B", none = "#8C8A8B" },
+
+  tui_mode = {
+    normal_mode_background = "#C2185B", normal_mode_foreground = "#FFFFFF",
+    command_mode_background = "#7B1FA2", command_mode_foreground = "#FFFFFF",
+    filter_mode_background = "#C2185B", filter_mode_foreground = "#FFFFFF",
+    help_mode_background = "#00796B", help_mode_foreground = "#FFFFFF",
+    quit_mode_background = "#2E2A2C", quit_mode_foreground = "#FFFFFF",
+    preview_mode_background = "#1565C0", preview_mode_foreground = "#FFFFFF",
+  },
+
+  syntax = {
+    attribute = "#8A6D00",
+    builtin = "#7B1FA2",
+    comment = "#8C8A8B+italic",
+    constant = "#C2185B",
+    ["function"] = "#1565C0",
+    keyword = "#C2185B+bold",
+    number = "#C2185B",
+    operator = "#7B1FA2",
+    punctuation = "#2E2A2C",
+    string = "#2E7D32",
+    tag = "#C2185B",
+    type = "#00796B",
+  },
+  hex = { offset = "#8C8A8B", null = "#B8B4B6", printable = "#2E2A2C", control = "#7B1FA2", extended = "#8A6D00" },
+}
@@ -0,0 +1,66 @@
+-- Solarized-like colors on the dark base03 background.
+local base03, base02 = "#002B36", "#073642"
+local base01, base0, base1 = "#586E75", "#839496", "#93A1A1"
+local yellow, orange, red, magenta = "#B58900", "#CB4B16", "#DC322F", "#D33682"
+local violet, blue, cyan, green = "#6C71C4", "#268BD2", "#2AA198", "#859900"
+
+return {
+  foreground = base0,
+  background = base03,
+  primary = blue,
+  secondary = yellow,
+  border_color = base01,
+
+  command_bar = { background = base03, foreground = base1, border = blue, placeholder = base01 },
+  search_bar = { background = base03, foreground = base1, border = base01, placeholder = base01 },
+  dialog = { background = base03, foreground = base0, border = blue, title = yellow },
+  file_list = { background = base03, foreground = base0 },
+  preview = { background = base03, foreground = base0, border = base01 },
+  status_bar = { background = base02, foreground = base1 },
+  current_dir = { background = base03, foreground = base1 },
+  header = { background = base03 },
+  view_mode = { background = base02, foreground = base1 },
+  selection = { background = base02, foreground = base1 },
+
+  preview_tab = { background = base03, foreground = base01 },
+  preview_tab_active = { background = blue, foreground = base03 },
+  search_match = { background = yellow, foreground = base03 },
+  search_match_current = { background = orange, foreground = base03 },
+
+  file_type_colors = {
+    directory = blue,
+    executable = green,
+    symlink = cyan,
+    socket = magenta,
+    pipe = yellow,
+    device = violet,
+    regular = base0,
+  },
+  field_colors = { user = yellow, group = yellow, size = cyan, time = base01, nlink = base01 },
+  permissions = { read = green, write = yellow, exec = red, none = base01 },
+
+  tui_mode = {
+    normal_mode_background = blue, normal_mode_foreground = base03,
+    command_mode_background = violet, command_mode_foreground = base03,
+    filter_mode_background = yellow, filter_mode_foreground = base03,
+    help_mode_background = cyan, help_mode_foreground = base03,
+    quit_mode_background = red, quit_mode_foreground = base03,
+    preview_mode_background = green, preview_mode_foreground = base03,
+  },
+
+  syntax = {
+    attribute = yellow,
+    builtin = violet,
+    comment = base01 .. "+italic",
+    constant = cyan,
+    ["function"] = blue,
+    keyword = green .. "+bold",
+    number = magenta,
+    operator = base0,
+    punctuation = base0,
+    string = cyan,
+    tag = blue,
+    type = yellow,
+  },
+  hex = { offset = base01, null = base02, printable = base0, control = orange, extended = violet },
+}
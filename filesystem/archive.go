@@ -0,0 +1,253 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// MaxArchiveEntries bounds how many entries ListArchive returns so huge
+// archives cannot stall the preview.
+const MaxArchiveEntries = 10000
+
+// Archive formats recognised by DetectArchive.
+const (
+	ArchiveZip      = "zip"
+	ArchiveTar      = "tar"
+	ArchiveTarGzip  = "tar.gz"
+	ArchiveTarXz    = "tar.xz"
+	ArchiveTarZstd  = "tar.zst"
+	ArchiveSevenZip = "7z"
+)
+
+// ErrNotArchive is returned by ListArchive for files that are not an archive
+// in one of the supported formats.
+var ErrNotArchive = errors.New("not a supported archive")
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	gzipMagic     = []byte{0x1f, 0x8b}
+	xzMagic       = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	zstdMagic     = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	sevenZipMagic = []byte{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}
+)
+
+// ArchiveEntry describes a single member of an archive.
+type ArchiveEntry struct {
+	Name string
+	// Size is the uncompressed size in bytes.
+	Size int64
+	// CompressedSize is the stored size in bytes, or -1 when the format does
+	// not record it per entry (e.g. compressed tarballs and solid 7z blocks).
+	CompressedSize int64
+	Mode           os.FileMode
+	ModTime        time.Time
+	IsDir          bool
+	LinkTarget     string
+}
+
+// ArchiveListing is the table of contents of an archive.
+type ArchiveListing struct {
+	Format  string
+	Entries []ArchiveEntry
+	// Truncated reports that the archive has more than MaxArchiveEntries
+	// entries and only the first ones were listed.
+	Truncated bool
+}
+
+// DetectArchive returns the archive format of the file at path, judged by its
+// content rather than its name, or "" if it is not a supported archive.
+// Compressed streams only count as archives when they contain a tarball.
+func DetectArchive(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, zipEmptyMagic):
+		return ArchiveZip
+	case bytes.HasPrefix(head, sevenZipMagic):
+		return ArchiveSevenZip
+	case isTarHeader(head):
+		return ArchiveTar
+	}
+
+	format := ""
+	switch {
+	case bytes.HasPrefix(head, gzipMagic):
+		format = ArchiveTarGzip
+	case bytes.HasPrefix(head, xzMagic):
+		format = ArchiveTarXz
+	case bytes.HasPrefix(head, zstdMagic):
+		format = ArchiveTarZstd
+	default:
+		return ""
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ""
+	}
+	r, closeFn, err := decompressor(format, f)
+	if err != nil {
+		return ""
+	}
+	defer closeFn()
+
+	head = make([]byte, 512)
+	if _, err := io.ReadFull(r, head); err != nil || !isTarHeader(head) {
+		return ""
+	}
+	return format
+}
+
+// isTarHeader reports whether block starts with a POSIX or GNU tar header.
+func isTarHeader(block []byte) bool {
+	return len(block) >= 262 && bytes.Equal(block[257:262], []byte("ustar"))
+}
+
+// ListArchive lists the entries of the archive at path without extracting
+// anything. Zip and 7z archives are listed from their central directory or
+// header; tarballs have no index, so their headers are read in sequence and
+// the member data in between is skipped.
+func ListArchive(ctx context.Context, path string) (ArchiveListing, error) {
+	format := DetectArchive(path)
+	listing := ArchiveListing{Format: format}
+
+	var err error
+	switch format {
+	case ArchiveZip:
+		listing.Entries, listing.Truncated, err = listZip(ctx, path)
+	case ArchiveSevenZip:
+		listing.Entries, listing.Truncated, err = listSevenZip(ctx, path)
+	case ArchiveTar, ArchiveTarGzip, ArchiveTarXz, ArchiveTarZstd:
+		listing.Entries, listing.Truncated, err = listTar(ctx, path, format)
+	default:
+		return listing, ErrNotArchive
+	}
+
+	return listing, err
+}
+
+func listZip(ctx context.Context, path string) ([]ArchiveEntry, bool, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer r.Close()
+
+	var entries []ArchiveEntry
+	for _, f := range r.File {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if len(entries) == MaxArchiveEntries {
+			return entries, true, nil
+		}
+
+		mode := f.Mode()
+		entries = append(entries, ArchiveEntry{
+			Name:           f.Name,
+			Size:           int64(f.UncompressedSize64),
+			CompressedSize: int64(f.CompressedSize64),
+			Mode:           mode,
+			ModTime:        f.Modified,
+			IsDir:          mode.IsDir(),
+		})
+	}
+
+	return entries, false, nil
+}
+
+func listTar(ctx context.Context, path, format string) ([]ArchiveEntry, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	r, closeFn, err := decompressor(format, f)
+	if err != nil {
+		return nil, false, err
+	}
+	defer closeFn()
+
+	var entries []ArchiveEntry
+	tr := tar.NewReader(r)
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, false, nil
+		}
+		if err != nil {
+			return entries, false, err
+		}
+		if len(entries) == MaxArchiveEntries {
+			return entries, true, nil
+		}
+
+		compressed := int64(-1)
+		if format == ArchiveTar {
+			compressed = hdr.Size
+		}
+
+		entries = append(entries, ArchiveEntry{
+			Name:           hdr.Name,
+			Size:           hdr.Size,
+			CompressedSize: compressed,
+			Mode:           hdr.FileInfo().Mode(),
+			ModTime:        hdr.ModTime,
+			IsDir:          hdr.Typeflag == tar.TypeDir,
+			LinkTarget:     hdr.Linkname,
+		})
+	}
+}
+
+// decompressor wraps r in the decompressor for a tarball format. The returned
+// function releases any resources held by the decompressor.
+func decompressor(format string, r io.Reader) (io.Reader, func(), error) {
+	switch format {
+	case ArchiveTar:
+		return r, func() {}, nil
+	case ArchiveTarGzip:
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, func() { zr.Close() }, nil
+	case ArchiveTarXz:
+		xr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		return xr, func() {}, nil
+	case ArchiveTarZstd:
+		zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, err
+		}
+		return zr, zr.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown archive format %q", format)
+	}
+}
@@ -0,0 +1,900 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+	"unicode/utf16"
+
+	"github.com/ulikunitz/xz/lzma"
+)
+
+// This file reads just enough of the 7z format to list an archive's files:
+// the signature header, the (optionally LZMA-compressed) header database and
+// its file properties. File data is never decoded.
+
+// sevenZipMaxHeaderSize bounds the size of the header database read into
+// memory, compressed or not.
+const sevenZipMaxHeaderSize = 64 * 1024 * 1024
+
+// 7z property IDs.
+const (
+	szEnd                   = 0x00
+	szHeader                = 0x01
+	szArchiveProperties     = 0x02
+	szAdditionalStreamsInfo = 0x03
+	szMainStreamsInfo       = 0x04
+	szFilesInfo             = 0x05
+	szPackInfo              = 0x06
+	szUnpackInfo            = 0x07
+	szSubStreamsInfo        = 0x08
+	szSize                  = 0x09
+	szCRC                   = 0x0a
+	szFolder                = 0x0b
+	szCodersUnpackSize      = 0x0c
+	szNumUnpackStream       = 0x0d
+	szEmptyStream           = 0x0e
+	szEmptyFile             = 0x0f
+	szName                  = 0x11
+	szMTime                 = 0x14
+	szWinAttributes         = 0x15
+	szEncodedHeader         = 0x17
+)
+
+// Coder method IDs understood when decoding a compressed header.
+var (
+	szMethodCopy  = []byte{0x00}
+	szMethodLZMA  = []byte{0x03, 0x01, 0x01}
+	szMethodLZMA2 = []byte{0x21}
+)
+
+var errSevenZipCorrupt = errors.New("7z: corrupt header")
+
+// szReader decodes the primitive types of a 7z header.
+type szReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *szReader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errSevenZipCorrupt
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *szReader) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(r.buf)-r.pos) {
+		return nil, errSevenZipCorrupt
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// number reads a 7z variable-length integer: the count of leading one bits in
+// the first byte gives the number of extra little-endian bytes, and the
+// remaining bits of the first byte are the most significant ones.
+func (r *szReader) number() (uint64, error) {
+	first, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+
+	var value uint64
+	mask := byte(0x80)
+	for i := 0; i < 8; i++ {
+		if first&mask == 0 {
+			high := uint64(first & (mask - 1))
+			return value | high<<(8*i), nil
+		}
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(b) << (8 * i)
+		mask >>= 1
+	}
+	return value, nil
+}
+
+// count reads a number used as an element count, rejecting values that could
+// not possibly fit in the remaining header.
+func (r *szReader) count() (int, error) {
+	n, err := r.number()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(r.buf)) {
+		return 0, errSevenZipCorrupt
+	}
+	return int(n), nil
+}
+
+func (r *szReader) uint32() (uint32, error) {
+	b, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b), nil
+}
+
+func (r *szReader) uint64() (uint64, error) {
+	b, err := r.bytes(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(b), nil
+}
+
+// bits reads a bit vector of n entries, most significant bit first.
+func (r *szReader) bits(n int) ([]bool, error) {
+	b, err := r.bytes(uint64((n + 7) / 8))
+	if err != nil {
+		return nil, err
+	}
+	v := make([]bool, n)
+	for i := range v {
+		v[i] = b[i/8]&(0x80>>(i%8)) != 0
+	}
+	return v, nil
+}
+
+// optionalBits reads an "all defined" flag followed, if it is not set, by a
+// bit vector of n entries.
+func (r *szReader) optionalBits(n int) ([]bool, error) {
+	all, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if all == 0 {
+		return r.bits(n)
+	}
+	v := make([]bool, n)
+	for i := range v {
+		v[i] = true
+	}
+	return v, nil
+}
+
+// skipDigests skips a list of n optional CRC32 values and reports which of
+// them were present.
+func (r *szReader) skipDigests(n int) ([]bool, error) {
+	defined, err := r.optionalBits(n)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range defined {
+		if d {
+			if _, err := r.uint32(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return defined, nil
+}
+
+func (r *szReader) expect(id byte) error {
+	b, err := r.byte()
+	if err != nil {
+		return err
+	}
+	if b != id {
+		return errSevenZipCorrupt
+	}
+	return nil
+}
+
+type szCoder struct {
+	method []byte
+	props  []byte
+	numIn  int
+	numOut int
+}
+
+type szFolderInfo struct {
+	coders      []szCoder
+	boundOut    map[int]bool
+	numPacked   int
+	unpackSizes []uint64
+	hasCRC      bool
+}
+
+// unpackSize returns the size of the folder's final output stream, the one
+// not consumed by another coder.
+func (f *szFolderInfo) unpackSize() uint64 {
+	for i, size := range f.unpackSizes {
+		if !f.boundOut[i] {
+			return size
+		}
+	}
+	return 0
+}
+
+type szStreams struct {
+	packPos     uint64
+	packSizes   []uint64
+	folders     []szFolderInfo
+	substreams  []int
+	streamSizes []uint64
+}
+
+func (r *szReader) packInfo(s *szStreams) error {
+	var err error
+	if s.packPos, err = r.number(); err != nil {
+		return err
+	}
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+
+	for {
+		id, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch id {
+		case szEnd:
+			return nil
+		case szSize:
+			s.packSizes = make([]uint64, n)
+			for i := range s.packSizes {
+				if s.packSizes[i], err = r.number(); err != nil {
+					return err
+				}
+			}
+		case szCRC:
+			if _, err := r.skipDigests(n); err != nil {
+				return err
+			}
+		default:
+			return errSevenZipCorrupt
+		}
+	}
+}
+
+func (r *szReader) folder() (szFolderInfo, error) {
+	var f szFolderInfo
+
+	numCoders, err := r.count()
+	if err != nil {
+		return f, err
+	}
+
+	totalIn, totalOut := 0, 0
+	for i := 0; i < numCoders; i++ {
+		flags, err := r.byte()
+		if err != nil {
+			return f, err
+		}
+		if flags&0x80 != 0 {
+			return f, fmt.Errorf("7z: alternative coder methods are not supported")
+		}
+
+		c := szCoder{numIn: 1, numOut: 1}
+		if c.method, err = r.bytes(uint64(flags & 0x0f)); err != nil {
+			return f, err
+		}
+		if flags&0x10 != 0 {
+			if c.numIn, err = r.count(); err != nil {
+				return f, err
+			}
+			if c.numOut, err = r.count(); err != nil {
+				return f, err
+			}
+		}
+		if flags&0x20 != 0 {
+			size, err := r.number()
+			if err != nil {
+				return f, err
+			}
+			if c.props, err = r.bytes(size); err != nil {
+				return f, err
+			}
+		}
+
+		totalIn += c.numIn
+		totalOut += c.numOut
+		f.coders = append(f.coders, c)
+	}
+
+	f.boundOut = map[int]bool{}
+	for i := 0; i < totalOut-1; i++ {
+		if _, err := r.number(); err != nil { // in index
+			return f, err
+		}
+		out, err := r.count()
+		if err != nil {
+			return f, err
+		}
+		f.boundOut[out] = true
+	}
+
+	f.numPacked = totalIn - (totalOut - 1)
+	if f.numPacked < 0 || totalOut > len(r.buf) {
+		return f, errSevenZipCorrupt
+	}
+	if f.numPacked > 1 {
+		for i := 0; i < f.numPacked; i++ {
+			if _, err := r.number(); err != nil {
+				return f, err
+			}
+		}
+	}
+
+	f.unpackSizes = make([]uint64, totalOut)
+	return f, nil
+}
+
+func (r *szReader) unpackInfo(s *szStreams) error {
+	if err := r.expect(szFolder); err != nil {
+		return err
+	}
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	if external, err := r.byte(); err != nil {
+		return err
+	} else if external != 0 {
+		return fmt.Errorf("7z: external folders are not supported")
+	}
+
+	s.folders = make([]szFolderInfo, n)
+	for i := range s.folders {
+		if s.folders[i], err = r.folder(); err != nil {
+			return err
+		}
+	}
+
+	if err := r.expect(szCodersUnpackSize); err != nil {
+		return err
+	}
+	for i := range s.folders {
+		for j := range s.folders[i].unpackSizes {
+			if s.folders[i].unpackSizes[j], err = r.number(); err != nil {
+				return err
+			}
+		}
+	}
+
+	for {
+		id, err := r.byte()
+		if err != nil {
+			return err
+		}
+		switch id {
+		case szEnd:
+			return nil
+		case szCRC:
+			defined, err := r.skipDigests(n)
+			if err != nil {
+				return err
+			}
+			for i, d := range defined {
+				s.folders[i].hasCRC = d
+			}
+		default:
+			return errSevenZipCorrupt
+		}
+	}
+}
+
+func (r *szReader) subStreamsInfo(s *szStreams) error {
+	s.substreams = make([]int, len(s.folders))
+	for i := range s.substreams {
+		s.substreams[i] = 1
+	}
+
+	id, err := r.byte()
+	if err != nil {
+		return err
+	}
+
+	if id == szNumUnpackStream {
+		// Every stream is a file listed in the header, so there cannot be
+		// more of them than there are bytes in it. This also bounds the
+		// digests read below.
+		total := 0
+		for i := range s.substreams {
+			if s.substreams[i], err = r.count(); err != nil {
+				return err
+			}
+			if total += s.substreams[i]; total > len(r.buf) {
+				return errSevenZipCorrupt
+			}
+		}
+		if id, err = r.byte(); err != nil {
+			return err
+		}
+	}
+
+	s.streamSizes = nil
+	for i, f := range s.folders {
+		n := s.substreams[i]
+		if n == 0 {
+			continue
+		}
+		total := f.unpackSize()
+		var sum uint64
+		if id == szSize {
+			for j := 0; j < n-1; j++ {
+				size, err := r.number()
+				if err != nil {
+					return err
+				}
+				s.streamSizes = append(s.streamSizes, size)
+				sum += size
+			}
+		}
+		s.streamSizes = append(s.streamSizes, total-sum)
+	}
+	if id == szSize {
+		if id, err = r.byte(); err != nil {
+			return err
+		}
+	}
+
+	for id != szEnd {
+		if id != szCRC {
+			return errSevenZipCorrupt
+		}
+		// Digests are listed for every stream whose CRC is not already known
+		// from its folder.
+		unknown := 0
+		for i, f := range s.folders {
+			if s.substreams[i] != 1 || !f.hasCRC {
+				unknown += s.substreams[i]
+			}
+		}
+		if _, err := r.skipDigests(unknown); err != nil {
+			return err
+		}
+		if id, err = r.byte(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *szReader) streamsInfo() (szStreams, error) {
+	var s szStreams
+	for {
+		id, err := r.byte()
+		if err != nil {
+			return s, err
+		}
+		switch id {
+		case szEnd:
+			if s.substreams != nil && len(s.substreams) != len(s.folders) {
+				// Substream info listed before the folders it describes.
+				return s, errSevenZipCorrupt
+			}
+			if s.substreams == nil {
+				// Without substream info every folder holds one file.
+				s.substreams = make([]int, len(s.folders))
+				for i, f := range s.folders {
+					s.substreams[i] = 1
+					s.streamSizes = append(s.streamSizes, f.unpackSize())
+				}
+			}
+			return s, nil
+		case szPackInfo:
+			err = r.packInfo(&s)
+		case szUnpackInfo:
+			err = r.unpackInfo(&s)
+		case szSubStreamsInfo:
+			err = r.subStreamsInfo(&s)
+		default:
+			err = errSevenZipCorrupt
+		}
+		if err != nil {
+			return s, err
+		}
+	}
+}
+
+// listSevenZip lists a 7z archive from its header database.
+func listSevenZip(ctx context.Context, path string) ([]ArchiveEntry, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	sig := make([]byte, 32)
+	if _, err := io.ReadFull(f, sig); err != nil {
+		return nil, false, err
+	}
+	if !bytes.HasPrefix(sig, sevenZipMagic) {
+		return nil, false, ErrNotArchive
+	}
+
+	nextOffset := binary.LittleEndian.Uint64(sig[12:20])
+	nextSize := binary.LittleEndian.Uint64(sig[20:28])
+	if nextSize == 0 {
+		return nil, false, nil
+	}
+	if nextSize > sevenZipMaxHeaderSize {
+		return nil, false, fmt.Errorf("7z: header too large")
+	}
+
+	buf := make([]byte, nextSize)
+	if _, err := f.ReadAt(buf, 32+int64(nextOffset)); err != nil {
+		return nil, false, err
+	}
+
+	r := &szReader{buf: buf}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		id, err := r.byte()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch id {
+		case szHeader:
+			return r.header(ctx)
+		case szEncodedHeader:
+			streams, err := r.streamsInfo()
+			if err != nil {
+				return nil, false, err
+			}
+			decoded, err := decodeSevenZipHeader(f, streams)
+			if err != nil {
+				return nil, false, err
+			}
+			r = &szReader{buf: decoded}
+		default:
+			return nil, false, errSevenZipCorrupt
+		}
+	}
+}
+
+// decodeSevenZipHeader decompresses an encoded header. Only single-coder
+// folders using LZMA, LZMA2 or no compression are supported, which covers the
+// headers written by 7-Zip and p7zip unless header encryption is enabled.
+func decodeSevenZipHeader(f *os.File, s szStreams) ([]byte, error) {
+	if len(s.folders) != 1 || len(s.folders[0].coders) != 1 || len(s.packSizes) < 1 {
+		return nil, fmt.Errorf("7z: unsupported header encoding")
+	}
+
+	folder := s.folders[0]
+	coder := folder.coders[0]
+	size := folder.unpackSize()
+	if size > sevenZipMaxHeaderSize {
+		return nil, fmt.Errorf("7z: header too large")
+	}
+
+	packed := io.NewSectionReader(f, 32+int64(s.packPos), int64(s.packSizes[0]))
+
+	var r io.Reader
+	switch {
+	case bytes.Equal(coder.method, szMethodCopy):
+		r = packed
+	case bytes.Equal(coder.method, szMethodLZMA):
+		if len(coder.props) != 5 {
+			return nil, errSevenZipCorrupt
+		}
+		// Prefix the classic .lzma header: properties, dictionary size and
+		// the uncompressed size.
+		hdr := make([]byte, 13)
+		copy(hdr, coder.props)
+		binary.LittleEndian.PutUint64(hdr[5:], size)
+		lr, err := lzma.NewReader(io.MultiReader(bytes.NewReader(hdr), packed))
+		if err != nil {
+			return nil, err
+		}
+		r = lr
+	case bytes.Equal(coder.method, szMethodLZMA2):
+		if len(coder.props) != 1 {
+			return nil, errSevenZipCorrupt
+		}
+		cfg := lzma.Reader2Config{DictCap: lzma2DictSize(coder.props[0])}
+		lr, err := cfg.NewReader2(packed)
+		if err != nil {
+			return nil, err
+		}
+		r = lr
+	default:
+		return nil, fmt.Errorf("7z: unsupported header compression (encrypted archive?)")
+	}
+
+	buf := make([]byte, size)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// lzma2DictSize decodes the dictionary size stored in LZMA2 properties.
+func lzma2DictSize(p byte) int {
+	if p >= 40 {
+		return 1<<31 - 1
+	}
+	size := (2 | int(p&1)) << (p/2 + 11)
+	return max(size, lzma.MinDictCap)
+}
+
+// header parses the header database and returns its file entries.
+func (r *szReader) header(ctx context.Context) ([]ArchiveEntry, bool, error) {
+	var streams szStreams
+
+	for {
+		id, err := r.byte()
+		if err != nil {
+			return nil, false, err
+		}
+
+		switch id {
+		case szEnd:
+			return nil, false, nil
+		case szArchiveProperties:
+			for {
+				prop, err := r.byte()
+				if err != nil {
+					return nil, false, err
+				}
+				if prop == szEnd {
+					break
+				}
+				size, err := r.number()
+				if err != nil {
+					return nil, false, err
+				}
+				if _, err := r.bytes(size); err != nil {
+					return nil, false, err
+				}
+			}
+		case szAdditionalStreamsInfo:
+			if _, err := r.streamsInfo(); err != nil {
+				return nil, false, err
+			}
+		case szMainStreamsInfo:
+			if streams, err = r.streamsInfo(); err != nil {
+				return nil, false, err
+			}
+		case szFilesInfo:
+			return r.filesInfo(ctx, streams)
+		default:
+			return nil, false, errSevenZipCorrupt
+		}
+	}
+}
+
+// filesInfo parses the file properties and pairs each file that has data
+// with the next unpacked stream.
+func (r *szReader) filesInfo(ctx context.Context, s szStreams) ([]ArchiveEntry, bool, error) {
+	n, err := r.count()
+	if err != nil {
+		return nil, false, err
+	}
+
+	var (
+		emptyStream []bool
+		emptyFile   []bool
+		names       []string
+		mtimes      []time.Time
+		attrs       []uint32
+		hasAttr     []bool
+	)
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+
+		id, err := r.byte()
+		if err != nil {
+			return nil, false, err
+		}
+		if id == szEnd {
+			break
+		}
+		size, err := r.number()
+		if err != nil {
+			return nil, false, err
+		}
+		data, err := r.bytes(size)
+		if err != nil {
+			return nil, false, err
+		}
+		p := &szReader{buf: data}
+
+		switch id {
+		case szEmptyStream:
+			if emptyStream, err = p.bits(n); err != nil {
+				return nil, false, err
+			}
+		case szEmptyFile:
+			numEmpty := 0
+			for _, e := range emptyStream {
+				if e {
+					numEmpty++
+				}
+			}
+			if emptyFile, err = p.bits(numEmpty); err != nil {
+				return nil, false, err
+			}
+		case szName:
+			if names, err = p.names(n); err != nil {
+				return nil, false, err
+			}
+		case szMTime:
+			if mtimes, err = p.times(n); err != nil {
+				return nil, false, err
+			}
+		case szWinAttributes:
+			if hasAttr, err = p.optionalBits(n); err != nil {
+				return nil, false, err
+			}
+			if _, err := p.byte(); err != nil { // external
+				return nil, false, err
+			}
+			attrs = make([]uint32, n)
+			for i, ok := range hasAttr {
+				if ok {
+					if attrs[i], err = p.uint32(); err != nil {
+						return nil, false, err
+					}
+				}
+			}
+		}
+	}
+
+	// Pack sizes are only meaningful per file when a folder holds one file.
+	packed := make([]int64, 0, len(s.streamSizes))
+	packIdx := 0
+	for i, f := range s.folders {
+		var size int64
+		for j := 0; j < f.numPacked && packIdx+j < len(s.packSizes); j++ {
+			size += int64(s.packSizes[packIdx+j])
+		}
+		packIdx += f.numPacked
+		for j := 0; j < s.substreams[i]; j++ {
+			if s.substreams[i] == 1 {
+				packed = append(packed, size)
+			} else {
+				packed = append(packed, -1)
+			}
+		}
+	}
+
+	entries := make([]ArchiveEntry, 0, min(n, MaxArchiveEntries))
+	stream, emptyIdx := 0, 0
+	for i := 0; i < n; i++ {
+		if len(entries) == MaxArchiveEntries {
+			return entries, true, nil
+		}
+
+		e := ArchiveEntry{CompressedSize: -1}
+		if i < len(names) {
+			e.Name = names[i]
+		}
+		if i < len(mtimes) {
+			e.ModTime = mtimes[i]
+		}
+
+		// Files without data are directories unless flagged as empty files.
+		empty := i < len(emptyStream) && emptyStream[i]
+		if empty {
+			e.IsDir = emptyIdx >= len(emptyFile) || !emptyFile[emptyIdx]
+			emptyIdx++
+		} else if stream < len(s.streamSizes) {
+			e.Size = int64(s.streamSizes[stream])
+			e.CompressedSize = packed[stream]
+			stream++
+		}
+
+		var attr uint32
+		if i < len(attrs) && hasAttr[i] {
+			attr = attrs[i]
+		}
+		if attr&0x10 != 0 {
+			e.IsDir = true
+		}
+		e.Mode = sevenZipMode(attr, e.IsDir)
+
+		entries = append(entries, e)
+	}
+
+	return entries, false, nil
+}
+
+// names reads n NUL-terminated UTF-16LE file names.
+func (r *szReader) names(n int) ([]string, error) {
+	external, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	if external != 0 {
+		return nil, fmt.Errorf("7z: external names are not supported")
+	}
+
+	names := make([]string, 0, n)
+	var units []uint16
+	for len(names) < n {
+		b, err := r.bytes(2)
+		if err != nil {
+			return nil, err
+		}
+		u := binary.LittleEndian.Uint16(b)
+		if u == 0 {
+			names = append(names, string(utf16.Decode(units)))
+			units = units[:0]
+			continue
+		}
+		units = append(units, u)
+	}
+	return names, nil
+}
+
+// times reads n optional Windows FILETIME values.
+func (r *szReader) times(n int) ([]time.Time, error) {
+	defined, err := r.optionalBits(n)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.byte(); err != nil { // external
+		return nil, err
+	}
+
+	// FILETIME counts 100ns intervals since 1601-01-01.
+	const epochDelta = 116444736000000000
+
+	times := make([]time.Time, n)
+	for i, ok := range defined {
+		if !ok {
+			continue
+		}
+		ft, err := r.uint64()
+		if err != nil {
+			return nil, err
+		}
+		if ft >= epochDelta {
+			times[i] = time.Unix(0, int64(ft-epochDelta)*100)
+		}
+	}
+	return times, nil
+}
+
+// sevenZipMode converts Windows attributes, with the Unix mode that p7zip
+// stores in the high 16 bits, into an os.FileMode.
+func sevenZipMode(attr uint32, isDir bool) os.FileMode {
+	const unixExtension = 0x8000
+
+	var mode os.FileMode
+	if attr&unixExtension != 0 {
+		unix := attr >> 16
+		mode = os.FileMode(unix & 0o777)
+		switch unix & 0o170000 {
+		case 0o040000:
+			mode |= os.ModeDir
+		case 0o120000:
+			mode |= os.ModeSymlink
+		}
+	} else if attr&0x01 != 0 { // read-only
+		mode = 0o444
+	} else {
+		mode = 0o644
+	}
+
+	if isDir {
+		mode |= os.ModeDir
+		if attr&unixExtension == 0 {
+			mode |= 0o111
+		}
+	}
+	return mode
+}
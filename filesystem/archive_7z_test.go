@@ -0,0 +1,64 @@
+package filesystem
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeSevenZip writes a 7z file made of the signature header and the given
+// header database, and returns its path.
+func writeSevenZip(t *testing.T, header []byte) string {
+	t.Helper()
+	sig := make([]byte, 32)
+	copy(sig, sevenZipMagic)
+	binary.LittleEndian.PutUint64(sig[20:28], uint64(len(header)))
+
+	path := filepath.Join(t.TempDir(), "test.7z")
+	if err := os.WriteFile(path, append(sig, header...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestListSevenZipMalformedHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []byte
+	}{
+		{
+			name:   "substreams before unpack info",
+			header: []byte{1, 4, 8, 0x0d, 0, 7, 0x0b, 1, 0, 0x01, 0x00, 0x0c, 5, 0, 0, 5, 1, 0, 0},
+		},
+		{
+			name:   "negative packed stream count",
+			header: []byte{1, 4, 7, 0x0b, 2, 0, 0x11, 0x00, 0, 3, 0, 0, 0, 1, 0x01, 0x00, 0x0c, 1, 1, 1, 1, 0, 0, 5, 2, 0, 0},
+		},
+		{
+			name:   "huge substream count",
+			header: []byte{1, 4, 7, 0x0b, 1, 0, 0x01, 0x00, 0x0c, 5, 0, 8, 0x0d, 0xbf, 0xff, 0, 0},
+		},
+		{
+			name:   "unknown property",
+			header: []byte{0x42},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, _, err := listSevenZip(context.Background(), writeSevenZip(t, tt.header))
+			if !errors.Is(err, errSevenZipCorrupt) {
+				t.Fatalf("listSevenZip() = %d entries, %v; want %v", len(entries), err, errSevenZipCorrupt)
+			}
+		})
+	}
+}
+
+func TestListSevenZipEmptyHeader(t *testing.T) {
+	entries, _, err := listSevenZip(context.Background(), writeSevenZip(t, []byte{1, 0}))
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("listSevenZip() = %d entries, %v; want none", len(entries), err)
+	}
+}
@@ -0,0 +1,725 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MaxDocumentPages bounds how many PDF pages ReadDocument extracts.
+	MaxDocumentPages = 10
+
+	// MaxDocumentText bounds how much text ReadDocument extracts, in bytes.
+	MaxDocumentText = 256 * 1024
+
+	// MaxDocumentSheets, MaxDocumentSheetRows and MaxDocumentSheetColumns
+	// bound how much of a spreadsheet ReadDocument extracts.
+	MaxDocumentSheets       = 10
+	MaxDocumentSheetRows    = 1000
+	MaxDocumentSheetColumns = 64
+
+	// maxDocumentPart bounds the size of a single XML part read from an
+	// office file.
+	maxDocumentPart = 64 * 1024 * 1024
+
+	// maxSheetColumns is the number of columns of a spreadsheet, up to XFD.
+	// Cells beyond it are invalid and skipped.
+	maxSheetColumns = 16384
+)
+
+// Document formats recognised by DetectDocument.
+const (
+	DocumentPDF  = "pdf"
+	DocumentDocx = "docx"
+	DocumentOdt  = "odt"
+	DocumentXlsx = "xlsx"
+)
+
+// ErrNotDocument is returned by ReadDocument for files that are not a
+// document in one of the supported formats.
+var ErrNotDocument = errors.New("not a supported document")
+
+// DocumentField is a single metadata entry, such as the title or author.
+type DocumentField struct {
+	Name  string
+	Value string
+}
+
+// DocumentSheet is one sheet of a spreadsheet.
+type DocumentSheet struct {
+	Name string
+	// Rows holds the cell values, with empty cells filled in.
+	Rows      [][]string
+	Truncated bool
+	// MoreColumns is how many columns with values past
+	// MaxDocumentSheetColumns were left out.
+	MoreColumns int
+}
+
+// Document is the text and metadata extracted from a document.
+type Document struct {
+	Format string
+	// Pages is the page count (sheet count for spreadsheets), or zero when
+	// the document does not record it.
+	Pages int
+	// Metadata lists the document properties that are set, in a fixed order.
+	Metadata []DocumentField
+	// Text is the text of the first pages, one paragraph or line per entry.
+	Text []string
+	// Sheets holds the contents of spreadsheets.
+	Sheets []DocumentSheet
+	// Truncated reports that only part of the text was extracted.
+	Truncated bool
+}
+
+// DetectDocument returns the document format of path based on its extension,
+// or "" if it is not a supported document.
+func DetectDocument(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		return DocumentPDF
+	case ".docx":
+		return DocumentDocx
+	case ".odt":
+		return DocumentOdt
+	case ".xlsx":
+		return DocumentXlsx
+	default:
+		return ""
+	}
+}
+
+// ReadDocument extracts the text and metadata of a PDF, docx, odt or xlsx
+// file. Only the first MaxDocumentPages pages of a PDF, MaxDocumentText bytes
+// of text and MaxDocumentSheets sheets are read.
+func ReadDocument(ctx context.Context, path string) (Document, error) {
+	format := DetectDocument(path)
+	switch format {
+	case DocumentPDF:
+		return readPDF(ctx, path)
+	case "":
+		return Document{}, ErrNotDocument
+	}
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return Document{}, err
+	}
+	defer zr.Close()
+
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	doc := Document{Format: format}
+	switch format {
+	case DocumentDocx:
+		err = readDocx(ctx, files, &doc)
+	case DocumentOdt:
+		err = readOdt(ctx, files, &doc)
+	case DocumentXlsx:
+		err = readXlsx(ctx, files, &doc)
+	}
+	return doc, err
+}
+
+// xmlPart opens a member of an office file as an XML decoder.
+func xmlPart(files map[string]*zip.File, name string) (*xml.Decoder, io.Closer, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, nil, fmt.Errorf("missing %s", name)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	return xml.NewDecoder(io.LimitReader(rc, maxDocumentPart)), rc, nil
+}
+
+// textBuilder collects paragraphs up to MaxDocumentText bytes.
+type textBuilder struct {
+	lines []string
+	line  strings.Builder
+	size  int
+	full  bool
+}
+
+func (b *textBuilder) WriteString(s string) {
+	if b.full {
+		return
+	}
+	b.line.WriteString(s)
+	b.size += len(s)
+	if b.size >= MaxDocumentText {
+		b.full = true
+	}
+}
+
+// EndParagraph finishes the current line.
+func (b *textBuilder) EndParagraph() {
+	b.lines = append(b.lines, strings.TrimRight(b.line.String(), " \t"))
+	b.line.Reset()
+}
+
+// Lines returns the collected lines without leading, trailing or repeated
+// blank lines.
+func (b *textBuilder) Lines() []string {
+	if b.line.Len() > 0 {
+		b.EndParagraph()
+	}
+
+	var lines []string
+	blank := true
+	for _, line := range b.lines {
+		if strings.TrimSpace(line) == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		lines = append(lines, line)
+		blank = false
+	}
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// readDocx extracts the paragraphs of word/document.xml.
+func readDocx(ctx context.Context, files map[string]*zip.File, doc *Document) error {
+	readOfficeProperties(files, doc)
+
+	dec, rc, err := xmlPart(files, "word/document.xml")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var text textBuilder
+	inText := false
+	cells := 0 // nesting of table cells, whose paragraphs share a line
+	for !text.full {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tc":
+				cells++
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.EndParagraph()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				if cells > 0 {
+					text.WriteString(" ")
+				} else {
+					text.EndParagraph()
+				}
+			case "tc":
+				// Separate table cells on the same row.
+				cells--
+				text.WriteString("\t")
+			case "tr":
+				text.EndParagraph()
+			}
+		case xml.CharData:
+			if inText {
+				text.WriteString(string(t))
+			}
+		}
+	}
+
+	doc.Text = text.Lines()
+	doc.Truncated = text.full
+	return nil
+}
+
+// readOdt extracts the paragraphs and headings of content.xml.
+func readOdt(ctx context.Context, files map[string]*zip.File, doc *Document) error {
+	readOdfMeta(files, doc)
+
+	dec, rc, err := xmlPart(files, "content.xml")
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	var text textBuilder
+	depth := 0 // nesting of text:p and text:h elements
+	cells := 0 // nesting of table cells, whose paragraphs share a line
+	for !text.full {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p", "h":
+				depth++
+			case "table-cell":
+				cells++
+			case "s":
+				count := 1
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "c" {
+						count, _ = strconv.Atoi(attr.Value)
+					}
+				}
+				text.WriteString(strings.Repeat(" ", max(min(count, 80), 1)))
+			case "tab":
+				text.WriteString("\t")
+			case "line-break":
+				text.EndParagraph()
+			case "note":
+				// Footnote bodies would interrupt the paragraph.
+				if err := dec.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "h":
+				depth--
+				switch {
+				case depth > 0:
+				case cells > 0:
+					text.WriteString(" ")
+				default:
+					text.EndParagraph()
+				}
+			case "table-cell":
+				cells--
+				text.WriteString("\t")
+			case "table-row":
+				text.EndParagraph()
+			}
+		case xml.CharData:
+			if depth > 0 {
+				text.WriteString(string(t))
+			}
+		}
+	}
+
+	doc.Text = text.Lines()
+	doc.Truncated = text.full
+	return nil
+}
+
+// readXlsx extracts the cells of the first sheets of a workbook.
+func readXlsx(ctx context.Context, files map[string]*zip.File, doc *Document) error {
+	readOfficeProperties(files, doc)
+
+	shared, err := readSharedStrings(files)
+	if err != nil {
+		return err
+	}
+
+	sheets, err := readWorkbookSheets(files)
+	if err != nil {
+		return err
+	}
+	doc.Pages = len(sheets)
+
+	for i, sheet := range sheets {
+		if i == MaxDocumentSheets {
+			doc.Truncated = true
+			break
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		s, err := readWorksheet(ctx, files, sheet.target, shared)
+		if err != nil {
+			return fmt.Errorf("sheet %q: %w", sheet.name, err)
+		}
+		s.Name = sheet.name
+		doc.Sheets = append(doc.Sheets, s)
+	}
+	return nil
+}
+
+// readSharedStrings reads the shared string table of a workbook, which cells
+// refer to by index.
+func readSharedStrings(files map[string]*zip.File) ([]string, error) {
+	if _, ok := files["xl/sharedStrings.xml"]; !ok {
+		return nil, nil
+	}
+	dec, rc, err := xmlPart(files, "xl/sharedStrings.xml")
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	var strs []string
+	var cur strings.Builder
+	inText, inPhonetic := false, false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, cur.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText && !inPhonetic {
+				cur.Write(t)
+			}
+		}
+	}
+}
+
+// workbookSheet is a sheet listed in xl/workbook.xml.
+type workbookSheet struct {
+	name   string
+	target string
+}
+
+// readWorkbookSheets lists the sheets of a workbook in order, resolving each
+// to its worksheet part.
+func readWorkbookSheets(files map[string]*zip.File) ([]workbookSheet, error) {
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	targets := map[string]string{}
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string     `xml:"name,attr"`
+			Attr []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(files, "xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+
+	var sheets []workbookSheet
+	for _, s := range workbook.Sheets {
+		for _, attr := range s.Attr {
+			if attr.Name.Local == "id" {
+				sheets = append(sheets, workbookSheet{name: s.Name, target: targets[attr.Value]})
+			}
+		}
+	}
+	return sheets, nil
+}
+
+// readWorksheet reads the cell values of one worksheet.
+func readWorksheet(ctx context.Context, files map[string]*zip.File, name string, shared []string) (DocumentSheet, error) {
+	dec, rc, err := xmlPart(files, name)
+	if err != nil {
+		return DocumentSheet{}, err
+	}
+	defer rc.Close()
+
+	var sheet DocumentSheet
+	var row []string
+	var cellType, cellRef string
+	var value strings.Builder
+	inValue := false
+
+	for {
+		if ctx.Err() != nil {
+			return DocumentSheet{}, ctx.Err()
+		}
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return sheet, nil
+		}
+		if err != nil {
+			return DocumentSheet{}, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				if len(sheet.Rows) == MaxDocumentSheetRows {
+					sheet.Truncated = true
+					return sheet, nil
+				}
+				row = nil
+			case "c":
+				cellType, cellRef = "", ""
+				for _, attr := range t.Attr {
+					switch attr.Name.Local {
+					case "t":
+						cellType = attr.Value
+					case "r":
+						cellRef = attr.Value
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				col := len(row)
+				if c, ok := cellColumn(cellRef); ok {
+					col = c
+				}
+				if col >= maxSheetColumns {
+					break
+				}
+				if col >= MaxDocumentSheetColumns {
+					if value.Len() > 0 {
+						sheet.MoreColumns = max(sheet.MoreColumns, col+1-MaxDocumentSheetColumns)
+					}
+					break
+				}
+				for len(row) < col {
+					row = append(row, "")
+				}
+				if col == len(row) {
+					row = append(row, cellValue(cellType, value.String(), shared))
+				}
+			case "row":
+				sheet.Rows = append(sheet.Rows, row)
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// cellValue formats the raw value of a cell according to its type.
+func cellValue(cellType, raw string, shared []string) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(raw)
+		if err != nil || i < 0 || i >= len(shared) {
+			return ""
+		}
+		return shared[i]
+	case "b":
+		if raw == "1" {
+			return "TRUE"
+		}
+		return "FALSE"
+	default:
+		return strings.ReplaceAll(raw, "\n", " ")
+	}
+}
+
+// cellColumn returns the zero-based column of a cell reference such as "C7".
+// Columns past maxSheetColumns are returned as maxSheetColumns.
+func cellColumn(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = min(col*26+int(c-'A'+1), maxSheetColumns+1)
+		n++
+	}
+	if n == 0 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// decodePart unmarshals a member of an office file.
+func decodePart(files map[string]*zip.File, name string, v any) error {
+	dec, rc, err := xmlPart(files, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return dec.Decode(v)
+}
+
+// readOfficeProperties reads the core and extended properties shared by
+// docx and xlsx files. Missing or malformed properties are ignored.
+func readOfficeProperties(files map[string]*zip.File, doc *Document) {
+	var core struct {
+		Title          string `xml:"title"`
+		Subject        string `xml:"subject"`
+		Creator        string `xml:"creator"`
+		Keywords       string `xml:"keywords"`
+		Description    string `xml:"description"`
+		LastModifiedBy string `xml:"lastModifiedBy"`
+		Created        string `xml:"created"`
+		Modified       string `xml:"modified"`
+	}
+	_ = decodePart(files, "docProps/core.xml", &core)
+
+	var app struct {
+		Application string `xml:"Application"`
+		Pages       int    `xml:"Pages"`
+		Words       int    `xml:"Words"`
+	}
+	_ = decodePart(files, "docProps/app.xml", &app)
+
+	doc.Pages = app.Pages
+	doc.Metadata = documentFields(
+		"Title", core.Title,
+		"Subject", core.Subject,
+		"Author", core.Creator,
+		"Keywords", core.Keywords,
+		"Description", core.Description,
+		"Last modified by", core.LastModifiedBy,
+		"Created", formatDocumentDate(core.Created),
+		"Modified", formatDocumentDate(core.Modified),
+		"Application", app.Application,
+		"Words", countField(app.Words),
+	)
+}
+
+// readOdfMeta reads the properties of an OpenDocument file from meta.xml.
+// Missing or malformed properties are ignored.
+func readOdfMeta(files map[string]*zip.File, doc *Document) {
+	var meta struct {
+		Meta struct {
+			Title          string `xml:"title"`
+			Subject        string `xml:"subject"`
+			Description    string `xml:"description"`
+			Keywords       string `xml:"keyword"`
+			Creator        string `xml:"creator"`
+			InitialCreator string `xml:"initial-creator"`
+			Created        string `xml:"creation-date"`
+			Date           string `xml:"date"`
+			Generator      string `xml:"generator"`
+			Statistic      struct {
+				Pages int `xml:"page-count,attr"`
+				Words int `xml:"word-count,attr"`
+			} `xml:"document-statistic"`
+		} `xml:"meta"`
+	}
+	_ = decodePart(files, "meta.xml", &meta)
+
+	m := meta.Meta
+	author := m.InitialCreator
+	if author == "" {
+		author = m.Creator
+	}
+	lastModifiedBy := ""
+	if m.Creator != author {
+		lastModifiedBy = m.Creator
+	}
+
+	doc.Pages = m.Statistic.Pages
+	doc.Metadata = documentFields(
+		"Title", m.Title,
+		"Subject", m.Subject,
+		"Author", author,
+		"Keywords", m.Keywords,
+		"Description", m.Description,
+		"Last modified by", lastModifiedBy,
+		"Created", formatDocumentDate(m.Created),
+		"Modified", formatDocumentDate(m.Date),
+		"Application", m.Generator,
+		"Words", countField(m.Statistic.Words),
+	)
+}
+
+// documentFields builds metadata from name/value pairs, skipping empty
+// values.
+func documentFields(pairs ...string) []DocumentField {
+	var fields []DocumentField
+	for i := 0; i+1 < len(pairs); i += 2 {
+		value := strings.Join(strings.Fields(pairs[i+1]), " ")
+		if value != "" {
+			fields = append(fields, DocumentField{Name: pairs[i], Value: value})
+		}
+	}
+	return fields
+}
+
+// countField formats a positive count, or returns "" for zero.
+func countField(n int) string {
+	if n <= 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+// formatDocumentDate formats an ISO 8601 timestamp as stored in office
+// files like the dates in the file list, leaving other values unchanged.
+func formatDocumentDate(s string) string {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return FormatDateModified(t)
+		}
+	}
+	return s
+}
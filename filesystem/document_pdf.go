@@ -0,0 +1,160 @@
+package filesystem
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// readPDF extracts the metadata and the text of the first MaxDocumentPages
+// pages of a PDF file.
+func readPDF(ctx context.Context, path string) (doc Document, err error) {
+	// The PDF reader reports some malformed files by panicking.
+	defer func() {
+		if r := recover(); r != nil {
+			doc, err = Document{}, fmt.Errorf("malformed PDF: %v", r)
+		}
+	}()
+
+	f, r, err := pdf.Open(path)
+	if err != nil {
+		return Document{}, err
+	}
+	defer f.Close()
+
+	doc = Document{Format: DocumentPDF, Pages: r.NumPage()}
+
+	info := r.Trailer().Key("Info")
+	doc.Metadata = documentFields(
+		"Title", info.Key("Title").Text(),
+		"Subject", info.Key("Subject").Text(),
+		"Author", info.Key("Author").Text(),
+		"Keywords", info.Key("Keywords").Text(),
+		"Created", formatPDFDate(info.Key("CreationDate").Text()),
+		"Modified", formatPDFDate(info.Key("ModDate").Text()),
+		"Application", info.Key("Creator").Text(),
+		"Producer", info.Key("Producer").Text(),
+		"PDF version", pdfVersion(f),
+	)
+
+	var text textBuilder
+	for i := 1; i <= doc.Pages; i++ {
+		if ctx.Err() != nil {
+			return Document{}, ctx.Err()
+		}
+		if i > MaxDocumentPages || text.full {
+			doc.Truncated = true
+			break
+		}
+
+		page := r.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+		if i > 1 {
+			text.EndParagraph()
+			text.WriteString(fmt.Sprintf("── Page %d ──", i))
+			text.EndParagraph()
+		}
+		for _, line := range pdfPageLines(page.Content().Text) {
+			text.WriteString(line)
+			text.EndParagraph()
+		}
+	}
+
+	doc.Text = text.Lines()
+	return doc, nil
+}
+
+// pdfPageLines lays out the glyphs of a page as lines of text, top to
+// bottom. Glyphs on roughly the same baseline form a line, and a space is
+// inserted wherever the gap between two glyphs is wider than a space would be.
+func pdfPageLines(glyphs []pdf.Text) []string {
+	glyphs = slices.Clone(glyphs)
+	slices.SortStableFunc(glyphs, func(a, b pdf.Text) int {
+		if math.Abs(a.Y-b.Y) > max(a.FontSize, b.FontSize)/2 {
+			return cmp.Compare(b.Y, a.Y)
+		}
+		return cmp.Compare(a.X, b.X)
+	})
+
+	var lines []string
+	var line strings.Builder
+	var prev pdf.Text
+	for i, g := range glyphs {
+		if i > 0 {
+			switch {
+			case math.Abs(g.Y-prev.Y) > max(g.FontSize, prev.FontSize)/2:
+				lines = append(lines, strings.TrimSpace(line.String()))
+				line.Reset()
+			case g.X-(prev.X+prev.W) > g.FontSize*0.2 && g.S != " " && prev.S != " ":
+				line.WriteByte(' ')
+			}
+		}
+		line.WriteString(g.S)
+		prev = g
+	}
+	if line.Len() > 0 {
+		lines = append(lines, strings.TrimSpace(line.String()))
+	}
+	return lines
+}
+
+// pdfVersion returns the version from the %PDF-x.y header.
+func pdfVersion(r io.ReaderAt) string {
+	var header [16]byte
+	n, _ := r.ReadAt(header[:], 0)
+	version, ok := bytes.CutPrefix(header[:n], []byte("%PDF-"))
+	if !ok {
+		return ""
+	}
+	if i := bytes.IndexAny(version, "\r\n \t%"); i >= 0 {
+		version = version[:i]
+	}
+	return string(version)
+}
+
+// formatPDFDate formats a PDF date string, D:YYYYMMDDHHmmSSOHH'mm', like the
+// dates in the file list. Values that cannot be parsed are returned as is.
+func formatPDFDate(s string) string {
+	raw := strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	if raw == "" {
+		return ""
+	}
+
+	// Normalise the time zone, e.g. +01'00' or Z, to +0100.
+	zone := "Z"
+	if i := strings.IndexAny(raw, "Z+-"); i >= 0 {
+		zone = strings.NewReplacer("'", "").Replace(raw[i:])
+		raw = raw[:i]
+		if zone != "Z" && len(zone) == 3 {
+			zone += "00"
+		}
+	}
+
+	layouts := []string{"20060102150405", "200601021504", "2006010215", "20060102", "200601", "2006"}
+	for _, layout := range layouts {
+		if len(raw) != len(layout) {
+			continue
+		}
+		t, err := time.Parse(layout, raw)
+		if err != nil {
+			break
+		}
+		if zone != "Z" {
+			if z, err := time.Parse("-0700", zone); err == nil {
+				t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, z.Location())
+			}
+		}
+		return FormatDateModified(t)
+	}
+	return s
+}
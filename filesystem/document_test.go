@@ -0,0 +1,103 @@
+package filesystem
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestCellColumn(t *testing.T) {
+	tests := []struct {
+		ref  string
+		col  int
+		want bool
+	}{
+		{"A1", 0, true},
+		{"Z9", 25, true},
+		{"AA1", 26, true},
+		{"XFD1", maxSheetColumns - 1, true},
+		{"XFE1", maxSheetColumns, true},
+		{"ZZZZZZZZZZZZZZZZZZZZ1", maxSheetColumns, true},
+		{"", 0, false},
+		{"12", 0, false},
+	}
+	for _, tt := range tests {
+		col, ok := cellColumn(tt.ref)
+		if col != tt.col || ok != tt.want {
+			t.Errorf("cellColumn(%q) = %d, %t; want %d, %t", tt.ref, col, ok, tt.col, tt.want)
+		}
+	}
+}
+
+// worksheetFiles returns the members of an xlsx file holding only the given
+// worksheet XML.
+func worksheetFiles(t *testing.T, sheet string) map[string]*zip.File {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte(sheet)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	return files
+}
+
+func TestReadWorksheetFarColumns(t *testing.T) {
+	var rows strings.Builder
+	for i := 1; i <= MaxDocumentSheetRows+5; i++ {
+		fmt.Fprintf(&rows, `<row r="%d"><c r="A%d"><v>%d</v></c><c r="XFD%d"><v>far</v></c><c r="ZZZZZZZZ%d"><v>invalid</v></c></row>`, i, i, i, i, i)
+	}
+	sheet := `<worksheet><sheetData>` + rows.String() + `</sheetData></worksheet>`
+
+	got, err := readWorksheet(context.Background(), worksheetFiles(t, sheet), "xl/worksheets/sheet1.xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Rows) != MaxDocumentSheetRows || !got.Truncated {
+		t.Errorf("got %d rows, truncated %t; want %d, true", len(got.Rows), got.Truncated, MaxDocumentSheetRows)
+	}
+	for i, row := range got.Rows {
+		if len(row) != 1 || row[0] != fmt.Sprint(i+1) {
+			t.Fatalf("row %d = %q; want only its first cell", i, row)
+		}
+	}
+	if want := maxSheetColumns - MaxDocumentSheetColumns; got.MoreColumns != want {
+		t.Errorf("MoreColumns = %d; want %d", got.MoreColumns, want)
+	}
+}
+
+func TestReadWorksheetFillsGaps(t *testing.T) {
+	sheet := `<worksheet><sheetData>` +
+		`<row r="1"><c r="A1"><v>1</v></c><c r="C1" t="b"><v>1</v></c><c><v>next</v></c></row>` +
+		`</sheetData></worksheet>`
+
+	got, err := readWorksheet(context.Background(), worksheetFiles(t, sheet), "xl/worksheets/sheet1.xml", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"1", "", "TRUE", "next"}
+	if len(got.Rows) != 1 || strings.Join(got.Rows[0], ",") != strings.Join(want, ",") {
+		t.Errorf("rows = %q; want [%q]", got.Rows, want)
+	}
+	if got.MoreColumns != 0 {
+		t.Errorf("MoreColumns = %d; want 0", got.MoreColumns)
+	}
+}
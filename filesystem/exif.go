@@ -0,0 +1,472 @@
+package filesystem
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// ErrNoEXIF is returned by ReadEXIF for images without EXIF data.
+var ErrNoEXIF = errors.New("no EXIF data")
+
+// exifMaxBytes bounds the size of an EXIF block read into memory.
+const exifMaxBytes = 1024 * 1024
+
+// EXIF holds the commonly useful EXIF fields of a photo. Fields that are not
+// present in the image are left at their zero value.
+type EXIF struct {
+	Make      string
+	Model     string
+	LensMake  string
+	LensModel string
+	Software  string
+	DateTime  string // DateTimeOriginal, or DateTime when missing
+
+	// Orientation is the EXIF orientation, 1-8; 0 when unknown.
+	Orientation int
+
+	Width  int // PixelXDimension
+	Height int // PixelYDimension
+
+	// ColorSpace is "sRGB", "Uncalibrated" or "" when unknown.
+	ColorSpace string
+
+	ExposureTime    string // e.g. "1/250"
+	FNumber         float64
+	ISO             int
+	ExposureBias    float64
+	HasExposureBias bool
+	FocalLength     float64
+	FocalLength35   int
+	FlashFired      bool
+	HasFlash        bool
+
+	// Latitude and Longitude are in decimal degrees, Altitude in metres.
+	HasGPS      bool
+	Latitude    float64
+	Longitude   float64
+	Altitude    float64
+	HasAltitude bool
+}
+
+// OrientationName describes an EXIF orientation value.
+func OrientationName(o int) string {
+	switch o {
+	case 1:
+		return "Normal"
+	case 2:
+		return "Mirrored horizontally"
+	case 3:
+		return "Rotated 180°"
+	case 4:
+		return "Mirrored vertically"
+	case 5:
+		return "Mirrored horizontally, rotated 270° CW"
+	case 6:
+		return "Rotated 90° CW"
+	case 7:
+		return "Mirrored horizontally, rotated 90° CW"
+	case 8:
+		return "Rotated 270° CW"
+	default:
+		return ""
+	}
+}
+
+// ReadEXIF reads the EXIF data of a JPEG, TIFF (including TIFF-based raw
+// formats), PNG or WebP image. Only the metadata blocks are read, never the
+// image data.
+func ReadEXIF(path string) (EXIF, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return EXIF{}, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 12)
+	if _, err := io.ReadFull(f, head); err != nil {
+		return EXIF{}, ErrNoEXIF
+	}
+
+	var tiff []byte
+	switch {
+	case bytes.HasPrefix(head, []byte{0xff, 0xd8}):
+		tiff, err = jpegEXIF(f)
+	case bytes.HasPrefix(head, []byte("II*\x00")), bytes.HasPrefix(head, []byte("MM\x00*")):
+		tiff, err = readTIFFHead(f)
+	case bytes.HasPrefix(head, []byte("\x89PNG\r\n\x1a\n")):
+		tiff, err = pngEXIF(f)
+	case bytes.HasPrefix(head, []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WEBP")):
+		tiff, err = webpEXIF(f)
+	default:
+		return EXIF{}, ErrNoEXIF
+	}
+	if err != nil {
+		return EXIF{}, err
+	}
+
+	return parseEXIF(bytes.TrimPrefix(tiff, []byte("Exif\x00\x00")))
+}
+
+// jpegEXIF returns the payload of the APP1 Exif segment of a JPEG file.
+func jpegEXIF(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(2, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	marker := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(f, marker); err != nil {
+			return nil, ErrNoEXIF
+		}
+		if marker[0] != 0xff {
+			return nil, ErrNoEXIF
+		}
+		// Start of scan: the metadata segments are over.
+		if marker[1] == 0xda || marker[1] == 0xd9 {
+			return nil, ErrNoEXIF
+		}
+
+		size := int64(binary.BigEndian.Uint16(marker[2:])) - 2
+		if size < 0 {
+			return nil, ErrNoEXIF
+		}
+		if marker[1] == 0xe1 {
+			data := make([]byte, size)
+			if _, err := io.ReadFull(f, data); err != nil {
+				return nil, err
+			}
+			if bytes.HasPrefix(data, []byte("Exif\x00\x00")) {
+				return data, nil
+			}
+			continue
+		}
+		if _, err := f.Seek(size, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// readTIFFHead returns the start of a TIFF file, which holds its IFDs in
+// practically every camera and raw file.
+func readTIFFHead(f *os.File) ([]byte, error) {
+	data := make([]byte, exifMaxBytes)
+	n, err := f.ReadAt(data, 0)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+// pngEXIF returns the content of a PNG eXIf chunk.
+func pngEXIF(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(8, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, hdr); err != nil {
+			return nil, ErrNoEXIF
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		switch string(hdr[4:]) {
+		case "eXIf":
+			if size > exifMaxBytes {
+				return nil, ErrNoEXIF
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(f, data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		case "IEND":
+			return nil, ErrNoEXIF
+		}
+		// Skip the chunk data and its CRC.
+		if _, err := f.Seek(size+4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// webpEXIF returns the content of a WebP EXIF chunk.
+func webpEXIF(f *os.File) ([]byte, error) {
+	if _, err := f.Seek(12, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	hdr := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(f, hdr); err != nil {
+			return nil, ErrNoEXIF
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		if string(hdr[:4]) == "EXIF" {
+			if size > exifMaxBytes {
+				return nil, ErrNoEXIF
+			}
+			data := make([]byte, size)
+			if _, err := io.ReadFull(f, data); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+		// Chunks are padded to an even size.
+		if _, err := f.Seek(size+size%2, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// EXIF tags read by parseEXIF.
+const (
+	tagMake             = 0x010f
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagSoftware         = 0x0131
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagExposureTime     = 0x829a
+	tagFNumber          = 0x829d
+	tagISO              = 0x8827
+	tagDateTimeOriginal = 0x9003
+	tagExposureBias     = 0x9204
+	tagFlash            = 0x9209
+	tagFocalLength      = 0x920a
+	tagColorSpace       = 0xa001
+	tagPixelXDimension  = 0xa002
+	tagPixelYDimension  = 0xa003
+	tagFocalLength35    = 0xa405
+	tagLensMake         = 0xa433
+	tagLensModel        = 0xa434
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+	tagGPSAltitudeRef   = 0x0005
+	tagGPSAltitude      = 0x0006
+)
+
+// tiffEntry is a raw IFD entry; data holds the entry's value bytes.
+type tiffEntry struct {
+	typ   uint16
+	count uint32
+	data  []byte
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// tiffTypeSizes gives the size in bytes of each TIFF field type.
+var tiffTypeSizes = map[uint16]uint32{
+	1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8,
+}
+
+// ifd reads the IFD at offset into a map of tag to entry.
+func (t *tiffReader) ifd(offset uint32) map[uint16]tiffEntry {
+	entries := map[uint16]tiffEntry{}
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return entries
+	}
+
+	n := int(t.order.Uint16(t.data[offset:]))
+	pos := int(offset) + 2
+	for i := 0; i < n && pos+12 <= len(t.data); i, pos = i+1, pos+12 {
+		tag := t.order.Uint16(t.data[pos:])
+		typ := t.order.Uint16(t.data[pos+2:])
+		count := t.order.Uint32(t.data[pos+4:])
+
+		size, ok := tiffTypeSizes[typ]
+		if !ok || count > exifMaxBytes {
+			continue
+		}
+		total := uint64(size) * uint64(count)
+
+		var value []byte
+		if total <= 4 {
+			value = t.data[pos+8 : pos+8+int(total)]
+		} else {
+			off := uint64(t.order.Uint32(t.data[pos+8:]))
+			if off+total > uint64(len(t.data)) {
+				continue
+			}
+			value = t.data[off : off+total]
+		}
+		entries[tag] = tiffEntry{typ: typ, count: count, data: value}
+	}
+	return entries
+}
+
+func (t *tiffReader) str(e tiffEntry) string {
+	return strings.TrimSpace(strings.TrimRight(string(e.data), "\x00"))
+}
+
+func (t *tiffReader) uint(e tiffEntry) (uint32, bool) {
+	if e.count == 0 {
+		return 0, false
+	}
+	switch e.typ {
+	case 1, 7:
+		return uint32(e.data[0]), true
+	case 3:
+		return uint32(t.order.Uint16(e.data)), true
+	case 4:
+		return t.order.Uint32(e.data), true
+	}
+	return 0, false
+}
+
+// rational returns the i-th (signed or unsigned) rational of an entry as its
+// numerator and denominator.
+func (t *tiffReader) rational(e tiffEntry, i int) (float64, float64, bool) {
+	if (e.typ != 5 && e.typ != 10) || uint32(i) >= e.count {
+		return 0, 0, false
+	}
+	b := e.data[i*8:]
+	if e.typ == 10 {
+		return float64(int32(t.order.Uint32(b))), float64(int32(t.order.Uint32(b[4:]))), true
+	}
+	return float64(t.order.Uint32(b)), float64(t.order.Uint32(b[4:])), true
+}
+
+func (t *tiffReader) float(e tiffEntry, i int) (float64, bool) {
+	num, den, ok := t.rational(e, i)
+	if !ok || den == 0 {
+		return 0, false
+	}
+	return num / den, true
+}
+
+// parseEXIF decodes the TIFF structure of an EXIF block.
+func parseEXIF(data []byte) (EXIF, error) {
+	var x EXIF
+	if len(data) < 8 {
+		return x, ErrNoEXIF
+	}
+
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return x, ErrNoEXIF
+	}
+
+	ifd0 := t.ifd(t.order.Uint32(data[4:]))
+	if len(ifd0) == 0 {
+		return x, ErrNoEXIF
+	}
+
+	x.Make = t.str(ifd0[tagMake])
+	x.Model = t.str(ifd0[tagModel])
+	x.Software = t.str(ifd0[tagSoftware])
+	x.DateTime = t.str(ifd0[tagDateTime])
+	if o, ok := t.uint(ifd0[tagOrientation]); ok {
+		x.Orientation = int(o)
+	}
+
+	if off, ok := t.uint(ifd0[tagExifIFD]); ok {
+		exif := t.ifd(off)
+
+		if dt := t.str(exif[tagDateTimeOriginal]); dt != "" {
+			x.DateTime = dt
+		}
+		x.LensMake = t.str(exif[tagLensMake])
+		x.LensModel = t.str(exif[tagLensModel])
+
+		if num, den, ok := t.rational(exif[tagExposureTime], 0); ok && den != 0 {
+			x.ExposureTime = formatExposureTime(num, den)
+		}
+		x.FNumber, _ = t.float(exif[tagFNumber], 0)
+		x.FocalLength, _ = t.float(exif[tagFocalLength], 0)
+		x.ExposureBias, x.HasExposureBias = t.float(exif[tagExposureBias], 0)
+
+		if v, ok := t.uint(exif[tagISO]); ok {
+			x.ISO = int(v)
+		}
+		if v, ok := t.uint(exif[tagFocalLength35]); ok {
+			x.FocalLength35 = int(v)
+		}
+		if v, ok := t.uint(exif[tagFlash]); ok {
+			x.HasFlash = true
+			x.FlashFired = v&1 != 0
+		}
+		if v, ok := t.uint(exif[tagPixelXDimension]); ok {
+			x.Width = int(v)
+		}
+		if v, ok := t.uint(exif[tagPixelYDimension]); ok {
+			x.Height = int(v)
+		}
+		if v, ok := t.uint(exif[tagColorSpace]); ok {
+			switch v {
+			case 1:
+				x.ColorSpace = "sRGB"
+			case 0xffff:
+				x.ColorSpace = "Uncalibrated"
+			}
+		}
+	}
+
+	if off, ok := t.uint(ifd0[tagGPSIFD]); ok {
+		gps := t.ifd(off)
+
+		lat, latOK := gpsCoordinate(t, gps[tagGPSLatitude])
+		lon, lonOK := gpsCoordinate(t, gps[tagGPSLongitude])
+		if latOK && lonOK {
+			if t.str(gps[tagGPSLatitudeRef]) == "S" {
+				lat = -lat
+			}
+			if t.str(gps[tagGPSLongitudeRef]) == "W" {
+				lon = -lon
+			}
+			x.HasGPS = true
+			x.Latitude, x.Longitude = lat, lon
+		}
+
+		if alt, ok := t.float(gps[tagGPSAltitude], 0); ok {
+			if ref, ok := t.uint(gps[tagGPSAltitudeRef]); ok && ref == 1 {
+				alt = -alt
+			}
+			x.HasAltitude = true
+			x.Altitude = alt
+		}
+	}
+
+	return x, nil
+}
+
+// gpsCoordinate converts degrees, minutes and seconds into decimal degrees.
+func gpsCoordinate(t *tiffReader, e tiffEntry) (float64, bool) {
+	deg, ok := t.float(e, 0)
+	if !ok {
+		return 0, false
+	}
+	minutes, _ := t.float(e, 1)
+	seconds, _ := t.float(e, 2)
+	v := deg + minutes/60 + seconds/3600
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, false
+	}
+	return v, true
+}
+
+// formatExposureTime formats an exposure time the way cameras display it,
+// e.g. "1/250" for short and "2.5" for long exposures.
+func formatExposureTime(num, den float64) string {
+	v := num / den
+	if v >= 1 || num == 0 {
+		return fmt.Sprintf("%g", math.Round(v*10)/10)
+	}
+	return fmt.Sprintf("1/%g", math.Round(den/num))
+}
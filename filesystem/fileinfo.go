@@ -48,61 +48,7 @@ func ListDirectory(dirPath string) ([]FileInfo, error) {
 			continue
 		}
 
-		// Get system-specific file info for user/group
-		sysInfo := info.Sys()
-		var uid, gid uint32
-		if sysInfo != nil {
-			if stat, ok := sysInfo.(*syscall.Stat_t); ok {
-				uid = stat.Uid
-				gid = stat.Gid
-			}
-		}
-
-		// Get username
-		username := "unknown"
-		if u, err := user.LookupId(fmt.Sprintf("%d", uid)); err == nil {
-			username = u.Username
-		}
-
-		// Get group name
-		groupname := "unknown"
-		if g, err := user.LookupGroupId(fmt.Sprintf("%d", gid)); err == nil {
-			groupname = g.Name
-		}
-
-		// Format permissions
-		permissions := formatPermissions(info.Mode(), entry.IsDir())
-
-		// Format size. For regular files we use the file size directly. For
-		// directories, we compute a shallow size by summing the sizes of
-		// non-directory entries in that directory.
-		var size string
-		if entry.IsDir() {
-			dirSize := calculateDirectorySize(fullPath)
-			size = formatSize(dirSize, false)
-		} else {
-			size = formatSize(info.Size(), false)
-		}
-
-		// Format date modified
-		dateModified := formatDateModified(info.ModTime())
-
-		// Determine file type for colorization.
-		fileType := classifyFileType(info, entry.IsDir())
-
-		fileInfo := FileInfo{
-			Permissions:  permissions,
-			Size:         size,
-			User:         username,
-			Group:        groupname,
-			DateModified: dateModified,
-			Name:         entry.Name(),
-			IsDir:        entry.IsDir(),
-			Path:         fullPath,
-			Type:         fileType,
-		}
-
-		fileInfos = append(fileInfos, fileInfo)
+		fileInfos = append(fileInfos, newFileInfo(fullPath, info))
 	}
 
 	// Sort: directories first, then files, both alphabetically
@@ -121,6 +67,75 @@ func ListDirectory(dirPath string) ([]FileInfo, error) {
 	return fileInfos, nil
 }
 
+// Stat returns the FileInfo for a single path, formatted the same way as the
+// entries returned by ListDirectory. Symlinks are not followed.
+func Stat(path string) (FileInfo, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return newFileInfo(path, info), nil
+}
+
+// newFileInfo builds the display information for a single entry.
+func newFileInfo(fullPath string, info os.FileInfo) FileInfo {
+	isDir := info.IsDir()
+
+	// Get system-specific file info for user/group
+	sysInfo := info.Sys()
+	var uid, gid uint32
+	if sysInfo != nil {
+		if stat, ok := sysInfo.(*syscall.Stat_t); ok {
+			uid = stat.Uid
+			gid = stat.Gid
+		}
+	}
+
+	// Get username
+	username := "unknown"
+	if u, err := user.LookupId(fmt.Sprintf("%d", uid)); err == nil {
+		username = u.Username
+	}
+
+	// Get group name
+	groupname := "unknown"
+	if g, err := user.LookupGroupId(fmt.Sprintf("%d", gid)); err == nil {
+		groupname = g.Name
+	}
+
+	// Format permissions
+	permissions := formatPermissions(info.Mode(), isDir)
+
+	// Format size. For regular files we use the file size directly. For
+	// directories, we compute a shallow size by summing the sizes of
+	// non-directory entries in that directory.
+	var size string
+	if isDir {
+		dirSize := calculateDirectorySize(fullPath)
+		size = formatSize(dirSize, false)
+	} else {
+		size = formatSize(info.Size(), false)
+	}
+
+	// Format date modified
+	dateModified := formatDateModified(info.ModTime())
+
+	// Determine file type for colorization.
+	fileType := classifyFileType(info, isDir)
+
+	return FileInfo{
+		Permissions:  permissions,
+		Size:         size,
+		User:         username,
+		Group:        groupname,
+		DateModified: dateModified,
+		Name:         info.Name(),
+		IsDir:        isDir,
+		Path:         fullPath,
+		Type:         fileType,
+	}
+}
+
 // classifyFileType classifies a file into a high-level type used for styling.
 func classifyFileType(info os.FileInfo, isDir bool) string {
 	if isDir {
@@ -267,3 +282,18 @@ func formatDateModified(modTime time.Time) string {
 	// Format: "02 Jan 15:04"
 	return modTime.Format("02 Jan 15:04")
 }
+
+// FormatSize formats a byte count the same way as file sizes in listings.
+func FormatSize(size int64) string {
+	return formatSize(size, false)
+}
+
+// FormatPermissions formats mode the same way as permissions in listings.
+func FormatPermissions(mode os.FileMode) string {
+	return formatPermissions(mode, mode.IsDir())
+}
+
+// FormatDateModified formats t the same way as dates in listings.
+func FormatDateModified(t time.Time) string {
+	return formatDateModified(t)
+}
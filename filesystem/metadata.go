@@ -0,0 +1,192 @@
+package filesystem
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// Metadata holds extended information about a file, beyond the columns shown
+// in the file list.
+type Metadata struct {
+	Path       string
+	Mode       os.FileMode
+	Size       int64
+	Inode      uint64
+	Links      uint64
+	Device     uint64
+	Blocks     int64
+	BlockSize  int64
+	UID        uint32
+	GID        uint32
+	User       string
+	Group      string
+	ModTime    time.Time
+	AccessTime time.Time
+	ChangeTime time.Time
+	// LinkTarget is the destination of a symlink; empty for other files.
+	LinkTarget string
+	// MimeType is guessed from the extension, falling back to sniffing the
+	// first bytes of regular files.
+	MimeType string
+}
+
+// ReadMetadata collects extended metadata for path. Symlinks are described
+// themselves rather than followed.
+func ReadMetadata(path string) (Metadata, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	md := Metadata{
+		Path:    path,
+		Mode:    info.Mode(),
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+		User:    "unknown",
+		Group:   "unknown",
+	}
+
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		md.Inode = uint64(stat.Ino)
+		md.Links = uint64(stat.Nlink)
+		md.Device = uint64(stat.Dev)
+		md.Blocks = int64(stat.Blocks)
+		md.BlockSize = int64(stat.Blksize)
+		md.UID = stat.Uid
+		md.GID = stat.Gid
+		md.AccessTime, md.ChangeTime = statTimes(stat)
+
+		if u, err := user.LookupId(strconv.FormatUint(uint64(stat.Uid), 10)); err == nil {
+			md.User = u.Username
+		}
+		if g, err := user.LookupGroupId(strconv.FormatUint(uint64(stat.Gid), 10)); err == nil {
+			md.Group = g.Name
+		}
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		md.LinkTarget, _ = os.Readlink(path)
+	}
+
+	md.MimeType = detectMimeType(path, info)
+
+	return md, nil
+}
+
+// MimeType guesses the MIME type of the file at path, following symlinks. It
+// returns an empty string when the file cannot be read.
+func MimeType(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return detectMimeType(path, info)
+}
+
+// detectMimeType guesses the MIME type of a file from its extension, or by
+// sniffing the first 512 bytes of a regular file.
+func detectMimeType(path string, info os.FileInfo) string {
+	switch {
+	case info.IsDir():
+		return "inode/directory"
+	case info.Mode()&os.ModeSymlink != 0:
+		return "inode/symlink"
+	case !info.Mode().IsRegular():
+		return "inode/special"
+	}
+
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	if n == 0 {
+		return "inode/x-empty"
+	}
+	return http.DetectContentType(buf[:n])
+}
+
+// TogglePermission flips a single permission bit (e.g. 0o200 for owner write)
+// on path, keeping the setuid, setgid and sticky bits intact. Like SetOwner
+// it does not follow symlinks; as their own permissions cannot be changed,
+// symlinks are refused.
+func TogglePermission(path string, bit os.FileMode) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode()&os.ModeSymlink != 0 {
+		return fmt.Errorf("%s is a symlink; change the permissions of its target instead", filepath.Base(path))
+	}
+	keep := os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	return os.Chmod(path, (info.Mode()&keep)^bit)
+}
+
+// SetOwner changes the owner and/or group of path. Either name may be empty
+// to leave it unchanged, and numeric IDs are accepted as well as names.
+// Symlinks are changed themselves rather than followed.
+func SetOwner(path, owner, group string) error {
+	uid, gid := -1, -1
+
+	if owner != "" {
+		id, err := lookupID(owner, func(name string) (string, error) {
+			u, err := user.Lookup(name)
+			if err != nil {
+				return "", err
+			}
+			return u.Uid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("unknown user %q: %w", owner, err)
+		}
+		uid = id
+	}
+
+	if group != "" {
+		id, err := lookupID(group, func(name string) (string, error) {
+			g, err := user.LookupGroup(name)
+			if err != nil {
+				return "", err
+			}
+			return g.Gid, nil
+		})
+		if err != nil {
+			return fmt.Errorf("unknown group %q: %w", group, err)
+		}
+		gid = id
+	}
+
+	return os.Lchown(path, uid, gid)
+}
+
+// lookupID resolves a user or group name to its numeric ID. Purely numeric
+// names are used as-is.
+func lookupID(name string, lookup func(string) (string, error)) (int, error) {
+	if id, err := strconv.Atoi(name); err == nil {
+		return id, nil
+	}
+	raw, err := lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(raw)
+}
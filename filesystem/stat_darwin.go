@@ -0,0 +1,13 @@
+package filesystem
+
+import (
+	"syscall"
+	"time"
+)
+
+// statTimes returns the access and status-change times from a stat result.
+func statTimes(stat *syscall.Stat_t) (atime, ctime time.Time) {
+	atime = time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec))
+	ctime = time.Unix(int64(stat.Ctimespec.Sec), int64(stat.Ctimespec.Nsec))
+	return atime, ctime
+}
@@ -0,0 +1,13 @@
+package filesystem
+
+import (
+	"syscall"
+	"time"
+)
+
+// statTimes returns the access and status-change times from a stat result.
+func statTimes(stat *syscall.Stat_t) (atime, ctime time.Time) {
+	atime = time.Unix(int64(stat.Atim.Sec), int64(stat.Atim.Nsec))
+	ctime = time.Unix(int64(stat.Ctim.Sec), int64(stat.Ctim.Nsec))
+	return atime, ctime
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package filesystem
+
+import (
+	"syscall"
+	"time"
+)
+
+// statTimes is not implemented on this platform; zero times are shown as
+// unknown.
+func statTimes(stat *syscall.Stat_t) (atime, ctime time.Time) {
+	return time.Time{}, time.Time{}
+}
@@ -0,0 +1,251 @@
+package filesystem
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ThumbnailSize is a freedesktop thumbnail size bucket. Thumbnails in a
+// bucket fit within a square of that many pixels.
+type ThumbnailSize struct {
+	Dir    string
+	Pixels int
+}
+
+// ThumbnailSizes lists the freedesktop size buckets from smallest to largest.
+var ThumbnailSizes = []ThumbnailSize{
+	{Dir: "normal", Pixels: 128},
+	{Dir: "large", Pixels: 256},
+	{Dir: "x-large", Pixels: 512},
+	{Dir: "xx-large", Pixels: 1024},
+}
+
+// ThumbnailSizeFor returns the smallest bucket that holds thumbnails of at
+// least pixels in size, or the largest bucket.
+func ThumbnailSizeFor(pixels int) ThumbnailSize {
+	for _, size := range ThumbnailSizes {
+		if size.Pixels >= pixels {
+			return size
+		}
+	}
+	return ThumbnailSizes[len(ThumbnailSizes)-1]
+}
+
+// ErrNoThumbnailCache is returned when no thumbnail cache directory can be
+// determined.
+var ErrNoThumbnailCache = errors.New("no thumbnail cache directory")
+
+// ThumbnailCacheDir returns the shared thumbnail cache directory,
+// $XDG_CACHE_HOME/thumbnails or ~/.cache/thumbnails.
+func ThumbnailCacheDir() (string, error) {
+	if dir := os.Getenv("XDG_CACHE_HOME"); filepath.IsAbs(dir) {
+		return filepath.Join(dir, "thumbnails"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", ErrNoThumbnailCache
+	}
+	return filepath.Join(home, ".cache", "thumbnails"), nil
+}
+
+// FileURI returns the file:// URI of an absolute path, escaped the way GLib
+// does so that thumbnail names match those of other applications.
+func FileURI(path string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.WriteString("file://")
+	for i := 0; i < len(path); i++ {
+		c := path[i]
+		if c < 0x80 && (c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			strings.IndexByte("!$&'()*+,-./:=@_~", c) >= 0) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hexDigits[c>>4])
+		b.WriteByte(hexDigits[c&0x0f])
+	}
+	return b.String()
+}
+
+// ThumbnailPath returns where the thumbnail of path is stored in the given
+// size bucket: the MD5 of the file's URI, in hex, with a .png extension.
+func ThumbnailPath(path string, size ThumbnailSize) (string, error) {
+	dir, err := ThumbnailCacheDir()
+	if err != nil {
+		return "", err
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+
+	sum := md5.Sum([]byte(FileURI(abs)))
+	return filepath.Join(dir, size.Dir, hex.EncodeToString(sum[:])+".png"), nil
+}
+
+// LookupThumbnail returns a cached thumbnail of path that is at least as
+// large as size. A thumbnail is only used while its Thumb::MTime (and
+// Thumb::Size, if recorded) still match the file; stale thumbnails are
+// ignored and get replaced by SaveThumbnail.
+func LookupThumbnail(path string, info os.FileInfo, size ThumbnailSize) ([]byte, bool) {
+	for _, bucket := range ThumbnailSizes {
+		if bucket.Pixels < size.Pixels {
+			continue
+		}
+
+		thumbPath, err := ThumbnailPath(path, bucket)
+		if err != nil {
+			return nil, false
+		}
+		data, err := os.ReadFile(thumbPath)
+		if err != nil {
+			continue
+		}
+
+		text, err := readPNGText(data)
+		if err != nil {
+			continue
+		}
+		if text["Thumb::MTime"] != strconv.FormatInt(info.ModTime().Unix(), 10) {
+			continue
+		}
+		if s, ok := text["Thumb::Size"]; ok && s != strconv.FormatInt(info.Size(), 10) {
+			continue
+		}
+		return data, true
+	}
+	return nil, false
+}
+
+// SaveThumbnail stores a PNG thumbnail of path in the cache, adding the
+// Thumb::URI, Thumb::MTime and Thumb::Size attributes required to validate
+// it. The file is written atomically with owner-only permissions, as the
+// specification requires. The PNG with the attributes is returned.
+func SaveThumbnail(path string, info os.FileInfo, size ThumbnailSize, png []byte) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := addPNGText(png, [][2]string{
+		{"Thumb::URI", FileURI(abs)},
+		{"Thumb::MTime", strconv.FormatInt(info.ModTime().Unix(), 10)},
+		{"Thumb::Size", strconv.FormatInt(info.Size(), 10)},
+		{"Software", "cute"},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	thumbPath, err := ThumbnailPath(path, size)
+	if err != nil {
+		return data, err
+	}
+
+	// Thumbnails of thumbnails are not cached.
+	if cacheDir, err := ThumbnailCacheDir(); err == nil && strings.HasPrefix(abs, cacheDir+string(filepath.Separator)) {
+		return data, nil
+	}
+
+	dir := filepath.Dir(thumbPath)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return data, err
+	}
+
+	tmp, err := os.CreateTemp(dir, "cute-*.png")
+	if err != nil {
+		return data, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return data, err
+	}
+	if err := tmp.Close(); err != nil {
+		return data, err
+	}
+	return data, os.Rename(tmp.Name(), thumbPath)
+}
+
+// pngSignature starts every PNG file.
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// readPNGText returns the tEXt key/value pairs of a PNG file.
+func readPNGText(data []byte) (map[string]string, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errors.New("not a PNG file")
+	}
+
+	text := map[string]string{}
+	r := bytes.NewReader(data[len(pngSignature):])
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, fmt.Errorf("reading PNG chunk: %w", err)
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+		if int64(length) > int64(r.Len()) {
+			return nil, errors.New("truncated PNG chunk")
+		}
+
+		switch kind {
+		case "IEND":
+			return text, nil
+		case "tEXt":
+			chunk := make([]byte, length)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, err
+			}
+			if key, value, ok := bytes.Cut(chunk, []byte{0}); ok {
+				text[string(key)] = string(value)
+			}
+		default:
+			if _, err := r.Seek(int64(length), io.SeekCurrent); err != nil {
+				return nil, err
+			}
+		}
+
+		// Skip the CRC.
+		if _, err := r.Seek(4, io.SeekCurrent); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addPNGText inserts tEXt chunks right after the IHDR chunk of a PNG file.
+func addPNGText(data []byte, pairs [][2]string) ([]byte, error) {
+	// The signature is followed by the 13 byte IHDR chunk, which must come
+	// first: 4 bytes length, 4 bytes type, data and 4 bytes CRC.
+	const ihdrEnd = 8 + 4 + 4 + 13 + 4
+	if !bytes.HasPrefix(data, pngSignature) || len(data) < ihdrEnd || string(data[12:16]) != "IHDR" {
+		return nil, errors.New("not a PNG file")
+	}
+
+	var out bytes.Buffer
+	out.Write(data[:ihdrEnd])
+	for _, pair := range pairs {
+		chunk := append([]byte("tEXt"), pair[0]...)
+		chunk = append(chunk, 0)
+		chunk = append(chunk, pair[1]...)
+
+		binary.Write(&out, binary.BigEndian, uint32(len(chunk)-4))
+		out.Write(chunk)
+		binary.Write(&out, binary.BigEndian, crc32.ChecksumIEEE(chunk))
+	}
+	out.Write(data[ihdrEnd:])
+	return out.Bytes(), nil
+}
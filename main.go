@@ -27,6 +27,7 @@ func main() {
 	m.SearchBar = components.SearchBar
 	m.CurrentDir = components.CurrentDir
 	m.Header = components.Header
+	m.Notification = components.Notification
 	m.StatusBar = components.StatusBar
 	m.ViewModeText = components.ViewModeText
 	m.PreviewTabs = components.PreviewTabs
@@ -38,6 +39,8 @@ func main() {
 	m.HelpModal = components.HelpModal
 	m.CommandModal = components.CommandModal
 	m.QuitModal = components.QuitModal
+	m.ConfigErrorsModal = components.ConfigErrorsModal
+	m.ThemePickerModal = components.ThemePickerModal
 
 	// Create a new Bubble Tea program
 	p := tea.NewProgram(m)
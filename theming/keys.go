@@ -0,0 +1,241 @@
+package theming
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"unicode"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Kinds of values a theme table holds, as returned by ThemeKeyKind.
+const (
+	// ThemeSection is a nested table, e.g. command_bar.
+	ThemeSection = "section"
+	// ThemeColor is a color: "#rrggbb", "#rgb", an ANSI color number, or ""
+	// for none.
+	ThemeColor = "color"
+	// ThemeStyle is a style spec, a color with attributes (see
+	// StyleFromSpec); the syntax and hex sections hold these.
+	ThemeStyle = "style"
+	// ThemeNumber is a number of cells, e.g. a padding.
+	ThemeNumber = "number"
+)
+
+// styleAttributes are the attributes StyleFromSpec understands.
+var styleAttributes = []string{"bold", "dim", "underline", "italic"}
+
+// legacyThemeKeys maps the flat keys of LoadThemeFromMap, which a theme
+// table still accepts at its top level, to the keys they stand for.
+var legacyThemeKeys = map[string][]string{
+	"directory":           {"file_type_colors", "directory"},
+	"symlink":             {"file_type_colors", "symlink"},
+	"socket":              {"file_type_colors", "socket"},
+	"pipe":                {"file_type_colors", "pipe"},
+	"device":              {"file_type_colors", "device"},
+	"executable":          {"file_type_colors", "executable"},
+	"regular":             {"file_type_colors", "regular"},
+	"nlink":               {"field_colors", "nlink"},
+	"user":                {"field_colors", "user"},
+	"group":               {"field_colors", "group"},
+	"size":                {"field_colors", "size"},
+	"time":                {"field_colors", "time"},
+	"border":              {"border_color"},
+	"selected_foreground": {"selection", "foreground"},
+	"selected_background": {"selection", "background"},
+}
+
+// A theme table mirrors Theme: its keys are the names of the fields in snake
+// case, and its sections are nested tables, e.g.
+//
+//	theme = {
+//	  primary = "#F25D94",
+//	  command_bar = { foreground = "#F0EDED", padding_left = 2 },
+//	  tui_mode = { normal_mode_background = "#FF9BC0" },
+//	  file_type_colors = { directory = "#A8D2FF" },
+//	  syntax = { keyword = "#F25D94+bold" },
+//	}
+//
+// The flat keys of LoadThemeFromMap are accepted at the top level too.
+
+// ThemeSectionKeys returns the keys of the theme table at path, sorted, or
+// nil when path is not a section. The top level has an empty path.
+func ThemeSectionKeys(path []string) []string {
+	v, ok := themeValue(reflect.ValueOf(DefaultTheme()), path)
+	if !ok {
+		return nil
+	}
+
+	var keys []string
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := range v.NumField() {
+			keys = append(keys, snakeCase(v.Type().Field(i).Name))
+		}
+	case reflect.Map:
+		for _, k := range v.MapKeys() {
+			keys = append(keys, k.String())
+		}
+	default:
+		return nil
+	}
+	if len(path) == 0 {
+		for k := range legacyThemeKeys {
+			if !slices.Contains(keys, k) {
+				keys = append(keys, k)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// ThemeKeyKind returns the kind of value the key path of a theme table
+// holds, or false when there is no such key.
+func ThemeKeyKind(path []string) (string, bool) {
+	path = resolveLegacyKey(path)
+	v, ok := themeValue(reflect.ValueOf(DefaultTheme()), path)
+	if !ok {
+		return "", false
+	}
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		return ThemeSection, true
+	case reflect.Int:
+		return ThemeNumber, true
+	}
+	if isStyleSection(path) {
+		return ThemeStyle, true
+	}
+	return ThemeColor, true
+}
+
+// SetString sets the color or style at the key path of t, after checking
+// it is one.
+func (t *Theme) SetString(path []string, s string) error {
+	kind, ok := ThemeKeyKind(path)
+	if !ok || (kind != ThemeColor && kind != ThemeStyle) {
+		return fmt.Errorf("not a color or style key")
+	}
+	if err := checkSpec(s, kind == ThemeStyle); err != nil {
+		return err
+	}
+
+	path = resolveLegacyKey(path)
+	parent, _ := themeValue(reflect.ValueOf(t).Elem(), path[:len(path)-1])
+	key := path[len(path)-1]
+	if parent.Kind() == reflect.Map {
+		if parent.IsNil() {
+			parent.Set(reflect.MakeMap(parent.Type()))
+		}
+		parent.SetMapIndex(reflect.ValueOf(key), reflect.ValueOf(s))
+		return nil
+	}
+	field, _ := themeValue(parent, []string{key})
+	field.SetString(s)
+	return nil
+}
+
+// SetNumber sets the number of cells at the key path of t.
+func (t *Theme) SetNumber(path []string, n int) error {
+	switch kind, ok := ThemeKeyKind(path); {
+	case !ok || kind != ThemeNumber:
+		return fmt.Errorf("not a number key")
+	case n < 0:
+		return fmt.Errorf("expected a number of cells, got %d", n)
+	}
+
+	field, _ := themeValue(reflect.ValueOf(t).Elem(), path)
+	field.SetInt(int64(n))
+	return nil
+}
+
+// themeValue returns the field, or map entry, of v at the key path.
+func themeValue(v reflect.Value, path []string) (reflect.Value, bool) {
+	for _, key := range path {
+		switch v.Kind() {
+		case reflect.Struct:
+			f, ok := v.Type().FieldByNameFunc(func(name string) bool { return snakeCase(name) == key })
+			if !ok {
+				return reflect.Value{}, false
+			}
+			v = v.FieldByIndex(f.Index)
+		case reflect.Map:
+			e := v.MapIndex(reflect.ValueOf(key))
+			if !e.IsValid() {
+				return reflect.Value{}, false
+			}
+			v = e
+		default:
+			return reflect.Value{}, false
+		}
+	}
+	return v, true
+}
+
+// resolveLegacyKey returns the key path a flat key of LoadThemeFromMap
+// stands for, or path itself.
+func resolveLegacyKey(path []string) []string {
+	if len(path) == 1 {
+		if p, ok := legacyThemeKeys[path[0]]; ok {
+			return p
+		}
+	}
+	return path
+}
+
+// isStyleSection reports whether the key path is in a section holding style
+// specs rather than colors.
+func isStyleSection(path []string) bool {
+	return len(path) == 2 && (path[0] == "syntax" || path[0] == "hex")
+}
+
+// checkSpec checks that s is a color, or, when attrs is set, a style spec.
+func checkSpec(s string, attrs bool) error {
+	if !attrs {
+		if slices.Contains(styleAttributes, strings.ToLower(strings.TrimSpace(s))) || strings.Contains(s, "+") {
+			return fmt.Errorf("expected a color, got %q; only the syntax and hex sections take attributes such as bold", s)
+		}
+		if !validColor(s) {
+			return fmt.Errorf("expected a color such as \"#ff8800\" or \"212\", got %q", s)
+		}
+		return nil
+	}
+
+	for _, token := range strings.Split(s, "+") {
+		token = strings.TrimSpace(token)
+		if !slices.Contains(styleAttributes, strings.ToLower(token)) && !validColor(token) {
+			return fmt.Errorf("unknown color or attribute %q in %q (expected a color such as \"#ff8800\" or \"212\", or bold, dim, underline or italic)", token, s)
+		}
+	}
+	return nil
+}
+
+// validColor reports whether lipgloss understands s as a color. An empty
+// string means no color.
+func validColor(s string) bool {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return true
+	}
+	_, none := lipgloss.Color(s).(lipgloss.NoColor)
+	return !none
+}
+
+// snakeCase converts a field name such as PreviewTabActive to
+// preview_tab_active.
+func snakeCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -72,7 +72,34 @@ var (
 	helpModeForeground      = color0
 	quitModeBackground      = "#000000"
 	quitModeForeground      = "#F0EDED"
+	previewModeBackground   = color8
+	previewModeForeground   = color0
+	searchMatchBackground   = color3
+	searchMatchForeground   = color0
+	searchCurrentBackground = color2
+	searchCurrentForeground = color0
+	previewTabBackground    = background
+	previewTabForeground    = "#A8A7A7"
+	previewTabActiveBg      = primary
+	previewTabActiveFg      = color0
 	dialogTitle             = color9
+	syntaxAttribute         = color3
+	syntaxBuiltin           = color7
+	syntaxComment           = "#7A7A7A+italic"
+	syntaxConstant          = color4
+	syntaxFunction          = color8
+	syntaxKeyword           = color2 + "+bold"
+	syntaxNumber            = color4
+	syntaxOperator          = color5
+	syntaxPunctuation       = foreground
+	syntaxString            = color3
+	syntaxTag               = color2
+	syntaxType              = color6
+	hexOffset               = "#7A7A7A"
+	hexNull                 = "#5A5A5A"
+	hexPrintable            = foreground
+	hexControl              = color5
+	hexExtended             = color3
 )
 
 type Style struct {
@@ -131,6 +158,40 @@ type TuiMode struct {
 	HelpModeForeground    string
 	QuitModeBackground    string
 	QuitModeForeground    string
+	PreviewModeBackground string
+	PreviewModeForeground string
+}
+
+// SyntaxStyle holds the style specs (see StyleFromSpec) used to highlight
+// source code in the text preview.
+type SyntaxStyle struct {
+	Attribute   string
+	Builtin     string
+	Comment     string
+	Constant    string
+	Function    string
+	Keyword     string
+	Number      string
+	Operator    string
+	Punctuation string
+	String      string
+	Tag         string
+	Type        string
+}
+
+// HexStyle holds the style specs (see StyleFromSpec) used by the hex dump
+// preview of binary files.
+type HexStyle struct {
+	// Offset colors the offset column.
+	Offset string
+	// Null colors zero bytes.
+	Null string
+	// Printable colors printable ASCII bytes.
+	Printable string
+	// Control colors ASCII control characters other than NUL.
+	Control string
+	// Extended colors bytes outside the ASCII range.
+	Extended string
 }
 
 type FilelistMode struct {
@@ -154,13 +215,21 @@ type Theme struct {
 	FileList       Style
 	FileTypeColors map[string]string
 	Header         StyleColor
+	Hex            HexStyle
 	Permissions    PermissionsStyle
 	Preview        Style
-	SearchBar      BarStyle
-	Selection      StyleColor
-	StatusBar      Style
-	ViewMode       StyleColor
-	TuiMode        TuiMode
+	// PreviewTab and PreviewTabActive color the tabs above the preview.
+	PreviewTab       StyleColor
+	PreviewTabActive StyleColor
+	SearchBar        BarStyle
+	// SearchMatch and SearchMatchCurrent color search hits in the preview.
+	SearchMatch        StyleColor
+	SearchMatchCurrent StyleColor
+	Selection          StyleColor
+	StatusBar          Style
+	Syntax             SyntaxStyle
+	ViewMode           StyleColor
+	TuiMode            TuiMode
 }
 
 // DefaultTheme returns a sane fallback theme used when the config
@@ -232,6 +301,14 @@ func DefaultTheme() Theme {
 			Background: headerBackground,
 		},
 
+		Hex: HexStyle{
+			Offset:    hexOffset,
+			Null:      hexNull,
+			Printable: hexPrintable,
+			Control:   hexControl,
+			Extended:  hexExtended,
+		},
+
 		Permissions: PermissionsStyle{
 			Exec:  permExec,
 			Read:  permRead,
@@ -250,6 +327,16 @@ func DefaultTheme() Theme {
 			PaddingTop:       0,
 		},
 
+		PreviewTab: StyleColor{
+			Background: previewTabBackground,
+			Foreground: previewTabForeground,
+		},
+
+		PreviewTabActive: StyleColor{
+			Background: previewTabActiveBg,
+			Foreground: previewTabActiveFg,
+		},
+
 		SearchBar: BarStyle{
 			Background:    background,
 			Border:        borderColor,
@@ -271,11 +358,36 @@ func DefaultTheme() Theme {
 			PaddingTop:    0,
 		},
 
+		SearchMatch: StyleColor{
+			Background: searchMatchBackground,
+			Foreground: searchMatchForeground,
+		},
+
+		SearchMatchCurrent: StyleColor{
+			Background: searchCurrentBackground,
+			Foreground: searchCurrentForeground,
+		},
+
 		Selection: StyleColor{
 			Background: "#3B3B3B",
 			Foreground: background,
 		},
 
+		Syntax: SyntaxStyle{
+			Attribute:   syntaxAttribute,
+			Builtin:     syntaxBuiltin,
+			Comment:     syntaxComment,
+			Constant:    syntaxConstant,
+			Function:    syntaxFunction,
+			Keyword:     syntaxKeyword,
+			Number:      syntaxNumber,
+			Operator:    syntaxOperator,
+			Punctuation: syntaxPunctuation,
+			String:      syntaxString,
+			Tag:         syntaxTag,
+			Type:        syntaxType,
+		},
+
 		TuiMode: TuiMode{
 			CommandModeBackground: commandModeBackground,
 			CommandModeForeground: commandModeForeground,
@@ -287,6 +399,8 @@ func DefaultTheme() Theme {
 			NormalModeForeground:  normalModeForeground,
 			QuitModeBackground:    quitModeBackground,
 			QuitModeForeground:    quitModeForeground,
+			PreviewModeBackground: previewModeBackground,
+			PreviewModeForeground: previewModeForeground,
 		},
 
 		ViewMode: StyleColor{
@@ -296,6 +410,13 @@ func DefaultTheme() Theme {
 	}
 }
 
+// ThemeKeys are the keys understood by LoadThemeFromMap.
+var ThemeKeys = []string{
+	"directory", "symlink", "socket", "pipe", "device", "executable", "regular",
+	"nlink", "user", "group", "size", "time",
+	"border", "selected_foreground", "selected_background", "foreground", "background",
+}
+
 // LoadThemeFromMap constructs a theme from a simple key/value map. The keys are
 // the same as the ones previously used in the TOML-style configuration:
 //
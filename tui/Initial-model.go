@@ -31,13 +31,20 @@ func InitialModel(startDir string) Model {
 	cfgDir := getConfigDir()
 
 	// Load Lua-based runtime configuration (theme + commands).
+	configStamp := statConfig(cfgDir)
 	runtimeCfg := config.LoadRuntimeConfig(cfgDir)
 
+	// Apply the key bindings of config.lua.
+	keymap, keymapErrs := NewKeymap(runtimeCfg.KeyBindings)
+	runtimeCfg.AddErrors(keymapErrs...)
+
 	// Load the initial directory.
 	files := loadDirectory(currentDir)
 
 	// Create the bubbles list with file items.
-	delegate := NewFileItemDelegate(runtimeCfg.Theme, 0)
+	columns := fileColumns(runtimeCfg.Columns)
+	columnValues := newColumnValues(runtimeCfg, currentDir)
+	delegate := NewFileItemDelegate(runtimeCfg.Theme, columns, files, 0, columnValues)
 	items := FileInfosToItems(files)
 	fileList := list.New(items, delegate, 0, 0)
 
@@ -53,9 +60,23 @@ func InitialModel(startDir string) Model {
 	// Use a simple style for the list.
 	fileList.Styles.NoItems = fileList.Styles.NoItems.Foreground(nil)
 
+	// Pick the image protocol from the terminal type unless the config sets
+	// one; the startup queries may still upgrade an automatic choice.
+	terminalType := detectTerminalType()
+	imageProtocolSetting := parseImageProtocol(runtimeCfg.ImagePreview)
+	imageProtocol := imageProtocolSetting
+	if imageProtocol == ImageProtocolAuto {
+		imageProtocol = detectImageProtocol(terminalType)
+	}
+
 	m := Model{
 		configDir:     cfgDir,
 		runtimeConfig: runtimeCfg,
+		keymap:        keymap,
+		configErrors:  runtimeCfg.Errors,
+		configStamp:   configStamp,
+		columns:       columns,
+		columnValues:  columnValues,
 
 		fileList:           fileList,
 		rightViewport:      rightViewport,
@@ -63,15 +84,22 @@ func InitialModel(startDir string) Model {
 		files:              files,
 		currentDir:         currentDir,
 		theme:              runtimeCfg.Theme,
+		themeName:          runtimeCfg.ThemeName,
 		viewportHeight:     0,
 		viewportWidth:      0,
 		layoutRows:         []string{""},
 		layout:             "",
 		titleText:          "The Cute File Manager",
-		terminalType:       string(detectTerminalType()),
+		terminalType:       string(terminalType),
 		lastPreviewedPath:  "",
 		imagePreviewActive: false,
 		previewEnabled:     false,
+		previewCache:       newPreviewCache(previewCacheSize),
+		previewTab:         PreviewTabContent,
+
+		imageProtocolSetting: imageProtocolSetting,
+		imageProtocol:        imageProtocol,
+		tmux:                 os.Getenv("TMUX") != "",
 	}
 
 	// Initialize the search input
@@ -92,8 +120,8 @@ func InitialModel(startDir string) Model {
 	// does not implicitly force the directories-only view ("ld").
 	ActiveFileListMode = FileListModeList
 
-	// Initialize preview for the initial selection, if any.
-	m.UpdatePreview()
+	// The preview for the initial selection is requested once the first
+	// tea.WindowSizeMsg arrives and the viewport dimensions are known.
 
 	return m
 }
@@ -107,10 +135,12 @@ func loadDirectory(dir string) []filesystem.FileInfo {
 	return files
 }
 
-// UpdateFileListDelegate updates the delegate with a new width.
+// UpdateFileListDelegate updates the delegate with a new width, laying the
+// columns out again for it and the files of the current directory.
 func (m *Model) UpdateFileListDelegate(width int) {
-	delegate := NewFileItemDelegate(m.theme, width)
+	delegate := NewFileItemDelegate(m.theme, m.columns, m.allFiles, width, m.columnValues)
 	m.fileList.SetDelegate(delegate)
+	m.columnValues.columns = customFileColumns(delegate.columns)
 }
 
 func getConfigDir() string {
@@ -0,0 +1,143 @@
+package tui
+
+import (
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/console"
+)
+
+// notificationTimeout is how long a notification stays in the status bar.
+const notificationTimeout = 5 * time.Second
+
+// notificationExpiredMsg clears the notification it was scheduled for, unless
+// a newer one replaced it in the meantime.
+type notificationExpiredMsg struct {
+	seq int
+}
+
+// applyCommandResult applies the state changes of a command, or of a Lua
+// prompt callback, to the model. The returned command renders the preview for
+// the new selection.
+func (m *Model) applyCommandResult(res command.Result, err error) tea.Cmd {
+	// The preview commands produced here are collected separately so command
+	// output can replace them.
+	var previewCmds []tea.Cmd
+
+	if res.Filter != nil {
+		m.searchInput.SetValue(*res.Filter)
+	}
+
+	if res.Cwd != "" && res.Cwd != m.currentDir {
+		previewCmds = append(previewCmds, m.ChangeDirectory(res.Cwd))
+	} else if res.Refresh {
+		// Re-list the current directory when requested by the command.
+		previewCmds = append(previewCmds, m.ChangeDirectory(m.currentDir))
+	} else if res.Filter != nil {
+		previewCmds = append(previewCmds, m.ApplyFilter())
+	}
+
+	// Update view mode and re-apply filters so the file list view
+	// actually changes when commands like "ll", "ls", "ld", "lf",
+	// etc. are executed.
+	if res.ViewMode != "" {
+		ActiveFileListMode = FileListMode(res.ViewMode)
+		previewCmds = append(previewCmds, m.ApplyFilter())
+	}
+
+	if res.OpenHelp {
+		m.activeModal = ModalHelp
+	}
+
+	if res.ConfigErrors {
+		m.openConfigErrors()
+	}
+
+	if res.Theme != "" {
+		previewCmds = append(previewCmds, m.switchTheme(res.Theme))
+	}
+
+	if res.OpenThemePicker {
+		m.openThemePicker()
+	}
+
+	// Command output replaces the preview, so make sure a preview that is
+	// still rendering does not overwrite it.
+	if res.Output != "" {
+		m.cancelPreview()
+		previewCmds = nil
+		m.setPreviewText(res.Output)
+	}
+
+	if err != nil && res.Output == "" {
+		m.cancelPreview()
+		previewCmds = nil
+		m.setPreviewText(err.Error())
+	}
+
+	// Jobs showing their output take the preview over like command output.
+	if slices.ContainsFunc(res.Jobs, func(j *command.Job) bool { return j.Output }) {
+		previewCmds = nil
+	}
+	previewCmds = append(previewCmds, m.startJobs(res.Jobs))
+
+	if res.Prompt != nil {
+		m.openPrompt(res.Prompt)
+	}
+
+	if res.Notification != "" {
+		previewCmds = append(previewCmds, m.notify(res.Notification))
+	}
+
+	previewCmds = append(previewCmds, m.runFileOpHooks(res.FileOps))
+
+	// Reload last: the hooks above still belong to the current config.
+	if res.Reload {
+		previewCmds = append(previewCmds, m.reloadConfig())
+	}
+
+	return tea.Batch(previewCmds...)
+}
+
+// notify shows a message in the status bar for notificationTimeout.
+func (m *Model) notify(message string) tea.Cmd {
+	m.notificationSeq++
+	m.notification = message
+
+	seq := m.notificationSeq
+	return tea.Tick(notificationTimeout, func(time.Time) tea.Msg {
+		return notificationExpiredMsg{seq: seq}
+	})
+}
+
+// handleNotificationExpired clears the notification once it has been shown
+// long enough.
+func (m *Model) handleNotificationExpired(msg notificationExpiredMsg) {
+	if msg.seq == m.notificationSeq {
+		m.notification = ""
+	}
+}
+
+// configErrorsMsg asks for the problems found in config.lua to be shown.
+type configErrorsMsg struct{}
+
+// reportConfigErrors shows the problems found in config.lua once the UI has
+// started, or returns nil when there are none.
+func (m Model) reportConfigErrors() tea.Cmd {
+	if len(m.configErrors) == 0 {
+		return nil
+	}
+	return func() tea.Msg { return configErrorsMsg{} }
+}
+
+// handleConfigErrors logs the problems found in config.lua and lists them in
+// a modal.
+func (m *Model) handleConfigErrors() {
+	for _, err := range m.configErrors {
+		console.Log("config error: %v", err)
+	}
+	m.openConfigErrors()
+}
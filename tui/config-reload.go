@@ -0,0 +1,167 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/config"
+	"cute/console"
+	"cute/theming"
+)
+
+// configWatchInterval is how often config.lua is checked for changes.
+const configWatchInterval = time.Second
+
+// configWatchMsg asks for config.lua to be checked for changes.
+type configWatchMsg struct{}
+
+// configStamp identifies a version of config.lua and the plugins. A
+// different path means another file of the search order took over, e.g. a
+// newly created one. plugins lists the path, modification time and size of
+// each plugin file.
+type configStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+	plugins string
+}
+
+// statConfig returns the stamp of the config file in use for configDir and
+// of its plugins.
+func statConfig(configDir string) configStamp {
+	stamp := configStamp{path: config.ConfigPath(configDir)}
+	if stamp.path != "" {
+		if info, err := os.Stat(stamp.path); err == nil {
+			stamp.modTime = info.ModTime()
+			stamp.size = info.Size()
+		}
+	}
+
+	var plugins strings.Builder
+	for _, path := range config.PluginPaths(configDir) {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&plugins, "%s %d %d\n", path, info.ModTime().UnixNano(), info.Size())
+		}
+	}
+	stamp.plugins = plugins.String()
+	return stamp
+}
+
+// watchConfig schedules the next check of config.lua.
+func watchConfig() tea.Cmd {
+	return tea.Tick(configWatchInterval, func(time.Time) tea.Msg {
+		return configWatchMsg{}
+	})
+}
+
+// handleConfigWatch reloads config.lua when it, or a plugin, changed since
+// it was last loaded.
+func (m *Model) handleConfigWatch() tea.Cmd {
+	// A pending prompt or a running command belongs to the current Lua
+	// state, so wait until it is done.
+	if ActiveTuiMode == TuiModePrompt || m.luaRunning() {
+		return watchConfig()
+	}
+	// Neither while a theme is being picked, which would replace the theme
+	// the picker shows.
+	if ActiveTuiMode == TuiModeThemes {
+		return watchConfig()
+	}
+
+	if statConfig(m.configDir) == m.configStamp {
+		return watchConfig()
+	}
+	return tea.Batch(m.reloadConfig(), watchConfig())
+}
+
+// reloadConfig loads config.lua and the plugins again and swaps them in:
+// the theme, key bindings, commands and hooks all come from the new files.
+// When config.lua fails to load, the configuration in use is kept and the
+// error is shown.
+func (m *Model) reloadConfig() tea.Cmd {
+	// Stat before loading, so a change made while loading is seen by the
+	// next check.
+	m.configStamp = statConfig(m.configDir)
+
+	rc, err := config.ReloadRuntimeConfig(m.configDir)
+	if err != nil {
+		console.Log("config error: %v", err)
+		m.configErrors = []error{err}
+		return m.notify("config.lua not reloaded: " + err.Error())
+	}
+
+	keymap, keymapErrs := NewKeymap(rc.KeyBindings)
+	rc.AddErrors(keymapErrs...)
+
+	// Commands, hooks and key bindings run on the Update goroutine, so
+	// nothing uses the old Lua state anymore once it is replaced here.
+	old := m.runtimeConfig
+	m.runtimeConfig = rc
+	m.keymap = keymap
+	m.pendingKeys = ""
+	m.columns = fileColumns(rc.Columns)
+	m.columnValues = newColumnValues(rc, m.currentDir)
+	m.configErrors = rc.Errors
+	old.Close()
+
+	m.setImageProtocolSetting(parseImageProtocol(rc.ImagePreview))
+	m.themeName = rc.ThemeName
+	cmd := m.applyTheme(rc.Theme)
+
+	if n := len(m.configErrors); n > 0 {
+		for _, err := range m.configErrors {
+			console.Log("config error: %v", err)
+		}
+		return tea.Batch(cmd, m.notify(fmt.Sprintf("Reloaded %s with %d problem(s); see :config-errors", m.configName(rc), n)))
+	}
+	if m.configName(rc) == "" {
+		return tea.Batch(cmd, m.notify("No config.lua found; using the defaults"))
+	}
+	return tea.Batch(cmd, m.notify("Reloaded "+m.configName(rc)))
+}
+
+// configName names what a reload loaded in notifications: config.lua, or
+// the plugins when there is no config.lua, or nothing when there is neither.
+func (m *Model) configName(rc *config.RuntimeConfig) string {
+	if rc.Path == "" && len(config.PluginPaths(m.configDir)) > 0 {
+		return "the plugins"
+	}
+	return rc.Path
+}
+
+// applyTheme switches the UI to another theme, restyling the inputs and the
+// file list and rendering the preview again.
+func (m *Model) applyTheme(theme theming.Theme) tea.Cmd {
+	m.theme = theme
+
+	// The inputs keep their value and focus; only their styles change.
+	m.searchInput.SetStyles(m.SearchInput("", "").Styles())
+	m.commandInput.SetStyles(m.CommandInput("", "").Styles())
+
+	// Rendered previews use the old colors.
+	m.cancelPreview()
+	m.previewCache = newPreviewCache(previewCacheSize)
+	m.previewShownKey = previewKey{}
+
+	// CalcLayout rebuilds the file list delegate, with the columns too.
+	m.CalcLayout()
+	return m.UpdatePreview()
+}
+
+// setImageProtocolSetting applies a new image_preview setting. An automatic
+// setting keeps the protocol detected so far.
+func (m *Model) setImageProtocolSetting(setting ImageProtocol) {
+	if setting == m.imageProtocolSetting {
+		return
+	}
+	m.imageProtocolSetting = setting
+	if setting == ImageProtocolAuto {
+		m.imageProtocol = detectImageProtocol(TerminalType(m.terminalType))
+		return
+	}
+	m.imageProtocol = setting
+}
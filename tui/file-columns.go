@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+	lua "github.com/yuin/gopher-lua"
+
+	"cute/command"
+	"cute/config"
+	"cute/console"
+	"cute/filesystem"
+)
+
+const (
+	// maxFitWidth caps the width of built-in columns sized to their values,
+	// so one long user name does not take the space of the file names.
+	maxFitWidth = 16
+	// customColumnWidth is the width of custom columns that set none; their
+	// values are only computed for the rows on screen, so they cannot be
+	// fitted.
+	customColumnWidth = 10
+	// minNameWidth is the width below which the name column makes other
+	// columns hide, unless config.lua sets another.
+	minNameWidth = 20
+	// customColumnPriority places custom columns among the built-in ones in
+	// the order columns are hidden in a narrow file list.
+	customColumnPriority = 2
+	// maxColumnValues bounds the cache of custom column values.
+	maxColumnValues = 10000
+	// columnFillBudget is how long custom column values are computed for
+	// before the file list is drawn again, so a slow column does not freeze
+	// it. A value taking longer than that on its own is left empty.
+	columnFillBudget = 100 * time.Millisecond
+	// columnValueMinBudget is the least time left in a round for another
+	// value to be started in it.
+	columnValueMinBudget = columnFillBudget / 4
+)
+
+// builtinColumn describes how a built-in column shows a file.
+type builtinColumn struct {
+	value func(fi filesystem.FileInfo) string
+	// field is the theme's FieldColors entry the column is styled with.
+	field string
+	// priority orders the columns hidden when the file list is narrow:
+	// lowest first.
+	priority int
+}
+
+var builtinColumns = map[string]builtinColumn{
+	"permissions": {value: func(fi filesystem.FileInfo) string { return fi.Permissions }, priority: 3},
+	"size":        {value: func(fi filesystem.FileInfo) string { return fi.Size }, field: "size", priority: 5},
+	"user":        {value: func(fi filesystem.FileInfo) string { return fi.User }, field: "user", priority: 1},
+	"group":       {value: func(fi filesystem.FileInfo) string { return fi.Group }, field: "group", priority: 0},
+	"modified":    {value: func(fi filesystem.FileInfo) string { return fi.DateModified }, field: "time", priority: 4},
+	"name":        {value: func(fi filesystem.FileInfo) string { return fi.Name }, priority: 6},
+}
+
+// fileColumn is a column of the file list. For the name column, which takes
+// the space the others leave, width is the least it is given.
+type fileColumn struct {
+	name  string
+	width int
+	// fit sizes the column to the widest of its values.
+	fit   bool
+	right bool
+	// fn computes the values of a custom column.
+	fn *lua.LFunction
+}
+
+func (c fileColumn) priority() int {
+	if c.fn != nil {
+		return customColumnPriority
+	}
+	return builtinColumns[c.name].priority
+}
+
+// fileColumns returns the columns config.lua asks for, or the built-in ones
+// in their default order when it sets none.
+func fileColumns(cfg []config.Column) []fileColumn {
+	if cfg == nil {
+		for _, name := range config.ColumnNames {
+			cfg = append(cfg, config.Column{Name: name})
+		}
+	}
+
+	columns := make([]fileColumn, 0, len(cfg))
+	for _, c := range cfg {
+		col := fileColumn{
+			name:  c.Name,
+			width: c.Width,
+			right: c.Align == "right",
+			fn:    c.Fn,
+		}
+		switch {
+		case col.width > 0:
+		case c.Name == "name":
+			col.width = minNameWidth
+		case c.Fn != nil:
+			col.width = customColumnWidth
+		default:
+			col.fit = true
+		}
+		columns = append(columns, col)
+	}
+	return columns
+}
+
+// builtinFileColumns returns the columns without the custom ones, for
+// listings rendered where Lua cannot be called.
+func builtinFileColumns(columns []fileColumn) []fileColumn {
+	return slices.DeleteFunc(slices.Clone(columns), func(c fileColumn) bool { return c.fn != nil })
+}
+
+// customFileColumns returns only the custom columns.
+func customFileColumns(columns []fileColumn) []fileColumn {
+	return slices.DeleteFunc(slices.Clone(columns), func(c fileColumn) bool { return c.fn == nil })
+}
+
+// layoutColumns sizes the columns to the values of files and, when the list
+// is width cells wide, hides the columns of lowest priority until the name
+// column gets its minimum width. A width of zero keeps every column.
+func layoutColumns(columns []fileColumn, files []filesystem.FileInfo, width int) []fileColumn {
+	out := slices.Clone(columns)
+	for i, c := range out {
+		if !c.fit {
+			continue
+		}
+		w := 0
+		for _, fi := range files {
+			w = max(w, lipgloss.Width(builtinColumns[c.name].value(fi)))
+		}
+		out[i].width = min(w, maxFitWidth)
+	}
+	if width <= 0 {
+		return out
+	}
+
+	for len(out) > 1 {
+		// Every column but the name column plus one separator each, and the
+		// name column's minimum.
+		needed := 0
+		for _, c := range out {
+			needed += c.width + 1
+		}
+		if needed-1 <= width {
+			break
+		}
+
+		drop := -1
+		for i, c := range out {
+			if c.name == "name" {
+				continue
+			}
+			if drop < 0 || c.priority() <= out[drop].priority() {
+				drop = i
+			}
+		}
+		if drop < 0 {
+			break
+		}
+		out = slices.Delete(out, drop, drop+1)
+	}
+	return out
+}
+
+// columnValues computes the values of custom columns with their Lua
+// functions and keeps them, so each is only computed once for the rows
+// scrolled onto the screen. It is shared by the copies of the delegate,
+// which only draw the values computed so far; see fillColumnValues.
+type columnValues struct {
+	config *config.RuntimeConfig
+	dir    string
+	cache  map[columnValueKey]string
+	// columns are the custom columns shown, as laid out by the delegate.
+	columns []fileColumn
+}
+
+// columnValuesMsg asks for more custom column values to be computed.
+type columnValuesMsg struct{}
+
+// columnValueKey identifies the value of a column for a version of a file.
+type columnValueKey struct {
+	column, path, modified, size string
+}
+
+func newColumnValues(rc *config.RuntimeConfig, dir string) *columnValues {
+	return &columnValues{config: rc, dir: dir, cache: map[columnValueKey]string{}}
+}
+
+// get returns the value of the custom column col for fi, and whether it has
+// been computed.
+func (v *columnValues) get(col fileColumn, fi filesystem.FileInfo) (string, bool) {
+	s, ok := v.cache[columnValueKey{col.name, fi.Path, fi.DateModified, fi.Size}]
+	return s, ok
+}
+
+// compute calls the function of the custom column col for fi, stopping it
+// at deadline, and reports whether the value is known. Errors are logged
+// and leave the cell empty, except running out of time when the whole
+// budget was not given (full is unset): the value is then left for the next
+// round.
+func (v *columnValues) compute(col fileColumn, fi filesystem.FileInfo, deadline time.Time, full bool) bool {
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	s, err := command.RunColumn(command.Environment{Cwd: v.dir, Config: v.config, Context: ctx}, col.fn, fi)
+	if !full && errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if err != nil {
+		console.Log("lua column %s error for %s: %v", col.name, fi.Path, err)
+	}
+	if len(v.cache) >= maxColumnValues {
+		clear(v.cache)
+	}
+	v.cache[columnValueKey{col.name, fi.Path, fi.DateModified, fi.Size}] = s
+	return true
+}
+
+// fillColumnValues computes the custom column values of the rows on screen
+// that are not known yet, for up to columnFillBudget. It runs after every
+// update rather than while the file list is drawn; when time runs out, the
+// cells left are drawn empty and the returned command asks for another
+// round, which starts with the value that ran out of time. Nothing is
+// computed while a Lua command runs in the background.
+func (m *Model) fillColumnValues() tea.Cmd {
+	v := m.columnValues
+	if v == nil || len(v.columns) == 0 || m.luaRunning() {
+		return nil
+	}
+
+	items := m.fileList.VisibleItems()
+	start, end := m.fileList.Paginator.GetSliceBounds(len(items))
+	deadline := time.Now().Add(columnFillBudget)
+	more := func() tea.Msg { return columnValuesMsg{} }
+	first := true
+	for _, item := range items[start:end] {
+		fi, ok := item.(FileItem)
+		if !ok {
+			continue
+		}
+		for _, col := range v.columns {
+			if _, ok := v.get(col, fi.Info); ok {
+				continue
+			}
+			if !first && time.Until(deadline) < columnValueMinBudget {
+				return more
+			}
+			if !v.compute(col, fi.Info, deadline, first) {
+				return more
+			}
+			first = false
+		}
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func TestFillColumnValuesSlowColumn(t *testing.T) {
+	configHome := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(configHome, "cute"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// About 30 ms per value, so a round of columnFillBudget runs out in the
+	// middle of a value.
+	config := `columns = {
+  { name = "slow", fn = function(file)
+      local t = os.clock()
+      while os.clock() - t < 0.03 do end
+      return "ok"
+  end },
+  "name",
+}
+`
+	if err := os.WriteFile(filepath.Join(configHome, "cute", "config.lua"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_CONFIG_HOME", configHome)
+
+	dir := t.TempDir()
+	for i := range 20 {
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("file%02d", i)), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var model tea.Model = InitialModel(dir)
+	model, cmd := model.Update(tea.WindowSizeMsg{Width: 120, Height: 40})
+	for rounds := 0; cmd != nil; rounds++ {
+		if rounds > 50 {
+			t.Fatal("custom column values still missing after 50 rounds")
+		}
+		model, cmd = model.Update(columnValuesMsg{})
+	}
+
+	m := model.(Model)
+	for _, fi := range m.files {
+		if s, ok := m.columnValues.get(m.columnValues.columns[0], fi); !ok || s != "ok" {
+			t.Errorf("%s: value %q, computed %t; want \"ok\"", fi.Name, s, ok)
+		}
+	}
+}
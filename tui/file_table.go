@@ -7,6 +7,7 @@ import (
 	"charm.land/bubbles/v2/list"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
 
 	"cute/filesystem"
 	"cute/theming"
@@ -26,13 +27,21 @@ func (i FileItem) FilterValue() string {
 type FileItemDelegate struct {
 	theme      theming.Theme
 	totalWidth int
+	// columns are the columns shown, laid out for totalWidth.
+	columns []fileColumn
+	// values holds the values of custom columns; without it they are left
+	// empty.
+	values *columnValues
 }
 
-// NewFileItemDelegate creates a new delegate for rendering file items.
-func NewFileItemDelegate(theme theming.Theme, width int) FileItemDelegate {
+// NewFileItemDelegate creates a new delegate for rendering file items with
+// the given columns, sized to the values of files and to width.
+func NewFileItemDelegate(theme theming.Theme, columns []fileColumn, files []filesystem.FileInfo, width int, values *columnValues) FileItemDelegate {
 	return FileItemDelegate{
 		theme:      theme,
 		totalWidth: width,
+		columns:    layoutColumns(columns, files, width),
+		values:     values,
 	}
 }
 
@@ -65,99 +74,96 @@ func (d FileItemDelegate) Render(w io.Writer, m list.Model, index int, item list
 
 // renderFileRow renders a single file row with all columns styled.
 func (d FileItemDelegate) renderFileRow(fi filesystem.FileInfo, isSelected bool) string {
-	const (
-		colPerms = 11
-		colSize  = 6
-		colUser  = 8
-		colGroup = 8
-		colDate  = 14
-	)
-
 	theme := d.theme
 
-	size := fi.Size
-	user := fi.User
-	group := fi.Group
-	date := fi.DateModified
-	name := fi.Name
-
-	// Field colors.
-	userStyle := theming.StyleFromSpec(theme.FieldColors["user"])
-	groupStyle := theming.StyleFromSpec(theme.FieldColors["group"])
-	sizeStyle := theming.StyleFromSpec(theme.FieldColors["size"])
-	timeStyle := theming.StyleFromSpec(theme.FieldColors["time"])
-
 	// Background color for the row.
-	bgColor := ""
+	bgColor := theme.FileList.Background
 	if isSelected && theme.Selection.Background != "" {
 		bgColor = theme.Selection.Background
-		bg := lipgloss.Color(bgColor)
-		userStyle = userStyle.Background(bg)
-		groupStyle = groupStyle.Background(bg)
-		sizeStyle = sizeStyle.Background(bg)
-		timeStyle = timeStyle.Background(bg)
-	} else {
-		bgColor = theme.FileList.Background
-		bg := lipgloss.Color(bgColor)
-		userStyle = userStyle.Background(bg)
-		groupStyle = groupStyle.Background(bg)
-		sizeStyle = sizeStyle.Background(bg)
-		timeStyle = timeStyle.Background(bg)
-	}
-
-	// Render permission string with per-character coloring.
-	permTextRaw := renderPermissions(theme, fi, bgColor)
-	permText := padCellWithBG(permTextRaw, colPerms, bgColor)
-
-	userText := padCellWithBG(userStyle.Render(user), colUser, bgColor)
-	groupText := padCellWithBG(groupStyle.Render(group), colGroup, bgColor)
-	sizeText := padCellWithBG(sizeStyle.Render(size), colSize, bgColor)
-	timeText := padCellWithBG(timeStyle.Render(date), colDate, bgColor)
-
-	// File name color based on file type.
-	nameColorSpec := theme.FileTypeColors[fi.Type]
-	nameStyle := theming.StyleFromSpec(nameColorSpec)
+	}
+	bgStyle := lipgloss.NewStyle()
 	if bgColor != "" {
-		nameStyle = nameStyle.Background(lipgloss.Color(bgColor))
+		bgStyle = bgStyle.Background(lipgloss.Color(bgColor))
+	}
+
+	// The name column takes the width the other columns leave.
+	nameWidth := 0
+	if d.totalWidth > 0 {
+		nameWidth = d.totalWidth - (len(d.columns) - 1)
+		for _, col := range d.columns {
+			if col.name != "name" {
+				nameWidth -= col.width
+			}
+		}
+		nameWidth = max(nameWidth, 1)
 	}
-	nameText := nameStyle.Render(name)
 
-	lineCols := []string{
-		permText,
-		sizeText,
-		userText,
-		groupText,
-		timeText,
-		nameText,
+	lineCols := make([]string, 0, len(d.columns))
+	for _, col := range d.columns {
+		width := col.width
+		if col.name == "name" {
+			width = nameWidth
+		}
+
+		var text string
+		switch {
+		case col.fn != nil:
+			if d.values != nil {
+				text, _ = d.values.get(col, fi)
+			}
+			text = bgStyle.Render(truncateCell(text, width))
+		case col.name == "permissions":
+			// Render permission string with per-character coloring.
+			text = truncateCell(renderPermissions(theme, fi, bgColor), width)
+		case col.name == "name":
+			// File name color based on file type.
+			nameStyle := theming.StyleFromSpec(theme.FileTypeColors[fi.Type])
+			if bgColor != "" {
+				nameStyle = nameStyle.Background(lipgloss.Color(bgColor))
+			}
+			text = nameStyle.Render(truncateCell(fi.Name, width))
+		default:
+			// Field colors.
+			builtin := builtinColumns[col.name]
+			style := theming.StyleFromSpec(theme.FieldColors[builtin.field])
+			if bgColor != "" {
+				style = style.Background(lipgloss.Color(bgColor))
+			}
+			text = style.Render(truncateCell(builtin.value(fi), width))
+		}
+
+		if col.right {
+			text = padCellLeftWithBG(text, width, bgColor)
+		} else {
+			text = padCellWithBG(text, width, bgColor)
+		}
+		lineCols = append(lineCols, text)
 	}
 
 	sep := " "
 	if bgColor != "" {
-		sep = lipgloss.NewStyle().Background(lipgloss.Color(bgColor)).Render(" ")
+		sep = bgStyle.Render(" ")
 	}
 
 	line := strings.Join(lineCols, sep)
 
 	// Pad the end of the line so that the row's background extends to the edge.
 	if d.totalWidth > 0 && bgColor != "" {
-		lineWidth := lipgloss.Width(line)
-		if lineWidth < d.totalWidth {
-			missing := d.totalWidth - lineWidth
-			bg := lipgloss.Color(bgColor)
-			spaceStyle := lipgloss.NewStyle().Background(bg)
-			pad := spaceStyle.Render(" ")
-
-			var tail strings.Builder
-			for i := 0; i < missing; i++ {
-				tail.WriteString(pad)
-			}
-			line += tail.String()
-		}
+		line = padCellWithBG(line, d.totalWidth, bgColor)
 	}
 
 	return line
 }
 
+// truncateCell shortens s to at most w cells, marking the cut with an
+// ellipsis. A width of zero leaves s alone.
+func truncateCell(s string, w int) string {
+	if w <= 0 || lipgloss.Width(s) <= w {
+		return s
+	}
+	return ansi.Truncate(s, w, "…")
+}
+
 // renderPermissions renders the permission string with per-character coloring.
 func renderPermissions(theme theming.Theme, fi filesystem.FileInfo, bgColor string) string {
 	perm := fi.Permissions
@@ -245,6 +251,20 @@ func padCellWithBG(content string, w int, bgColor string) string {
 	return b.String()
 }
 
+// padCellLeftWithBG left-pads content like padCellWithBG, aligning it to
+// the right of the cell.
+func padCellLeftWithBG(content string, w int, bgColor string) string {
+	width := lipgloss.Width(content)
+	if width >= w {
+		return content
+	}
+	pad := strings.Repeat(" ", w-width)
+	if bgColor != "" {
+		pad = lipgloss.NewStyle().Background(lipgloss.Color(bgColor)).Render(pad)
+	}
+	return pad + content
+}
+
 // FileInfosToItems converts a slice of FileInfo to a slice of list.Item.
 func FileInfosToItems(files []filesystem.FileInfo) []list.Item {
 	items := make([]list.Item, len(files))
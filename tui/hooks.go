@@ -0,0 +1,104 @@
+package tui
+
+import (
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/config"
+	"cute/console"
+)
+
+// hookResultMsg carries the changes made by Lua hooks. They are applied on
+// the next update rather than right away, so a hook that changes directory
+// does not run the on_cd hook from within the hook that triggered it.
+type hookResultMsg struct {
+	res command.Result
+	err error
+}
+
+// runHook calls the Lua functions registered for the hook. It returns nil
+// when there are none, or while a Lua command runs in the background.
+func (m *Model) runHook(name string) tea.Cmd {
+	env, cancel := m.boundedEnvironment(luaCallTimeout)
+	defer cancel()
+	if !command.HasHook(env, name) || m.luaRunning() {
+		return nil
+	}
+
+	res, err := command.RunHook(env, name)
+	return func() tea.Msg {
+		return hookResultMsg{res: res, err: err}
+	}
+}
+
+// runFileOpHooks tells the on_file_op hook about the file operations a
+// command performed.
+func (m *Model) runFileOpHooks(ops []command.FileOp) tea.Cmd {
+	env, cancel := m.boundedEnvironment(luaCallTimeout)
+	defer cancel()
+	if len(ops) == 0 || !command.HasHook(env, config.HookFileOp) || m.luaRunning() {
+		return nil
+	}
+
+	var cmds []tea.Cmd
+	for _, op := range ops {
+		res, err := command.RunFileOpHook(env, op)
+		cmds = append(cmds, func() tea.Msg {
+			return hookResultMsg{res: res, err: err}
+		})
+	}
+	return tea.Sequence(cmds...)
+}
+
+// runSelectionHooks runs the on_select hook when the cursor has moved to
+// another entry since it last ran.
+func (m *Model) runSelectionHooks() tea.Cmd {
+	path, _ := m.selectedFilePath()
+	if path == m.hookSelectedPath {
+		return nil
+	}
+	m.hookSelectedPath = path
+	if path == "" {
+		return nil
+	}
+	return m.runHook(config.HookSelect)
+}
+
+// runPreviewHooks runs the on_preview hook when the preview pane starts
+// showing another file.
+func (m *Model) runPreviewHooks(path string) tea.Cmd {
+	if path == m.hookPreviewedPath {
+		return nil
+	}
+	m.hookPreviewedPath = path
+	return m.runHook(config.HookPreview)
+}
+
+// handleHookResult applies the changes made by hooks. Hook errors are shown
+// in the status bar so they never interrupt what the user is doing.
+func (m *Model) handleHookResult(msg hookResultMsg) tea.Cmd {
+	cmd := m.applyCommandResult(msg.res, nil)
+	if msg.err != nil {
+		console.Log("hook error: %v", msg.err)
+		message := strings.ReplaceAll(msg.err.Error(), "\n", "; ")
+		cmd = tea.Batch(cmd, m.notify("Lua hook failed: "+message))
+	}
+	if msg.res.Quit {
+		return tea.Batch(cmd, m.quit())
+	}
+	return cmd
+}
+
+// quit runs the on_quit hook, stops the jobs still running and exits. The
+// hook's result cannot be shown anymore, so its errors are only logged.
+func (m *Model) quit() tea.Cmd {
+	env, cancel := m.boundedEnvironment(luaCallTimeout)
+	defer cancel()
+	if _, err := command.RunHook(env, config.HookQuit); err != nil {
+		console.Log("hook error: %v", err)
+	}
+	command.StopJobs()
+	return tea.Quit
+}
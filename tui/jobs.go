@@ -0,0 +1,133 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/console"
+)
+
+// maxJobOutputLines bounds the output of a job kept in the preview pane; the
+// oldest lines are dropped past it.
+const maxJobOutputLines = 5000
+
+// jobEventMsg carries a line of output of a job, or its exit.
+type jobEventMsg struct {
+	ev command.JobEvent
+}
+
+// startJobs starts the jobs spawned by a command. A job showing its output
+// takes the preview pane over, until something else is previewed.
+func (m *Model) startJobs(jobs []*command.Job) tea.Cmd {
+	var cmds []tea.Cmd
+	for _, job := range jobs {
+		if err := job.Start(); err != nil {
+			console.Log("job %d error: %v", job.ID, err)
+			cmds = append(cmds, m.notify(fmt.Sprintf("Could not start %s: %v", job.Label, err)))
+			continue
+		}
+		if job.Output {
+			m.cancelPreview()
+			m.setPreviewText("$ " + job.Label)
+			m.outputJob = job.ID
+		}
+		cmds = append(cmds, waitJob(job))
+	}
+	return tea.Batch(cmds...)
+}
+
+// waitJob waits for the next event of a job.
+func waitJob(job *command.Job) tea.Cmd {
+	return func() tea.Msg {
+		ev, ok := job.Next()
+		if !ok {
+			return nil
+		}
+		return jobEventMsg{ev: ev}
+	}
+}
+
+// handleJobEvent shows a line of output of a job in the preview pane, while
+// it shows the job, and calls the job's Lua callback for it. Callbacks wait
+// for a Lua command running in the background.
+func (m *Model) handleJobEvent(msg jobEventMsg) tea.Cmd {
+	ev := msg.ev
+	var next tea.Cmd
+	if !ev.Exited() {
+		next = waitJob(ev.Job)
+	}
+
+	if m.outputJob == ev.Job.ID {
+		m.appendJobOutput(ev)
+	}
+
+	if m.luaRunning() {
+		if ev.HasCallback(m.runtimeConfig) || ev.Exited() {
+			m.luaRun.jobEvents = append(m.luaRun.jobEvents, ev)
+		}
+		return next
+	}
+	return tea.Batch(m.runJobCallback(ev), next)
+}
+
+// appendJobOutput adds a line of output of the job shown in the preview
+// pane, or says how it exited.
+func (m *Model) appendJobOutput(ev command.JobEvent) {
+	line := ev.Line
+	if ev.Exited() {
+		line = "[" + jobExitStatus(ev) + "]"
+	}
+	m.appendPreviewContent(previewContent{lines: []string{line}})
+
+	if n := len(m.preview.lines) - maxJobOutputLines; n > 0 {
+		yOffset := m.rightViewport.YOffset()
+		m.preview.lines = slices.Delete(m.preview.lines, 0, n)
+		m.previewMatches = findPreviewMatches(m.preview.lines, m.previewSearch)
+		m.previewMatchIdx = 0
+		m.refreshPreviewViewport()
+		m.rightViewport.SetYOffset(max(yOffset-n, 0))
+	}
+}
+
+// runJobCallback calls the Lua callback of a job for the event and applies
+// its result. A job without an on_exit callback, or whose callbacks were
+// dropped when config.lua was reloaded, says how it exited in the status bar
+// instead.
+func (m *Model) runJobCallback(ev command.JobEvent) tea.Cmd {
+	if !ev.HasCallback(m.runtimeConfig) {
+		if ev.Exited() {
+			return m.notify(ev.Job.Label + ": " + jobExitStatus(ev))
+		}
+		return nil
+	}
+
+	env, cancel := m.boundedEnvironment(luaCallTimeout)
+	defer cancel()
+	res, err := command.RunJobCallback(env, ev)
+
+	cmd := m.applyCommandResult(res, nil)
+	if err != nil {
+		console.Log("job %d callback error: %v", ev.Job.ID, err)
+		message := strings.ReplaceAll(err.Error(), "\n", "; ")
+		cmd = tea.Batch(cmd, m.notify("Lua job callback failed: "+message))
+	}
+	if res.Quit {
+		return tea.Batch(cmd, m.quit())
+	}
+	return cmd
+}
+
+// jobExitStatus describes how a job exited.
+func jobExitStatus(ev command.JobEvent) string {
+	switch {
+	case ev.Err != nil:
+		return "failed: " + ev.Err.Error()
+	case ev.Code != 0:
+		return fmt.Sprintf("exited with code %d", ev.Code)
+	}
+	return "done"
+}
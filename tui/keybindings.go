@@ -17,25 +17,39 @@ type Keybindings struct {
 	Enter        Keybinding
 	Files        Keybinding
 	Filter       Keybinding
+	FocusPreview Keybinding
 	GoToStart    Keybinding
 	GoToEnd      Keybinding
 	Help         Keybinding
 	HiddenFiles  Keybinding
+	Left         Keybinding
 	List         Keybinding
 	Mkdir        Keybinding
 	Move         Keybinding
+	NextMatch    Keybinding
+	NextTab      Keybinding
+	PageDown     Keybinding
+	PageUp       Keybinding
 	Paste        Keybinding
+	PrevMatch    Keybinding
+	PrevTab      Keybinding
 	Preview      Keybinding
 	Quit         Keybinding
 	Redo         Keybinding
 	Rename       Keybinding
+	Right        Keybinding
+	ScrollDown   Keybinding
+	ScrollUp     Keybinding
+	Search       Keybinding
 	Select       Keybinding
 	AutoComplete Keybinding
 	Undo         Keybinding
 	Up           Keybinding
 }
 
-func GetKeyBindings() Keybindings {
+// DefaultKeyBindings returns the built-in key bindings, before the keys table
+// of config.lua is applied. Modes get theirs from the model's Keymap.
+func DefaultKeyBindings() Keybindings {
 	bindings := Keybindings{
 		AddFile: Keybinding{
 			On:          []string{"n"},
@@ -81,6 +95,10 @@ func GetKeyBindings() Keybindings {
 			On:          []string{"f"},
 			Description: "Filter directory content.",
 		},
+		FocusPreview: Keybinding{
+			On:          []string{"tab"},
+			Description: "Switch focus between file list and preview.",
+		},
 
 		GoToStart: Keybinding{
 			On:          []string{"g"},
@@ -100,6 +118,10 @@ func GetKeyBindings() Keybindings {
 			On:          []string{"h"},
 			Description: "Toggle hidden files.",
 		},
+		Left: Keybinding{
+			On:          []string{"left", "h"},
+			Description: "Move left.",
+		},
 		List: Keybinding{
 			On:          []string{"ctrl+l"},
 			Description: "List directory contents.",
@@ -112,10 +134,34 @@ func GetKeyBindings() Keybindings {
 			On:          []string{"k"},
 			Description: "Create a new directory.",
 		},
+		NextMatch: Keybinding{
+			On:          []string{"n"},
+			Description: "Jump to next search match in preview.",
+		},
+		NextTab: Keybinding{
+			On:          []string{"]"},
+			Description: "Next preview tab.",
+		},
+		PageDown: Keybinding{
+			On:          []string{"pgdown", "space"},
+			Description: "Scroll preview down one page.",
+		},
+		PageUp: Keybinding{
+			On:          []string{"pgup", "b"},
+			Description: "Scroll preview up one page.",
+		},
 		Paste: Keybinding{
 			On:          []string{"v"},
 			Description: "Paste file or directory.",
 		},
+		PrevMatch: Keybinding{
+			On:          []string{"N"},
+			Description: "Jump to previous search match in preview.",
+		},
+		PrevTab: Keybinding{
+			On:          []string{"["},
+			Description: "Previous preview tab.",
+		},
 		Preview: Keybinding{
 			On:          []string{"w"},
 			Description: "Preview file or folder.",
@@ -132,6 +178,22 @@ func GetKeyBindings() Keybindings {
 			On:          []string{"ctrl+z"},
 			Description: "Redo.",
 		},
+		Right: Keybinding{
+			On:          []string{"right", "l"},
+			Description: "Move right.",
+		},
+		ScrollDown: Keybinding{
+			On:          []string{"j", "down"},
+			Description: "Scroll preview down.",
+		},
+		ScrollUp: Keybinding{
+			On:          []string{"k", "up"},
+			Description: "Scroll preview up.",
+		},
+		Search: Keybinding{
+			On:          []string{"/"},
+			Description: "Search preview content.",
+		},
 		Select: Keybinding{
 			On:          []string{"s"},
 			Description: "Select files or directories.",
@@ -153,6 +215,51 @@ func GetKeyBindings() Keybindings {
 	return bindings
 }
 
+// actions maps the action names used in config.lua to the bindings.
+func (k *Keybindings) actions() map[string]*Keybinding {
+	return map[string]*Keybinding{
+		"add_file":      &k.AddFile,
+		"cancel":        &k.Cancel,
+		"cd":            &k.Cd,
+		"parent":        &k.Parent,
+		"command":       &k.Command,
+		"copy":          &k.Copy,
+		"directories":   &k.Directories,
+		"down":          &k.Down,
+		"enter":         &k.Enter,
+		"files":         &k.Files,
+		"filter":        &k.Filter,
+		"focus_preview": &k.FocusPreview,
+		"go_to_start":   &k.GoToStart,
+		"go_to_end":     &k.GoToEnd,
+		"help":          &k.Help,
+		"hidden_files":  &k.HiddenFiles,
+		"left":          &k.Left,
+		"list":          &k.List,
+		"mkdir":         &k.Mkdir,
+		"move":          &k.Move,
+		"next_match":    &k.NextMatch,
+		"next_tab":      &k.NextTab,
+		"page_down":     &k.PageDown,
+		"page_up":       &k.PageUp,
+		"paste":         &k.Paste,
+		"prev_match":    &k.PrevMatch,
+		"prev_tab":      &k.PrevTab,
+		"preview":       &k.Preview,
+		"quit":          &k.Quit,
+		"redo":          &k.Redo,
+		"rename":        &k.Rename,
+		"right":         &k.Right,
+		"scroll_down":   &k.ScrollDown,
+		"scroll_up":     &k.ScrollUp,
+		"search":        &k.Search,
+		"select":        &k.Select,
+		"auto_complete": &k.AutoComplete,
+		"undo":          &k.Undo,
+		"up":            &k.Up,
+	}
+}
+
 func (k Keybinding) Matches(key string) bool {
 	for _, v := range k.On {
 		if v == key {
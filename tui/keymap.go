@@ -0,0 +1,378 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/config"
+)
+
+// modeActions lists the actions each mode responds to, which are the ones
+// the keys table of config.lua may rebind in that mode.
+var modeActions = map[TUIMode][]string{
+	TuiModeNormal: {
+		"add_file", "command", "directories", "down", "enter", "files",
+		"filter", "focus_preview", "go_to_end", "go_to_start", "help", "list",
+		"next_tab", "parent", "prev_tab", "preview", "quit", "select", "up",
+	},
+	TuiModePreview: {
+		"cancel", "enter", "focus_preview", "go_to_end", "go_to_start", "left",
+		"next_match", "next_tab", "page_down", "page_up", "prev_match",
+		"prev_tab", "quit", "right", "scroll_down", "scroll_up", "search",
+	},
+	TuiModeHelp:          {"cancel", "help", "quit"},
+	TuiModeQuit:          {"cancel", "quit"},
+	TuiModeSelect:        {"cancel", "quit", "select"},
+	TuiModeCommand:       {"auto_complete", "cancel", "command", "down", "enter", "quit", "up"},
+	TuiModeFilter:        {"cancel", "quit"},
+	TuiModeAddFile:       {"cancel", "enter", "quit"},
+	TuiModeMkdir:         {"cancel", "enter", "quit"},
+	TuiModeOwner:         {"cancel", "enter"},
+	TuiModePreviewSearch: {"cancel", "enter"},
+	TuiModePrompt:        {"cancel", "enter"},
+	TuiModeConfigErrors:  {"cancel", "enter", "quit"},
+	TuiModeRunning:       {"cancel"},
+	TuiModeThemes:        {"cancel", "down", "enter", "quit", "up"},
+}
+
+// textInputModes are the modes in which keys are typed into an input, so
+// they cannot wait for a key sequence or take over printable keys.
+var textInputModes = []TUIMode{
+	TuiModeCommand, TuiModeFilter, TuiModeAddFile, TuiModeMkdir,
+	TuiModeOwner, TuiModePreviewSearch, TuiModePrompt,
+}
+
+// namedKeys are the multi-letter key names that stand for a single key in a
+// key sequence. Other words are read one key per letter, so "gg" is g
+// followed by g.
+var namedKeys = []string{
+	"enter", "esc", "tab", "space", "backspace", "up", "down", "left",
+	"right", "home", "end", "pgup", "pgdown", "delete", "insert",
+}
+
+// Keymap holds the key bindings of every mode, with the keys table of
+// config.lua applied.
+type Keymap struct {
+	modes map[TUIMode]*modeKeymap
+}
+
+// modeKeymap holds the key bindings of one mode. Sequences are keys joined
+// by spaces, as reported by tea.KeyMsg.String, e.g. "g g".
+type modeKeymap struct {
+	bindings Keybindings
+
+	// custom maps sequences to the commands, shell commands and Lua
+	// functions bound to them.
+	custom map[string]config.KeyBinding
+
+	// sequences are the multi-key sequences bound to actions, and prefixes
+	// the partial sequences that wait for another key.
+	sequences map[string]bool
+	prefixes  map[string]bool
+}
+
+// keySequence is the key message passed to a mode when a multi-key sequence
+// bound to an action is complete. Its String is the whole sequence, so it
+// matches the action's binding.
+type keySequence struct {
+	keys string
+	last tea.KeyMsg
+}
+
+func (k keySequence) String() string { return k.keys }
+func (k keySequence) Key() tea.Key   { return k.last.Key() }
+
+// NewKeymap applies the key bindings from config.lua to the defaults. Each
+// mode starts from DefaultKeyBindings:
+//
+//   - binding an action replaces the action's default keys in that mode;
+//   - a user binding takes its keys away from the defaults that use them,
+//     or that start with or are the start of them;
+//   - false removes the default bindings of the keys.
+//
+// Bindings that conflict with one another or cannot be applied are skipped
+// and reported.
+func NewKeymap(userBindings []config.KeyBinding) (*Keymap, []error) {
+	var errs []error
+
+	byMode := map[TUIMode][]config.KeyBinding{}
+	for _, b := range userBindings {
+		mode := modeByName(b.Mode)
+		if mode == "" {
+			errs = append(errs, fmt.Errorf("keys.%s: unknown mode (expected one of %s)", b.Mode, strings.Join(modeNames(), ", ")))
+			continue
+		}
+		byMode[mode] = append(byMode[mode], b)
+	}
+
+	km := &Keymap{modes: map[TUIMode]*modeKeymap{}}
+	for mode := range modeActions {
+		mk, modeErrs := newModeKeymap(mode, byMode[mode])
+		km.modes[mode] = mk
+		errs = append(errs, modeErrs...)
+	}
+	slices.SortFunc(errs, func(a, b error) int { return strings.Compare(a.Error(), b.Error()) })
+	return km, errs
+}
+
+// newModeKeymap applies the user bindings of one mode.
+func newModeKeymap(mode TUIMode, userBindings []config.KeyBinding) (*modeKeymap, []error) {
+	var errs []error
+
+	mk := &modeKeymap{
+		bindings:  DefaultKeyBindings(),
+		custom:    map[string]config.KeyBinding{},
+		sequences: map[string]bool{},
+		prefixes:  map[string]bool{},
+	}
+	actions := mk.bindings.actions()
+	textInput := slices.Contains(textInputModes, mode)
+
+	// Check the user bindings against each other first.
+	type userBinding struct {
+		config.KeyBinding
+		seq string
+	}
+	var accepted []userBinding
+	for _, b := range userBindings {
+		where := fmt.Sprintf("keys.%s[%q]", b.Mode, b.Keys)
+
+		seq, err := normalizeKeys(b.Keys)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+			continue
+		}
+		keys := strings.Split(seq, " ")
+
+		if b.Action != "" {
+			if _, ok := actions[b.Action]; !ok {
+				errs = append(errs, fmt.Errorf("%s: unknown action %q", where, b.Action))
+				continue
+			}
+			if !slices.Contains(modeActions[mode], b.Action) {
+				errs = append(errs, fmt.Errorf("%s: action %q does nothing in %s mode", where, b.Action, b.Mode))
+				continue
+			}
+		}
+		if textInput && len(keys) > 1 {
+			errs = append(errs, fmt.Errorf("%s: key sequences cannot be used while typing in %s mode", where, b.Mode))
+			continue
+		}
+		if textInput && !b.Unbind && b.Action == "" && isPrintableKey(seq) {
+			errs = append(errs, fmt.Errorf("%s: %q is needed for typing in %s mode", where, seq, b.Mode))
+			continue
+		}
+
+		conflict := false
+		for _, other := range accepted {
+			if other.seq == seq || isKeyPrefix(other.seq, seq) || isKeyPrefix(seq, other.seq) {
+				errs = append(errs, fmt.Errorf("%s: conflicts with keys.%s[%q]", where, other.Mode, other.Keys))
+				conflict = true
+				break
+			}
+		}
+		if !conflict {
+			accepted = append(accepted, userBinding{b, seq})
+		}
+	}
+
+	// Replace the default keys of rebound actions.
+	rebound := map[string][]string{}
+	for _, b := range accepted {
+		if b.Action != "" {
+			rebound[b.Action] = append(rebound[b.Action], b.seq)
+		}
+	}
+	for name, seqs := range rebound {
+		actions[name].On = seqs
+	}
+
+	// Take the keys of the user bindings away from the other actions.
+	for _, b := range accepted {
+		for name, action := range actions {
+			if name == b.Action {
+				continue
+			}
+			action.On = slices.DeleteFunc(action.On, func(key string) bool {
+				return key == b.seq || isKeyPrefix(key, b.seq) || isKeyPrefix(b.seq, key)
+			})
+		}
+		if b.Action == "" && !b.Unbind {
+			mk.custom[b.seq] = b.KeyBinding
+		}
+	}
+
+	// Remember the sequences so the keys in between are waited for.
+	for _, name := range modeActions[mode] {
+		for _, seq := range actions[name].On {
+			if strings.Contains(seq, " ") {
+				mk.sequences[seq] = true
+				mk.addPrefixes(seq)
+			}
+		}
+	}
+	for seq := range mk.custom {
+		mk.addPrefixes(seq)
+	}
+
+	return mk, errs
+}
+
+// addPrefixes marks the partial sequences of seq.
+func (mk *modeKeymap) addPrefixes(seq string) {
+	keys := strings.Split(seq, " ")
+	for i := 1; i < len(keys); i++ {
+		mk.prefixes[strings.Join(keys[:i], " ")] = true
+	}
+}
+
+// Bindings returns the action bindings of the given mode.
+func (km *Keymap) Bindings(mode TUIMode) *Keybindings {
+	if mk := km.modes[mode]; mk != nil {
+		return &mk.bindings
+	}
+	bindings := DefaultKeyBindings()
+	return &bindings
+}
+
+// normalizeKeys turns a key sequence as written in config.lua into the keys
+// reported by tea.KeyMsg.String, separated by spaces. Words are split into
+// one key per letter unless they name a key ("esc", "f5", "ctrl+x").
+func normalizeKeys(s string) (string, error) {
+	var keys []string
+	for _, word := range strings.Fields(s) {
+		if utf8.RuneCountInString(word) == 1 || isNamedKey(word) {
+			keys = append(keys, word)
+			continue
+		}
+		for _, r := range word {
+			keys = append(keys, string(r))
+		}
+	}
+	if len(keys) == 0 {
+		return "", fmt.Errorf("empty key sequence")
+	}
+	return strings.Join(keys, " "), nil
+}
+
+// isNamedKey reports whether word is the name of a single key.
+func isNamedKey(word string) bool {
+	if strings.Contains(word, "+") || slices.Contains(namedKeys, word) {
+		return true
+	}
+	var n int
+	_, err := fmt.Sscanf(word, "f%d", &n)
+	return err == nil && fmt.Sprintf("f%d", n) == word && n >= 1 && n <= 20
+}
+
+// isPrintableKey reports whether the key types a character into an input.
+func isPrintableKey(key string) bool {
+	return key == "space" || utf8.RuneCountInString(key) == 1
+}
+
+// isKeyPrefix reports whether the sequence prefix is the start of the
+// longer sequence seq.
+func isKeyPrefix(prefix, seq string) bool {
+	return strings.HasPrefix(seq, prefix+" ")
+}
+
+// modeByName returns the mode called name in config.lua, e.g. "add_file".
+func modeByName(name string) TUIMode {
+	for mode := range modeActions {
+		if strings.ToLower(string(mode)) == name {
+			return mode
+		}
+	}
+	return ""
+}
+
+// modeNames returns the names of the modes in config.lua, sorted.
+func modeNames() []string {
+	var names []string
+	for mode := range modeActions {
+		names = append(names, strings.ToLower(string(mode)))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// resolveKey adds a key press to the pending key sequence. It runs the
+// command bound to a complete sequence, and waits for the next key of a
+// partial one. Otherwise it returns the key message for the active mode to
+// handle, which is a keySequence once a multi-key action binding is complete.
+// A sequence that leads nowhere is dropped, except for its last key.
+func (m *Model) resolveKey(msg tea.KeyMsg) (tea.KeyMsg, tea.Cmd) {
+	mk := m.keymap.modes[ActiveTuiMode]
+	if mk == nil {
+		m.pendingKeys = ""
+		return msg, nil
+	}
+
+	key := msg.String()
+	seq := key
+	if m.pendingKeys != "" {
+		seq = m.pendingKeys + " " + key
+	}
+	m.pendingKeys = ""
+
+	for {
+		if b, ok := mk.custom[seq]; ok {
+			return nil, m.runKeyBinding(b)
+		}
+		if mk.prefixes[seq] {
+			m.pendingKeys = seq
+			return nil, nil
+		}
+		if mk.sequences[seq] {
+			return keySequence{keys: seq, last: msg}, nil
+		}
+		if seq == key {
+			return msg, nil
+		}
+		seq = key
+	}
+}
+
+// runKeyBinding runs the command, shell command or Lua function bound to a
+// key and applies its result.
+func (m *Model) runKeyBinding(b config.KeyBinding) tea.Cmd {
+	env := m.commandEnvironment()
+
+	// Lua runs in the background, so it can be cancelled.
+	switch {
+	case b.Fn != nil:
+		return m.runLua("the "+b.Keys+" key binding", func(env command.Environment) (command.Result, error) {
+			return command.RunFunction(env, b.Fn)
+		})
+	case b.Shell == "" && command.RunsLua(env, b.Command):
+		return m.runLua(":"+b.Command, func(env command.Environment) (command.Result, error) {
+			return command.Execute(env, b.Command)
+		})
+	}
+
+	var (
+		res command.Result
+		err error
+	)
+	switch {
+	case b.Shell != "":
+		res, err = command.Execute(env, "sh "+b.Shell)
+	case m.runtimeConfig.Restricted && command.RunsShell(env, b.Command):
+		err = fmt.Errorf("%s: shell commands are not available in restricted mode", b.Keys)
+	default:
+		res, err = command.Execute(env, b.Command)
+	}
+
+	cmd := m.applyCommandResult(res, err)
+	m.CalcLayout()
+
+	if res.Quit {
+		return tea.Batch(cmd, m.quit())
+	}
+	return cmd
+}
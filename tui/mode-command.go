@@ -4,6 +4,8 @@ import (
 	"strings"
 
 	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
 )
 
 func (m Model) CommandMode(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -12,7 +14,7 @@ func (m Model) CommandMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeCommand)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -26,10 +28,6 @@ func (m Model) CommandMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.commandInput, cmd = m.commandInput.Update(msg)
 	cmds = append(cmds, cmd)
 
-	// Update right viewport (second row, right column)
-	m.rightViewport, cmd = m.rightViewport.Update(msg)
-	cmds = append(cmds, cmd)
-
 	// Update history matches when input changes
 	if m.commandInput.Value() != beforeValue {
 		m.updateHistoryMatches()
@@ -70,49 +68,31 @@ func (m Model) CommandMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case bindings.Enter.Matches(keyMsg.String()):
 		line := strings.TrimSpace(m.commandInput.Value())
 
-		res, err := m.ExecuteCommand(line)
-
-		// Apply environment changes.
-		if res.Cwd != "" && res.Cwd != m.currentDir {
-			m.ChangeDirectory(res.Cwd)
-		} else if res.Refresh {
-			// Re-list the current directory when requested by the command.
-			m.ChangeDirectory(m.currentDir)
-		}
-
-		// Update view mode and re-apply filters so the file list view
-		// actually changes when commands like "ll", "ls", "ld", "lf",
-		// etc. are executed.
-		if res.ViewMode != "" {
-			ActiveFileListMode = FileListMode(res.ViewMode)
-			m.ApplyFilter()
-		}
-
-		if res.OpenHelp {
-			m.activeModal = ModalHelp
-		}
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+		m.searchInput.Focus()
 
-		if res.Output != "" {
-			m.rightViewport.SetContent(res.Output)
-		}
+		// Restore the mode first, as a command may open a prompt.
+		ActiveTuiMode = PreviousTuiMode
 
-		if err != nil && res.Output == "" {
-			m.rightViewport.SetContent(err.Error())
+		// Lua commands run in the background, so they can be cancelled.
+		if command.RunsLua(m.commandEnvironment(), line) {
+			m.recordCommand(line)
+			return m, m.runLua(":"+line, func(env command.Environment) (command.Result, error) {
+				return command.Execute(env, line)
+			})
 		}
 
-		m.commandInput.Blur()
-		m.commandInput.SetValue("")
-		m.searchInput.Focus()
+		res, err := m.ExecuteCommand(line)
+		cmd = m.applyCommandResult(res, err)
 
 		m.CalcLayout()
 
 		if res.Quit {
-			return m, tea.Quit
+			return m, tea.Batch(cmd, m.quit())
 		}
 
-		ActiveTuiMode = PreviousTuiMode
-
-		return m, nil
+		return m, cmd
 
 	}
 
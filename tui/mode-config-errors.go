@@ -0,0 +1,38 @@
+package tui
+
+import (
+	tea "charm.land/bubbletea/v2"
+)
+
+// openConfigErrors shows the problems found in config.lua in a modal.
+func (m *Model) openConfigErrors() {
+	if ActiveTuiMode != TuiModeConfigErrors {
+		PreviousTuiMode = ActiveTuiMode
+	}
+	ActiveTuiMode = TuiModeConfigErrors
+}
+
+func (m Model) ConfigErrorsMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	bindings := m.keymap.Bindings(TuiModeConfigErrors)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	// Quit application
+	case bindings.Quit.Matches(keyMsg.String()):
+		SetQuitMode()
+		return m, nil
+
+	// Close the modal
+	case bindings.Enter.Matches(keyMsg.String()) ||
+		bindings.Cancel.Matches(keyMsg.String()):
+		ActiveTuiMode = PreviousTuiMode
+		return m, nil
+	}
+
+	return m, nil
+}
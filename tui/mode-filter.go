@@ -10,7 +10,7 @@ func (m Model) FilterMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeFilter)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -23,7 +23,7 @@ func (m Model) FilterMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 	m.searchInput, cmd = m.searchInput.Update(msg)
 	cmds = append(cmds, cmd)
 	if m.searchInput.Value() != before {
-		m.ApplyFilter()
+		cmds = append(cmds, m.ApplyFilter())
 	}
 
 	switch {
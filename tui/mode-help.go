@@ -5,7 +5,7 @@ import (
 )
 
 func (m Model) HelpMode(msg tea.Msg) (tea.Model, tea.Cmd) {
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeHelp)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -12,7 +12,7 @@ func (m Model) MkdirMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		cmds []tea.Cmd
 	)
 
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeMkdir)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -38,17 +38,18 @@ func (m Model) MkdirMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 			res, _ := m.ExecuteCommand("touch " + line)
 
 			if res.Cwd != "" && res.Cwd != m.currentDir {
-				m.ChangeDirectory(res.Cwd)
+				cmd = m.ChangeDirectory(res.Cwd)
 			} else if res.Refresh {
-				m.ChangeDirectory(m.currentDir)
+				cmd = m.ChangeDirectory(m.currentDir)
 			}
+			cmd = tea.Batch(cmd, m.runFileOpHooks(res.FileOps))
 		}
 
 		m.commandInput.Blur()
 		m.commandInput.SetValue("")
 
 		ActiveTuiMode = PreviousTuiMode
-		return m, nil
+		return m, cmd
 
 	// Cancel add-file mode
 	case bindings.Cancel.Matches(keyMsg.String()):
@@ -7,7 +7,7 @@ import (
 )
 
 func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeNormal)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -48,14 +48,12 @@ func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Change file list to directoties only view
 	case bindings.Directories.Matches(keyMsg.String()):
 		ActiveFileListMode = "ld"
-		m.ApplyFilter()
-		return m, nil
+		return m, m.ApplyFilter()
 
 	// Move cursor down in file list
 	case bindings.Down.Matches(keyMsg.String()):
 		m.fileList.CursorDown()
-		m.UpdatePreview()
-		return m, nil
+		return m, m.UpdatePreview()
 
 		// Navigate into the selected directory.
 	case bindings.Enter.Matches(keyMsg.String()):
@@ -63,16 +61,14 @@ func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if selectedIdx >= 0 && selectedIdx < len(m.files) {
 			fi := m.files[selectedIdx]
 			if fi.IsDir {
-				m.ChangeDirectory(fi.Path)
-				return m, nil
+				return m, m.ChangeDirectory(fi.Path)
 			}
 		}
 
 	// Change file list to files only view
 	case bindings.Files.Matches(keyMsg.String()):
 		ActiveFileListMode = "lf"
-		m.ApplyFilter()
-		return m, nil
+		return m, m.ApplyFilter()
 
 	// Enter filter mode
 	case bindings.Filter.Matches(keyMsg.String()):
@@ -85,17 +81,38 @@ func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, nil
 		}
 
+	// Move keyboard focus to the preview pane
+	case bindings.FocusPreview.Matches(keyMsg.String()):
+		PreviousTuiMode = ActiveTuiMode
+		ActiveTuiMode = TuiModePreview
+		if m.previewTab == PreviewTabPermissions {
+			// Re-render so the editor shows its cursor hint.
+			return m, m.UpdatePreview()
+		}
+		// Show the tree cursor of structured data previews.
+		m.refreshPreviewViewport()
+		if m.previewNeedsMore() {
+			return m, m.loadMorePreview()
+		}
+		return m, nil
+
+	// Switch to the next preview tab
+	case bindings.NextTab.Matches(keyMsg.String()):
+		return m, m.cyclePreviewTab(1)
+
+	// Switch to the previous preview tab
+	case bindings.PrevTab.Matches(keyMsg.String()):
+		return m, m.cyclePreviewTab(-1)
+
 	// Move move cursor to end of file list
 	case bindings.GoToEnd.Matches(keyMsg.String()):
 		m.fileList.GoToEnd()
-		m.UpdatePreview()
-		return m, nil
+		return m, m.UpdatePreview()
 
 		// Move move cursor to start of file list
 	case bindings.GoToStart.Matches(keyMsg.String()):
 		m.fileList.GoToStart()
-		m.UpdatePreview()
-		return m, nil
+		return m, m.UpdatePreview()
 
 	// Open help modal
 	case bindings.Help.Matches(keyMsg.String()):
@@ -108,26 +125,22 @@ func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Change file list to list all items view
 	case bindings.List.Matches(keyMsg.String()):
 		ActiveFileListMode = "ll"
-		m.ApplyFilter()
-		return m, nil
+		return m, m.ApplyFilter()
 
 	// Navigate to the parent directory.
 	case bindings.Parent.Matches(keyMsg.String()):
 		parent := filepath.Dir(m.currentDir)
 		if parent != "" && parent != m.currentDir {
-			m.ChangeDirectory(parent)
-		} else {
-			// Even if we're at the root (Dir("/") == "/"), attempt to
-			// reload so the listing stays fresh.
-			m.ChangeDirectory(m.currentDir)
+			return m, m.ChangeDirectory(parent)
 		}
-		return m, nil
+		// Even if we're at the root (Dir("/") == "/"), attempt to
+		// reload so the listing stays fresh.
+		return m, m.ChangeDirectory(m.currentDir)
 
 	// Toggle preview
 	case bindings.Preview.Matches(keyMsg.String()):
 		m.previewEnabled = !m.previewEnabled
-		m.UpdatePreview()
-		return m, nil
+		return m, m.UpdatePreview()
 
 	// Quit application
 	case bindings.Quit.Matches(keyMsg.String()):
@@ -145,8 +158,7 @@ func (m Model) NormalMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 	// Move cursor up in file list
 	case bindings.Up.Matches(keyMsg.String()):
 		m.fileList.CursorUp()
-		m.UpdatePreview()
-		return m, nil
+		return m, m.UpdatePreview()
 	}
 
 	return m, nil
@@ -0,0 +1,63 @@
+package tui
+
+import (
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/filesystem"
+)
+
+func (m Model) OwnerMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	bindings := m.keymap.Bindings(TuiModeOwner)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	m.searchInput.Blur()
+
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch {
+	// Apply the new "user:group" ownership on Enter.
+	case bindings.Enter.Matches(keyMsg.String()):
+		line := strings.TrimSpace(m.commandInput.Value())
+		var hooks tea.Cmd
+		if line != "" && m.lastPreviewedPath != "" {
+			owner, group, _ := strings.Cut(line, ":")
+			err := filesystem.SetOwner(m.lastPreviewedPath, owner, group)
+			if err != nil {
+				m.permStatus = "Error: " + err.Error()
+			} else {
+				m.permStatus = ""
+				m.refreshSelectedFile()
+			}
+			hooks = m.runFileOpHooks([]command.FileOp{{Name: "chown", Paths: []string{m.lastPreviewedPath}, Err: err}})
+		}
+
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+
+		ActiveTuiMode = PreviousTuiMode
+		return m, tea.Batch(m.UpdatePreview(), hooks)
+
+	// Cancel the ownership change
+	case bindings.Cancel.Matches(keyMsg.String()):
+		ActiveTuiMode = PreviousTuiMode
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+		return m, nil
+	}
+
+	return m, tea.Batch(cmds...)
+}
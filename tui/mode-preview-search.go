@@ -0,0 +1,56 @@
+package tui
+
+import (
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+func (m Model) PreviewSearchMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	bindings := m.keymap.Bindings(TuiModePreviewSearch)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	m.searchInput.Blur()
+
+	// Search incrementally as the query is typed.
+	before := m.commandInput.Value()
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	cmds = append(cmds, cmd)
+	if m.commandInput.Value() != before {
+		m.searchPreview(strings.TrimSpace(m.commandInput.Value()))
+	}
+
+	switch {
+	// Confirm the search and return to the preview
+	case bindings.Enter.Matches(keyMsg.String()):
+		m.searchPreview(strings.TrimSpace(m.commandInput.Value()))
+
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+
+		ActiveTuiMode = TuiModePreview
+		return m, nil
+
+	// Clear the search and return to the preview
+	case bindings.Cancel.Matches(keyMsg.String()):
+		m.searchPreview("")
+
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+
+		ActiveTuiMode = TuiModePreview
+		return m, nil
+	}
+
+	return m, tea.Batch(cmds...)
+}
@@ -0,0 +1,121 @@
+package tui
+
+import (
+	tea "charm.land/bubbletea/v2"
+)
+
+func (m Model) PreviewMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	bindings := m.keymap.Bindings(TuiModePreview)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	// The permissions editor uses the movement keys itself.
+	if m.previewTab == PreviewTabPermissions {
+		if handled, cmd := m.handlePermissionsKey(keyMsg.String()); handled {
+			return m, cmd
+		}
+	}
+
+	// Structured data previews move a cursor through the tree instead of
+	// scrolling, and fold the node under it.
+	if tree := m.preview.tree; tree != nil {
+		changed := false
+		switch {
+		case bindings.ScrollDown.Matches(keyMsg.String()):
+			tree.cursor = min(tree.cursor+1, len(tree.lines)-1)
+			changed = true
+		case bindings.ScrollUp.Matches(keyMsg.String()):
+			tree.cursor = max(tree.cursor-1, 0)
+			changed = true
+		case bindings.Enter.Matches(keyMsg.String()):
+			changed = tree.toggle()
+		case bindings.Left.Matches(keyMsg.String()):
+			changed = tree.collapse()
+		case bindings.Right.Matches(keyMsg.String()):
+			changed = tree.expand()
+		}
+		if changed {
+			m.updatePreviewTree()
+			return m, nil
+		}
+	}
+
+	switch {
+	// Quit application
+	case bindings.Quit.Matches(keyMsg.String()):
+		SetQuitMode()
+		return m, nil
+
+	// Return focus to the file list
+	case bindings.FocusPreview.Matches(keyMsg.String()) ||
+		bindings.Cancel.Matches(keyMsg.String()):
+		ActiveTuiMode = TuiModeNormal
+		if m.previewTab == PreviewTabPermissions {
+			return m, m.UpdatePreview()
+		}
+		// Hide the tree cursor.
+		m.refreshPreviewViewport()
+		return m, nil
+
+	// Switch to the next preview tab
+	case bindings.NextTab.Matches(keyMsg.String()):
+		return m, m.cyclePreviewTab(1)
+
+	// Switch to the previous preview tab
+	case bindings.PrevTab.Matches(keyMsg.String()):
+		return m, m.cyclePreviewTab(-1)
+
+	// Scroll preview down one line
+	case bindings.ScrollDown.Matches(keyMsg.String()):
+		m.rightViewport.ScrollDown(1)
+
+	// Scroll preview up one line
+	case bindings.ScrollUp.Matches(keyMsg.String()):
+		m.rightViewport.ScrollUp(1)
+
+	// Scroll preview down one page
+	case bindings.PageDown.Matches(keyMsg.String()):
+		m.rightViewport.PageDown()
+
+	// Scroll preview up one page
+	case bindings.PageUp.Matches(keyMsg.String()):
+		m.rightViewport.PageUp()
+
+	// Jump to the top of the preview
+	case bindings.GoToStart.Matches(keyMsg.String()):
+		m.rightViewport.GotoTop()
+
+	// Jump to the end of the loaded preview
+	case bindings.GoToEnd.Matches(keyMsg.String()):
+		m.rightViewport.GotoBottom()
+
+	// Open the preview search prompt
+	case bindings.Search.Matches(keyMsg.String()):
+		PreviousTuiMode = ActiveTuiMode
+		ActiveTuiMode = TuiModePreviewSearch
+
+		m.commandInput.SetValue(m.previewSearch)
+		m.commandInput.CursorEnd()
+		m.commandInput.Focus()
+		return m, nil
+
+	// Jump to next search match
+	case bindings.NextMatch.Matches(keyMsg.String()):
+		m.nextPreviewMatch(1)
+
+	// Jump to previous search match
+	case bindings.PrevMatch.Matches(keyMsg.String()):
+		m.nextPreviewMatch(-1)
+	}
+
+	// Load the next chunk of a large file before the reader reaches the end.
+	if m.previewNeedsMore() {
+		return m, m.loadMorePreview()
+	}
+
+	return m, nil
+}
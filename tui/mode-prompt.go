@@ -0,0 +1,62 @@
+package tui
+
+import (
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+)
+
+// openPrompt asks the user for input on behalf of a Lua command, reusing the
+// command input.
+func (m *Model) openPrompt(p *command.Prompt) {
+	m.prompt = p
+	m.searchInput.Blur()
+	m.commandInput.SetValue(p.Default)
+	m.commandInput.CursorEnd()
+	m.commandInput.Focus()
+
+	if ActiveTuiMode != TuiModePrompt {
+		PreviousTuiMode = ActiveTuiMode
+	}
+	ActiveTuiMode = TuiModePrompt
+}
+
+func (m Model) PromptMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var (
+		cmd  tea.Cmd
+		cmds []tea.Cmd
+	)
+
+	bindings := m.keymap.Bindings(TuiModePrompt)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	m.commandInput, cmd = m.commandInput.Update(msg)
+	cmds = append(cmds, cmd)
+
+	switch {
+	// Pass the answer to the Lua command on Enter, or nil on cancel.
+	case bindings.Enter.Matches(keyMsg.String()),
+		bindings.Cancel.Matches(keyMsg.String()):
+		answered := bindings.Enter.Matches(keyMsg.String())
+		answer := m.commandInput.Value()
+		prompt := m.prompt
+
+		m.prompt = nil
+		m.commandInput.Blur()
+		m.commandInput.SetValue("")
+		m.searchInput.Focus()
+		ActiveTuiMode = PreviousTuiMode
+
+		// The callback is Lua, so it runs in the background like a command.
+		return m, m.runLua("the prompt callback", func(env command.Environment) (command.Result, error) {
+			return command.AnswerPrompt(env, prompt, answer, answered)
+		})
+	}
+
+	return m, tea.Batch(cmds...)
+}
@@ -5,7 +5,7 @@ import (
 )
 
 func (m Model) QuitMode(msg tea.Msg) (tea.Model, tea.Cmd) {
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeQuit)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
@@ -15,7 +15,7 @@ func (m Model) QuitMode(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch {
 	case bindings.Quit.Matches(keyMsg.String()):
-		return m, tea.Quit
+		return m, m.quit()
 
 	case bindings.Cancel.Matches(keyMsg.String()):
 		ActiveTuiMode = PreviousTuiMode
@@ -0,0 +1,181 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+)
+
+// luaCallTimeout bounds the Lua code that runs on the UI goroutine and so
+// freezes the UI while it runs: hooks and job callbacks. Custom columns are
+// bounded by columnFillBudget instead.
+const luaCallTimeout = 2 * time.Second
+
+// luaPreviewerTimeout bounds a Lua previewer. Previewers run on the UI
+// goroutine every time the selection moves, before the preview is handed to
+// the background renderer, so one that reads a large file holds up moving
+// through the list. Slow work belongs in the command a previewer returns,
+// which runs in the background.
+const luaPreviewerTimeout = 500 * time.Millisecond
+
+// luaRunNoticeDelay is how long a Lua command runs before the status bar
+// says how to cancel it, so quick commands do not flash a message.
+const luaRunNoticeDelay = 500 * time.Millisecond
+
+// luaRun is a Lua command running in the background while the UI waits in
+// running mode.
+type luaRun struct {
+	seq    int
+	label  string
+	mode   TUIMode
+	cancel context.CancelFunc
+
+	// notice is the status bar message saying how to cancel the command,
+	// once shown.
+	notice string
+
+	// hookResults are the hook results that arrived while the command ran,
+	// applied once it is done, and jobEvents the job events whose callbacks
+	// wait for it.
+	hookResults []hookResultMsg
+	jobEvents   []command.JobEvent
+}
+
+// luaDoneMsg carries the result of a Lua command run in the background.
+type luaDoneMsg struct {
+	seq int
+	res command.Result
+	err error
+}
+
+// luaRunNoticeMsg asks for the notice of a command that is still running.
+type luaRunNoticeMsg struct {
+	seq int
+}
+
+// runLua runs call, which calls into Lua with the environment it is given,
+// in the background. Until it returns, the UI is in running mode, where the
+// only key is the one cancelling it, and nothing else uses the Lua state.
+// Its context has the deadline set by command_timeout.
+func (m *Model) runLua(label string, call func(env command.Environment) (command.Result, error)) tea.Cmd {
+	env := m.commandEnvironment()
+
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if timeout := m.runtimeConfig.CommandTimeout; timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), timeout)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+	env.Context = ctx
+
+	m.luaRunSeq++
+	seq := m.luaRunSeq
+	m.luaRun = &luaRun{seq: seq, label: label, mode: ActiveTuiMode, cancel: cancel}
+	ActiveTuiMode = TuiModeRunning
+
+	run := func() tea.Msg {
+		defer cancel()
+		res, err := call(env)
+		return luaDoneMsg{seq: seq, res: res, err: err}
+	}
+	notice := tea.Tick(luaRunNoticeDelay, func(time.Time) tea.Msg {
+		return luaRunNoticeMsg{seq: seq}
+	})
+	return tea.Batch(run, notice)
+}
+
+// luaRunning reports whether a Lua command is running in the background,
+// in which case nothing else may call into Lua.
+func (m *Model) luaRunning() bool {
+	return m.luaRun != nil
+}
+
+// boundedEnvironment returns the command environment for Lua code run on
+// the UI goroutine, bounded by timeout. The caller cancels it once the code
+// returns.
+func (m *Model) boundedEnvironment(timeout time.Duration) (command.Environment, context.CancelFunc) {
+	env := m.commandEnvironment()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	env.Context = ctx
+	return env, cancel
+}
+
+// handleLuaRunNotice tells the user how to cancel a command that is taking
+// a while.
+func (m *Model) handleLuaRunNotice(msg luaRunNoticeMsg) tea.Cmd {
+	if m.luaRun == nil || msg.seq != m.luaRun.seq {
+		return nil
+	}
+	cancelKeys := strings.Join(m.keymap.Bindings(TuiModeRunning).Cancel.On, " or ")
+	m.luaRun.notice = fmt.Sprintf("Running %s… press %s to cancel", m.luaRun.label, cancelKeys)
+	return m.notify(m.luaRun.notice)
+}
+
+// handleLuaDone leaves running mode and applies the result of the command
+// like one run on the UI goroutine. A cancelled command's changes are still
+// applied, but its prompt is not opened and its jobs are not started.
+func (m *Model) handleLuaDone(msg luaDoneMsg) tea.Cmd {
+	if m.luaRun == nil || msg.seq != m.luaRun.seq {
+		return nil
+	}
+	run := m.luaRun
+	m.luaRun = nil
+	ActiveTuiMode = run.mode
+
+	var cmds []tea.Cmd
+	res, err := msg.res, msg.err
+	if errors.Is(err, context.Canceled) {
+		res.Output = ""
+		res.Prompt = nil
+		res.Jobs = nil
+		err = nil
+		cmds = append(cmds, m.notify("Cancelled "+run.label))
+	} else if run.notice != "" && m.notification == run.notice {
+		m.notification = ""
+	}
+
+	cmds = append(cmds, m.applyCommandResult(res, err))
+	m.CalcLayout()
+
+	for _, hookRes := range run.hookResults {
+		cmds = append(cmds, m.handleHookResult(hookRes))
+	}
+	for _, ev := range run.jobEvents {
+		cmds = append(cmds, m.runJobCallback(ev))
+	}
+
+	if res.Quit {
+		cmds = append(cmds, m.quit())
+	}
+	return tea.Batch(cmds...)
+}
+
+func (m Model) RunningMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	bindings := m.keymap.Bindings(TuiModeRunning)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	switch {
+	// Stop the command; its result arrives as usual.
+	case bindings.Cancel.Matches(keyMsg.String()):
+		if m.luaRun != nil {
+			m.luaRun.cancel()
+		}
+		return m, nil
+	}
+
+	return m, nil
+}
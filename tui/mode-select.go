@@ -5,7 +5,7 @@ import (
 )
 
 func (m Model) SelectMode(msg tea.Msg) (tea.Model, tea.Cmd) {
-	bindings := GetKeyBindings()
+	bindings := m.keymap.Bindings(TuiModeSelect)
 
 	// Only handle key messages here; ignore everything else.
 	keyMsg, ok := msg.(tea.KeyMsg)
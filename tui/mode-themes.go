@@ -0,0 +1,127 @@
+package tui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/config"
+	"cute/console"
+	"cute/theming"
+)
+
+// themePicker lists the themes in themes mode. The highlighted theme is
+// applied as the cursor moves, so the file list shows how it looks.
+type themePicker struct {
+	names []string
+	index int
+
+	// theme and name are the theme in use when the picker opened, restored
+	// when it is cancelled.
+	theme theming.Theme
+	name  string
+}
+
+// switchTheme loads the named theme and applies it for the rest of the
+// session, or until config.lua is reloaded.
+func (m *Model) switchTheme(name string) tea.Cmd {
+	if !slices.Contains(config.ThemeNames(m.configDir), name) {
+		_, errs := config.LoadNamedTheme(m.configDir, name)
+		return m.notify(errs[0].Error())
+	}
+
+	theme, errs := config.LoadNamedTheme(m.configDir, name)
+	m.themeName = name
+	cmd := m.applyTheme(theme)
+	if len(errs) > 0 {
+		for _, err := range errs {
+			console.Log("theme error: %v", err)
+		}
+		message := strings.ReplaceAll(errs[0].Error(), "\n", "; ")
+		return tea.Batch(cmd, m.notify(fmt.Sprintf("Theme %s has %d problem(s): %s", name, len(errs), message)))
+	}
+	return tea.Batch(cmd, m.notify("Theme "+name))
+}
+
+// openThemePicker lets the user pick a theme, starting at the one in use.
+func (m *Model) openThemePicker() {
+	names := config.ThemeNames(m.configDir)
+	m.themePicker = &themePicker{
+		names: names,
+		index: max(slices.Index(names, m.themeName), 0),
+		theme: m.theme,
+		name:  m.themeName,
+	}
+
+	if ActiveTuiMode != TuiModeThemes {
+		PreviousTuiMode = ActiveTuiMode
+	}
+	ActiveTuiMode = TuiModeThemes
+}
+
+// previewPickedTheme applies the theme under the cursor of the picker.
+// Problems in it are logged; they are reported once it is picked.
+func (m *Model) previewPickedTheme() tea.Cmd {
+	theme, errs := config.LoadNamedTheme(m.configDir, m.themePicker.names[m.themePicker.index])
+	for _, err := range errs {
+		console.Log("theme error: %v", err)
+	}
+	return m.applyTheme(theme)
+}
+
+// closeThemePicker leaves themes mode.
+func (m *Model) closeThemePicker() {
+	m.themePicker = nil
+	ActiveTuiMode = PreviousTuiMode
+}
+
+func (m Model) ThemesMode(msg tea.Msg) (tea.Model, tea.Cmd) {
+	bindings := m.keymap.Bindings(TuiModeThemes)
+
+	// Only handle key messages here; ignore everything else.
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || m.themePicker == nil {
+		return m, nil
+	}
+	picker := m.themePicker
+
+	switch {
+	// Quit application
+	case bindings.Quit.Matches(keyMsg.String()):
+		SetQuitMode()
+		return m, nil
+
+	// Move through the themes, showing each
+	case bindings.Up.Matches(keyMsg.String()):
+		if len(picker.names) == 0 {
+			return m, nil
+		}
+		picker.index = (picker.index + len(picker.names) - 1) % len(picker.names)
+		return m, m.previewPickedTheme()
+
+	case bindings.Down.Matches(keyMsg.String()):
+		if len(picker.names) == 0 {
+			return m, nil
+		}
+		picker.index = (picker.index + 1) % len(picker.names)
+		return m, m.previewPickedTheme()
+
+	// Keep the theme under the cursor
+	case bindings.Enter.Matches(keyMsg.String()):
+		m.closeThemePicker()
+		if len(picker.names) == 0 {
+			return m, nil
+		}
+		return m, m.switchTheme(picker.names[picker.index])
+
+	// Go back to the theme in use before
+	case bindings.Cancel.Matches(keyMsg.String()):
+		m.closeThemePicker()
+		m.themeName = picker.name
+		return m, m.applyTheme(picker.theme)
+	}
+
+	return m, nil
+}
@@ -1,7 +1,7 @@
 package tui
 
 import (
-	"time"
+	"context"
 
 	"charm.land/bubbles/v2/list"
 	"charm.land/bubbles/v2/textinput"
@@ -9,6 +9,7 @@ import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 
+	"cute/command"
 	"cute/config"
 	"cute/filesystem"
 	"cute/theming"
@@ -24,34 +25,55 @@ const (
 type TUIMode string
 
 type TUIModes struct {
-	TuiModeNormal  TUIMode
-	TuiModeCommand TUIMode
-	TuiModeFilter  TUIMode
-	TuiModeHelp    TUIMode
-	TuiModeSelect  TUIMode
-	TuiModeQuit    TUIMode
-	TuiModeAddFile TUIMode
-	TuiModeMkdir   TUIMode
+	TuiModeNormal        TUIMode
+	TuiModeCommand       TUIMode
+	TuiModeFilter        TUIMode
+	TuiModeHelp          TUIMode
+	TuiModeSelect        TUIMode
+	TuiModeQuit          TUIMode
+	TuiModeAddFile       TUIMode
+	TuiModeMkdir         TUIMode
+	TuiModePreview       TUIMode
+	TuiModePreviewSearch TUIMode
+	TuiModeOwner         TUIMode
+	TuiModePrompt        TUIMode
+	TuiModeConfigErrors  TUIMode
+	TuiModeRunning       TUIMode
+	TuiModeThemes        TUIMode
 }
 
 const (
-	TuiModeCommand TUIMode = "COMMAND"
-	TuiModeFilter  TUIMode = "FILTER"
-	TuiModeHelp    TUIMode = "HELP"
-	TuiModeNormal  TUIMode = "NORMAL"
-	TuiModeQuit    TUIMode = "QUIT"
-	TuiModeSelect  TUIMode = "SELECT"
-	TuiModeAddFile TUIMode = "ADD_FILE"
-	TuiModeMkdir   TUIMode = "MKDIR"
+	TuiModeCommand       TUIMode = "COMMAND"
+	TuiModeFilter        TUIMode = "FILTER"
+	TuiModeHelp          TUIMode = "HELP"
+	TuiModeNormal        TUIMode = "NORMAL"
+	TuiModeQuit          TUIMode = "QUIT"
+	TuiModeSelect        TUIMode = "SELECT"
+	TuiModeAddFile       TUIMode = "ADD_FILE"
+	TuiModeMkdir         TUIMode = "MKDIR"
+	TuiModePreview       TUIMode = "PREVIEW"
+	TuiModePreviewSearch TUIMode = "SEARCH"
+	TuiModeOwner         TUIMode = "OWNER"
+	TuiModePrompt        TUIMode = "PROMPT"
+	TuiModeConfigErrors  TUIMode = "ERRORS"
+	TuiModeRunning       TUIMode = "RUNNING"
+	TuiModeThemes        TUIMode = "THEMES"
 )
 
 var TuiModes = TUIModes{
-	TuiModeNormal:  TuiModeNormal,
-	TuiModeCommand: TuiModeCommand,
-	TuiModeFilter:  TuiModeFilter,
-	TuiModeHelp:    TuiModeHelp,
-	TuiModeAddFile: TuiModeAddFile,
-	TuiModeMkdir:   TuiModeMkdir,
+	TuiModeNormal:        TuiModeNormal,
+	TuiModeCommand:       TuiModeCommand,
+	TuiModeFilter:        TuiModeFilter,
+	TuiModeHelp:          TuiModeHelp,
+	TuiModeAddFile:       TuiModeAddFile,
+	TuiModeMkdir:         TuiModeMkdir,
+	TuiModePreview:       TuiModePreview,
+	TuiModePreviewSearch: TuiModePreviewSearch,
+	TuiModeOwner:         TuiModeOwner,
+	TuiModePrompt:        TuiModePrompt,
+	TuiModeConfigErrors:  TuiModeConfigErrors,
+	TuiModeRunning:       TuiModeRunning,
+	TuiModeThemes:        TuiModeThemes,
 }
 
 type (
@@ -137,14 +159,105 @@ type Model struct {
 	imagePreviewActive bool
 	previewEnabled     bool
 
-	// Debounced image preview
-	imagePreviewTimer *time.Timer
-	pendingImagePath  string
+	// Image previews. imageProtocolSetting is the image_preview config value;
+	// imageProtocol is the protocol in use, which is detected when the setting
+	// is auto. imagePreviewProtocol is the protocol that drew the image on
+	// screen and imagePreviewID its Kitty image ID. imagePreviewSeq is bumped
+	// whenever a pending image preview is cancelled so late results are
+	// dropped. kittyImageID numbers the Kitty images sent so far.
+	imageProtocolSetting ImageProtocol
+	imageProtocol        ImageProtocol
+	imagePreviewProtocol ImageProtocol
+	imagePreviewID       int
+	imagePreviewSeq      int
+	pendingImagePath     string
+	kittyImageID         int
+
+	// tmux is set when running inside tmux, whose passthrough sequence is
+	// used to reach the outer terminal with image sequences.
+	tmux bool
+
+	// cellWidth and cellHeight are the size of a terminal cell in pixels, as
+	// reported by the terminal, or zero when unknown.
+	cellWidth  int
+	cellHeight int
+
+	// Asynchronous text/directory previews. previewSeq is bumped whenever a
+	// new preview is requested so results for an old selection are dropped.
+	previewCache      *previewCache
+	previewSeq        int
+	previewCancel     context.CancelFunc
+	previewPendingKey previewKey
+
+	// Preview pane content. previewSource and previewShownKey describe what
+	// is currently shown so further chunks of a large file can be loaded.
+	preview         previewContent
+	previewSource   previewRequest
+	previewShownKey previewKey
+
+	// Preview search state.
+	previewSearch   string
+	previewMatches  []previewMatch
+	previewMatchIdx int
+
+	// previewTab is the active tab above the preview pane.
+	previewTab PreviewTab
+
+	// Permissions editor state (Permissions tab).
+	permRow    int
+	permCol    int
+	permStatus string
+
+	// hookSelectedPath and hookPreviewedPath are the paths the on_select and
+	// on_preview hooks last ran for.
+	hookSelectedPath  string
+	hookPreviewedPath string
+
+	// keymap holds the key bindings of every mode. pendingKeys is the start
+	// of a key sequence waiting for its next key.
+	keymap      *Keymap
+	pendingKeys string
+
+	// configErrors are the problems found when config.lua was last loaded,
+	// shown once the UI starts and by :config-errors. configStamp
+	// identifies the version of the file that was loaded, so changes to it
+	// are reloaded.
+	configErrors []error
+	configStamp  configStamp
+
+	// columns are the columns of the file list config.lua asks for;
+	// columnValues keeps the values of the custom ones for the current
+	// directory.
+	columns      []fileColumn
+	columnValues *columnValues
+
+	// prompt is the question a Lua command is waiting on in prompt mode.
+	prompt *command.Prompt
+
+	// luaRun is the Lua command running in the background in running mode;
+	// luaRunSeq numbers them so a stale result is ignored.
+	luaRun    *luaRun
+	luaRunSeq int
+
+	// outputJob is the ID of the job whose output the preview pane shows,
+	// or zero.
+	outputJob int
+
+	// themeName is the name of the theme in use, if it has one;
+	// themePicker lists the themes in themes mode.
+	themeName   string
+	themePicker *themePicker
+
+	// notification is the message shown in the status bar;
+	// notificationSeq is bumped with each one so it is cleared on time.
+	notification    string
+	notificationSeq int
 
 	// Components
 	CurrentDir   func(m Model, args ComponentArgs) string
 	FileListView func(m Model, args ComponentArgs) string
 	Header       func(m Model, args ComponentArgs) string
+	Notification func(m Model, args ComponentArgs) string
 	Preview      func(m Model, args ComponentArgs) string
 	PreviewTabs  func(m Model, args ComponentArgs) string
 	SearchBar    func(m Model, args ComponentArgs) string
@@ -153,13 +266,35 @@ type Model struct {
 	ViewModeText func(m Model, args ComponentArgs) string
 
 	// Modals
-	HelpModal    func(m Model) *lipgloss.Layer
-	CommandModal func(m Model, args CommandModalArgs) *lipgloss.Layer
-	QuitModal    func(m Model) *lipgloss.Layer
+	HelpModal         func(m Model) *lipgloss.Layer
+	CommandModal      func(m Model, args CommandModalArgs) *lipgloss.Layer
+	QuitModal         func(m Model) *lipgloss.Layer
+	ConfigErrorsModal func(m Model) *lipgloss.Layer
+	ThemePickerModal  func(m Model) *lipgloss.Layer
 }
 
 func (m Model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, imageProtocolQueries(m.tmux), m.runHook(config.HookStartup), m.reportConfigErrors(), watchConfig())
+}
+
+// GetConfigErrors returns the problems found when config.lua was last
+// loaded.
+func (m Model) GetConfigErrors() []error {
+	return m.configErrors
+}
+
+// GetThemePicker returns the names of the themes listed in themes mode and
+// the index of the highlighted one.
+func (m Model) GetThemePicker() ([]string, int) {
+	if m.themePicker == nil {
+		return nil, 0
+	}
+	return m.themePicker.names, m.themePicker.index
+}
+
+// GetThemeName returns the name of the theme in use, if it has one.
+func (m Model) GetThemeName() string {
+	return m.themeName
 }
 
 func (m Model) GetActiveModal() ModalKind {
@@ -212,6 +347,19 @@ func (m Model) GetHistoryMatches() []string {
 	return m.historyMatches
 }
 
+// GetNotification returns the message to show in the status bar, if any.
+func (m Model) GetNotification() string {
+	return m.notification
+}
+
+// GetPrompt returns the question a Lua command is asking in prompt mode.
+func (m Model) GetPrompt() string {
+	if m.prompt == nil {
+		return ""
+	}
+	return m.prompt.Message
+}
+
 func (m Model) GetLayout() string {
 	return m.layout
 }
@@ -249,13 +397,18 @@ func (m Model) GetTerminalType() string {
 	return m.terminalType
 }
 
+// GetImageProtocol returns the protocol used to draw image previews.
+func (m Model) GetImageProtocol() ImageProtocol {
+	return m.imageProtocol
+}
+
 // GetLastPreviewedPath returns the last file path we generated a preview for.
 func (m Model) GetLastPreviewedPath() string {
 	return m.lastPreviewedPath
 }
 
 // IsImagePreviewActive reports whether the current preview is expected to be
-// rendered as an image (e.g. via Kitty graphics) rather than text content in
+// rendered as an image (e.g. via Kitty or Sixel graphics) rather than text content in
 // the right viewport.
 func (m Model) IsImagePreviewActive() bool {
 	return m.imagePreviewActive
@@ -277,6 +430,25 @@ func (m Model) IsSearchBarOpen() bool {
 	return m.isSearchBarOpen
 }
 
+// IsPreviewFocused reports whether keyboard focus is on the preview pane
+// rather than the file list.
+func (m Model) IsPreviewFocused() bool {
+	return ActiveTuiMode == TuiModePreview ||
+		ActiveTuiMode == TuiModePreviewSearch ||
+		ActiveTuiMode == TuiModeOwner
+}
+
+// GetPreviewTab returns the active preview tab.
+func (m Model) GetPreviewTab() PreviewTab {
+	return m.previewTab
+}
+
+// GetPreviewSearch returns the active preview search query and the number of
+// matches found in the loaded preview content.
+func (m Model) GetPreviewSearch() (query string, matches int) {
+	return m.previewSearch, len(m.previewMatches)
+}
+
 // IsPreviewEnabled reports whether rich previews (text/image) are enabled for
 // the right-hand panel. When disabled, the panel shows simple file
 // information/properties instead.
@@ -0,0 +1,92 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	"cute/filesystem"
+	"cute/theming"
+)
+
+// renderArchivePreview lists the entries of an archive with their modes,
+// sizes, compressed sizes and dates. It reports false when path is not an
+// archive in a supported format.
+func renderArchivePreview(ctx context.Context, path string, theme theming.Theme) (string, bool) {
+	listing, err := filesystem.ListArchive(ctx, path)
+	if err == filesystem.ErrNotArchive {
+		return "", false
+	}
+	if err != nil && len(listing.Entries) == 0 {
+		return formatPreviewError("Error reading archive:\n" + err.Error()), true
+	}
+
+	fieldStyle := func(field string) lipgloss.Style {
+		return lipgloss.NewStyle().Foreground(lipgloss.Color(theme.FieldColors[field]))
+	}
+	sizeStyle := fieldStyle("size")
+	timeStyle := fieldStyle("time")
+	dirStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.FileTypeColors["directory"]))
+	linkStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(theme.FileTypeColors["symlink"]))
+
+	var total, packed int64
+	for _, e := range listing.Entries {
+		total += e.Size
+		if e.CompressedSize > 0 {
+			packed += e.CompressedSize
+		}
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Archive (%s): %d entries, %s", listing.Format, len(listing.Entries), filesystem.FormatSize(total))
+	if packed > 0 && listing.Format == filesystem.ArchiveZip {
+		fmt.Fprintf(&b, " (%s compressed)", filesystem.FormatSize(packed))
+	}
+	b.WriteString("\n\n")
+
+	fmt.Fprintf(&b, "%-10s %6s %6s %-12s %s\n", "Mode", "Size", "Packed", "Modified", "Name")
+
+	for _, e := range listing.Entries {
+		if ctx.Err() != nil {
+			return "", true
+		}
+
+		packedSize := "-"
+		if e.CompressedSize >= 0 && !e.IsDir {
+			packedSize = filesystem.FormatSize(e.CompressedSize)
+		}
+
+		modified := "-"
+		if !e.ModTime.IsZero() {
+			modified = filesystem.FormatDateModified(e.ModTime)
+		}
+
+		name := e.Name
+		switch {
+		case e.IsDir:
+			name = dirStyle.Render(name)
+		case e.LinkTarget != "":
+			name = linkStyle.Render(name) + " -> " + e.LinkTarget
+		}
+
+		fmt.Fprintf(&b, "%-10s %s %6s %s %s\n",
+			filesystem.FormatPermissions(e.Mode),
+			sizeStyle.Render(fmt.Sprintf("%6s", filesystem.FormatSize(e.Size))),
+			packedSize,
+			timeStyle.Render(fmt.Sprintf("%-12s", modified)),
+			name,
+		)
+	}
+
+	if listing.Truncated {
+		fmt.Fprintf(&b, "\n… listing truncated after %d entries\n", filesystem.MaxArchiveEntries)
+	}
+	if err != nil {
+		fmt.Fprintf(&b, "\n%s\n", formatPreviewError("Error reading archive:\n"+err.Error()))
+	}
+
+	return strings.TrimRight(b.String(), "\n"), true
+}
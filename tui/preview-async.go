@@ -0,0 +1,221 @@
+package tui
+
+import (
+	"context"
+	"os"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/theming"
+)
+
+// previewRequest describes everything needed to render a preview off the UI
+// goroutine. It deliberately holds copies rather than a *Model so the
+// background command never races with the update loop.
+type previewRequest struct {
+	path   string
+	isDir  bool
+	offset int64
+	width  int
+	theme  theming.Theme
+	// columns are the file list columns directory listings are shown with.
+	columns []fileColumn
+
+	// command, when set, is the command a Lua previewer asked to show the
+	// output of.
+	command *command.Preview
+
+	// tab is the preview tab rendered, PreviewTabMetadata or empty for the
+	// file's contents.
+	tab PreviewTab
+}
+
+// previewMsg carries the result of a background preview render back into the
+// update loop. seq identifies the request that produced it so results for a
+// selection the cursor has already left can be dropped. appended marks a
+// further chunk of a preview that is already shown.
+type previewMsg struct {
+	seq      int
+	key      previewKey
+	content  previewContent
+	appended bool
+	err      error
+}
+
+// requestPreview returns a command that renders the preview for path in the
+// background. Cached previews are applied immediately and no command is
+// returned. Any preview still in flight is cancelled.
+func (m *Model) requestPreview(path string, isDir bool) tea.Cmd {
+	return m.sendPreviewRequest(previewRequest{
+		path:  path,
+		isDir: isDir,
+		width: m.viewportWidth,
+		theme: m.theme,
+		// Custom columns call Lua, which cannot run off the UI goroutine.
+		columns: builtinFileColumns(m.columns),
+	})
+}
+
+// sendPreviewRequest does the work of requestPreview for any request.
+func (m *Model) sendPreviewRequest(req previewRequest) tea.Cmd {
+	path := req.path
+	stat := os.Stat
+	if req.tab == PreviewTabMetadata {
+		// Symlinks are described themselves, even when broken.
+		stat = os.Lstat
+	}
+	info, err := stat(path)
+	if err != nil {
+		m.cancelPreview()
+		m.setPreviewText(formatPreviewError("Error reading file:\n" + err.Error()))
+		return nil
+	}
+
+	key := previewKey{
+		path:    path,
+		modTime: info.ModTime().UnixNano(),
+		size:    info.Size(),
+		width:   m.viewportWidth,
+		tab:     req.tab,
+	}
+
+	if content, ok := m.previewCache.Get(key); ok {
+		if key == m.previewShownKey {
+			// Already on screen; keep the scroll position and any chunks
+			// loaded since.
+			return nil
+		}
+		m.cancelPreview()
+		m.previewSource = req
+		m.previewShownKey = key
+		m.setPreviewContent(content)
+		return nil
+	}
+
+	// The same preview is already being rendered; let it finish.
+	if m.previewCancel != nil && m.previewPendingKey == key {
+		return nil
+	}
+
+	shown := key == m.previewShownKey
+	m.cancelPreview()
+	m.previewSource = req
+	m.previewShownKey = previewKey{}
+
+	// Clear textual content so the previous file's preview is not shown while
+	// the new one is loading.
+	if !shown {
+		m.setPreviewText("")
+	}
+
+	return m.startPreview(key, req, false)
+}
+
+// loadMorePreview returns a command that loads the next chunk of the preview
+// currently shown, or nil if it is complete or a chunk is already loading.
+func (m *Model) loadMorePreview() tea.Cmd {
+	if !m.preview.more || m.previewCancel != nil || m.previewShownKey == (previewKey{}) {
+		return nil
+	}
+
+	req := m.previewSource
+	req.offset = m.preview.offset
+	return m.startPreview(m.previewShownKey, req, true)
+}
+
+// startPreview launches the background render for req under the current
+// sequence number.
+func (m *Model) startPreview(key previewKey, req previewRequest, appended bool) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.previewCancel = cancel
+	m.previewPendingKey = key
+	seq := m.previewSeq
+
+	return func() tea.Msg {
+		content := renderPreview(ctx, req)
+		return previewMsg{seq: seq, key: key, content: content, appended: appended, err: ctx.Err()}
+	}
+}
+
+// cancelPreview aborts the preview currently being rendered, if any, and
+// invalidates its result so it is ignored when it arrives.
+func (m *Model) cancelPreview() {
+	if m.previewCancel != nil {
+		m.previewCancel()
+		m.previewCancel = nil
+	}
+	m.previewPendingKey = previewKey{}
+	m.previewShownKey = previewKey{}
+	m.previewSeq++
+}
+
+// handlePreviewMsg applies a finished background preview. Stale or cancelled
+// results are dropped; successful ones are cached. When the user is reading
+// the preview and has already scrolled near the end, the next chunk is
+// requested straight away.
+func (m *Model) handlePreviewMsg(msg previewMsg) tea.Cmd {
+	if msg.seq != m.previewSeq || msg.err != nil {
+		return nil
+	}
+
+	if m.previewCancel != nil {
+		m.previewCancel()
+		m.previewCancel = nil
+	}
+	m.previewPendingKey = previewKey{}
+	m.previewShownKey = msg.key
+
+	if msg.appended {
+		m.appendPreviewContent(msg.content)
+	} else {
+		m.setPreviewContent(msg.content)
+	}
+	// Metadata changes without the modification time (permissions, access
+	// time), so it is read afresh every time.
+	if msg.key.tab != PreviewTabMetadata {
+		m.previewCache.Add(msg.key, m.preview)
+	}
+
+	if ActiveTuiMode == TuiModePreview && m.previewNeedsMore() {
+		return m.loadMorePreview()
+	}
+	return nil
+}
+
+// renderPreview produces the preview for req. It runs inside a tea.Cmd and
+// must only touch the data carried by the request.
+func renderPreview(ctx context.Context, req previewRequest) previewContent {
+	if req.tab == PreviewTabMetadata {
+		return newPreviewContent(renderMetadataPanel(req.path))
+	}
+	if req.command != nil {
+		return renderCommandPreview(ctx, req.path, *req.command)
+	}
+	if req.isDir {
+		return newPreviewContent(previewDirectory(ctx, req.path, req.theme, req.columns, req.width))
+	}
+	// Documents, archives, structured data and Markdown are rendered in full
+	// in the first chunk. Office documents are zip files, so they are checked
+	// before archives.
+	if req.offset == 0 {
+		if content, ok := renderDocumentPreview(ctx, req.path, req.width, req.theme); ok {
+			return content
+		}
+		if listing, ok := renderArchivePreview(ctx, req.path, req.theme); ok {
+			return newPreviewContent(listing)
+		}
+		if content, ok := renderStructuredPreview(ctx, req.path, req.theme); ok {
+			return content
+		}
+		if content, ok := renderMarkdownPreview(ctx, req.path, req.width, req.theme); ok {
+			return content
+		}
+	}
+	// isTextFile only inspects the start of the file, so every chunk of a
+	// file is rendered the same way.
+	if isTextFile(req.path) {
+		return renderTextPreview(ctx, req.path, req.offset, req.theme)
+	}
+	return renderHexPreview(ctx, req.path, req.offset, req.width, req.theme)
+}
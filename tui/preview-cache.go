@@ -0,0 +1,81 @@
+package tui
+
+import (
+	"container/list"
+)
+
+// previewCacheSize is the number of rendered previews kept in memory.
+const previewCacheSize = 128
+
+// previewKey identifies a rendered preview. Any change to the file (modified
+// time or size) or to the width of the preview pane yields a new key, so stale
+// entries are never served.
+type previewKey struct {
+	path    string
+	modTime int64
+	size    int64
+	width   int
+	tab     PreviewTab
+}
+
+type previewCacheEntry struct {
+	key     previewKey
+	content previewContent
+}
+
+// previewCache is a small LRU cache of rendered previews. It is only accessed
+// from the Bubble Tea update loop, so it does not need any locking.
+type previewCache struct {
+	capacity int
+	order    *list.List
+	items    map[previewKey]*list.Element
+}
+
+// newPreviewCache creates an empty cache holding at most capacity entries.
+func newPreviewCache(capacity int) *previewCache {
+	if capacity <= 0 {
+		capacity = previewCacheSize
+	}
+	return &previewCache{
+		capacity: capacity,
+		order:    list.New(),
+		items:    map[previewKey]*list.Element{},
+	}
+}
+
+// Get returns the cached content for key and marks it as recently used.
+func (c *previewCache) Get(key previewKey) (previewContent, bool) {
+	if c == nil {
+		return previewContent{}, false
+	}
+	el, ok := c.items[key]
+	if !ok {
+		return previewContent{}, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*previewCacheEntry).content, true
+}
+
+// Add stores content for key, evicting the least recently used entry when the
+// cache is full.
+func (c *previewCache) Add(key previewKey, content previewContent) {
+	if c == nil {
+		return
+	}
+	if el, ok := c.items[key]; ok {
+		el.Value.(*previewCacheEntry).content = content
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&previewCacheEntry{key: key, content: content})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*previewCacheEntry).key)
+	}
+}
@@ -0,0 +1,555 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"charm.land/lipgloss/v2"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"cute/theming"
+)
+
+// structuredPreviewMaxBytes is the largest data file parsed into a tree or
+// table. Larger files use the plain text preview, which is loaded in chunks.
+const structuredPreviewMaxBytes = 2 * 1024 * 1024
+
+// dataTreeMaxDepth guards against deeply nested documents and YAML alias
+// cycles.
+const dataTreeMaxDepth = 256
+
+// dataTreeMaxNodes bounds the nodes built from a YAML document, whose aliases
+// can expand a small file into an exponentially large tree. Larger documents
+// use the text preview.
+const dataTreeMaxNodes = 100000
+
+// dataKind is the kind of a node in a structured data tree.
+type dataKind int
+
+const (
+	dataScalar dataKind = iota
+	dataObject
+	dataArray
+)
+
+// dataNode is a node of a parsed JSON, YAML or TOML document. Object members
+// keep the order in which they appear in the file.
+type dataNode struct {
+	key       string
+	kind      dataKind
+	value     string // rendered scalar, styled
+	children  []*dataNode
+	parent    *dataNode
+	collapsed bool
+}
+
+// dataTree is a document shown as a collapsible tree in the preview. lines
+// maps each rendered line back to its node, and cursor is the selected line.
+type dataTree struct {
+	root   *dataNode
+	lines  []*dataNode
+	cursor int
+	styles dataStyles
+}
+
+// dataStyles resolves the theme's syntax specs used by data previews.
+type dataStyles struct {
+	key         lipgloss.Style
+	str         lipgloss.Style
+	number      lipgloss.Style
+	constant    lipgloss.Style
+	punctuation lipgloss.Style
+	comment     lipgloss.Style
+	header      lipgloss.Style
+}
+
+func newDataStyles(syntax theming.SyntaxStyle) dataStyles {
+	return dataStyles{
+		key:         theming.StyleFromSpec(syntax.Attribute),
+		str:         theming.StyleFromSpec(syntax.String),
+		number:      theming.StyleFromSpec(syntax.Number),
+		constant:    theming.StyleFromSpec(syntax.Constant),
+		punctuation: theming.StyleFromSpec(syntax.Punctuation),
+		comment:     theming.StyleFromSpec(syntax.Comment),
+		header:      theming.StyleFromSpec(syntax.Keyword),
+	}
+}
+
+// renderStructuredPreview renders JSON, YAML and TOML files as a collapsible
+// tree and CSV and TSV files as a table. It reports false for other files,
+// files too large to parse, and files that fail to parse, so the caller can
+// fall back to the text preview.
+func renderStructuredPreview(ctx context.Context, path string, theme theming.Theme) (previewContent, bool) {
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".json", ".geojson", ".yaml", ".yml", ".toml", ".csv", ".tsv":
+	default:
+		return previewContent{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > structuredPreviewMaxBytes {
+		return previewContent{}, false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return previewContent{}, false
+	}
+
+	styles := newDataStyles(theme.Syntax)
+
+	if ext == ".csv" || ext == ".tsv" {
+		comma := ','
+		if ext == ".tsv" {
+			comma = '\t'
+		}
+		table, err := renderDataTable(ctx, data, comma, styles)
+		if err != nil {
+			return previewContent{}, false
+		}
+		return newPreviewContent(table), true
+	}
+
+	var root *dataNode
+	switch ext {
+	case ".json", ".geojson":
+		root, err = parseJSONTree(data, styles)
+	case ".yaml", ".yml":
+		root, err = parseYAMLTree(ctx, data, styles)
+	case ".toml":
+		root, err = parseTOMLTree(data, styles)
+	}
+	if err != nil || ctx.Err() != nil {
+		return previewContent{}, false
+	}
+
+	tree := &dataTree{root: root, styles: styles}
+	return previewContent{lines: tree.render(), tree: tree}, true
+}
+
+// render lays out the visible nodes of the tree and returns one line per node.
+func (t *dataTree) render() []string {
+	t.lines = t.lines[:0]
+
+	var lines []string
+	var walk func(n *dataNode, depth int)
+	walk = func(n *dataNode, depth int) {
+		t.lines = append(t.lines, n)
+		lines = append(lines, t.renderNode(n, depth))
+
+		if n.kind != dataScalar && !n.collapsed {
+			for _, child := range n.children {
+				walk(child, depth+1)
+			}
+		}
+	}
+	walk(t.root, 0)
+
+	t.cursor = min(t.cursor, len(t.lines)-1)
+	return lines
+}
+
+// renderNode renders a single line of the tree: a fold marker for containers,
+// the member key and either the scalar value or a summary of the children.
+func (t *dataTree) renderNode(n *dataNode, depth int) string {
+	s := t.styles
+	var b strings.Builder
+
+	b.WriteString(strings.Repeat("  ", depth))
+
+	switch {
+	case n.kind == dataScalar, len(n.children) == 0:
+		b.WriteString("  ")
+	case n.collapsed:
+		b.WriteString(s.punctuation.Render("▸ "))
+	default:
+		b.WriteString(s.punctuation.Render("▾ "))
+	}
+
+	if n.key != "" {
+		b.WriteString(s.key.Render(n.key))
+		b.WriteString(s.punctuation.Render(": "))
+	}
+
+	switch n.kind {
+	case dataScalar:
+		b.WriteString(n.value)
+	case dataObject:
+		b.WriteString(s.punctuation.Render("{}"))
+		b.WriteString(s.comment.Render(" " + plural(len(n.children), "key")))
+	case dataArray:
+		b.WriteString(s.punctuation.Render("[]"))
+		b.WriteString(s.comment.Render(" " + plural(len(n.children), "item")))
+	}
+
+	return b.String()
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// selected returns the node under the cursor.
+func (t *dataTree) selected() *dataNode {
+	if t.cursor < 0 || t.cursor >= len(t.lines) {
+		return nil
+	}
+	return t.lines[t.cursor]
+}
+
+// toggle expands or collapses the node under the cursor.
+func (t *dataTree) toggle() bool {
+	n := t.selected()
+	if n == nil || n.kind == dataScalar || len(n.children) == 0 {
+		return false
+	}
+	n.collapsed = !n.collapsed
+	return true
+}
+
+// collapse folds the node under the cursor, or moves the cursor to its parent
+// when it is already folded or is a scalar.
+func (t *dataTree) collapse() bool {
+	n := t.selected()
+	if n == nil {
+		return false
+	}
+	if n.kind != dataScalar && !n.collapsed && len(n.children) > 0 {
+		n.collapsed = true
+		return true
+	}
+	if n.parent != nil {
+		t.cursor = slices.Index(t.lines, n.parent)
+		return true
+	}
+	return false
+}
+
+// expand unfolds the node under the cursor.
+func (t *dataTree) expand() bool {
+	n := t.selected()
+	if n == nil || n.kind == dataScalar || !n.collapsed {
+		return false
+	}
+	n.collapsed = false
+	return true
+}
+
+// newScalarNode creates a leaf node with a value styled by its type.
+func newScalarNode(key string, value any, styles dataStyles) *dataNode {
+	var rendered string
+	switch v := value.(type) {
+	case nil:
+		rendered = styles.constant.Render("null")
+	case bool:
+		rendered = styles.constant.Render(strconv.FormatBool(v))
+	case json.Number:
+		rendered = styles.number.Render(v.String())
+	case int64:
+		rendered = styles.number.Render(strconv.FormatInt(v, 10))
+	case float64:
+		rendered = styles.number.Render(strconv.FormatFloat(v, 'g', -1, 64))
+	case string:
+		rendered = styles.str.Render(strconv.Quote(v))
+	default:
+		rendered = styles.constant.Render(fmt.Sprint(v))
+	}
+	return &dataNode{key: key, kind: dataScalar, value: rendered}
+}
+
+// addChild appends child to n and links it back to its parent.
+func (n *dataNode) addChild(child *dataNode) {
+	child.parent = n
+	n.children = append(n.children, child)
+}
+
+// parseJSONTree parses a JSON document, keeping the order of object members.
+func parseJSONTree(data []byte, styles dataStyles) (*dataNode, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	root, err := parseJSONValue(dec, "", styles, 0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after JSON value")
+	}
+	return root, nil
+}
+
+func parseJSONValue(dec *json.Decoder, key string, styles dataStyles, depth int) (*dataNode, error) {
+	if depth > dataTreeMaxDepth {
+		return nil, errors.New("document nested too deeply")
+	}
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return newScalarNode(key, tok, styles), nil
+	}
+
+	switch delim {
+	case '{':
+		n := &dataNode{key: key, kind: dataObject}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			name, _ := tok.(string)
+			child, err := parseJSONValue(dec, name, styles, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		_, err := dec.Token() // '}'
+		return n, err
+	case '[':
+		n := &dataNode{key: key, kind: dataArray}
+		for i := 0; dec.More(); i++ {
+			child, err := parseJSONValue(dec, strconv.Itoa(i), styles, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		_, err := dec.Token() // ']'
+		return n, err
+	default:
+		return nil, fmt.Errorf("unexpected %q", delim)
+	}
+}
+
+// parseYAMLTree parses one or more YAML documents. A stream of several
+// documents is shown as an array of documents.
+func parseYAMLTree(ctx context.Context, data []byte, styles dataStyles) (*dataNode, error) {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	b := &yamlTreeBuilder{ctx: ctx, styles: styles}
+
+	var docs []*dataNode
+	for {
+		var doc yaml.Node
+		err := dec.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		n, err := b.node(&doc, "", 0)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, n)
+	}
+
+	switch len(docs) {
+	case 0:
+		return newScalarNode("", nil, styles), nil
+	case 1:
+		return docs[0], nil
+	}
+
+	root := &dataNode{kind: dataArray}
+	for i, doc := range docs {
+		doc.key = fmt.Sprintf("--- %d", i+1)
+		root.addChild(doc)
+	}
+	return root, nil
+}
+
+// yamlTreeBuilder converts YAML nodes to a data tree, expanding each alias
+// where it is used, within dataTreeMaxNodes.
+type yamlTreeBuilder struct {
+	ctx    context.Context
+	styles dataStyles
+	nodes  int
+}
+
+func (b *yamlTreeBuilder) node(y *yaml.Node, key string, depth int) (*dataNode, error) {
+	if depth > dataTreeMaxDepth {
+		return nil, errors.New("document nested too deeply")
+	}
+	b.nodes++
+	if b.nodes > dataTreeMaxNodes {
+		return nil, errors.New("document expands to too many nodes")
+	}
+	if b.nodes%1024 == 0 {
+		if err := b.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+	styles := b.styles
+
+	switch y.Kind {
+	case yaml.DocumentNode:
+		if len(y.Content) == 0 {
+			return newScalarNode(key, nil, styles), nil
+		}
+		return b.node(y.Content[0], key, depth+1)
+
+	case yaml.AliasNode:
+		return b.node(y.Alias, key, depth+1)
+
+	case yaml.MappingNode:
+		n := &dataNode{key: key, kind: dataObject}
+		for i := 0; i+1 < len(y.Content); i += 2 {
+			child, err := b.node(y.Content[i+1], y.Content[i].Value, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		return n, nil
+
+	case yaml.SequenceNode:
+		n := &dataNode{key: key, kind: dataArray}
+		for i, item := range y.Content {
+			child, err := b.node(item, strconv.Itoa(i), depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		return n, nil
+
+	default:
+		var value any
+		switch y.ShortTag() {
+		case "!!null":
+			value = nil
+		case "!!bool", "!!int", "!!float":
+			// Keep the literal as written (e.g. 0x1F, 1e3) but color it as a
+			// number or constant.
+			if err := y.Decode(&value); err != nil {
+				value = y.Value
+			} else if _, isBool := value.(bool); !isBool {
+				return &dataNode{key: key, kind: dataScalar, value: styles.number.Render(y.Value)}, nil
+			}
+		default:
+			value = y.Value
+		}
+		return newScalarNode(key, value, styles), nil
+	}
+}
+
+// parseTOMLTree parses a TOML document. Members are ordered as they appear in
+// the file.
+func parseTOMLTree(data []byte, styles dataStyles) (*dataNode, error) {
+	var doc map[string]any
+	md, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record where each key path first appears so tables can be listed in
+	// file order rather than map order.
+	order := map[string]int{}
+	for i, key := range md.Keys() {
+		path := key.String()
+		if _, ok := order[path]; !ok {
+			order[path] = i
+		}
+	}
+
+	return tomlToNode(doc, "", "", order, styles, 0)
+}
+
+func tomlToNode(value any, key, path string, order map[string]int, styles dataStyles, depth int) (*dataNode, error) {
+	if depth > dataTreeMaxDepth {
+		return nil, errors.New("document nested too deeply")
+	}
+
+	switch v := value.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		pos := func(k string) int {
+			if i, ok := order[joinTOMLPath(path, k)]; ok {
+				return i
+			}
+			return len(order)
+		}
+		slices.SortStableFunc(keys, func(a, b string) int {
+			if d := pos(a) - pos(b); d != 0 {
+				return d
+			}
+			return strings.Compare(a, b)
+		})
+
+		n := &dataNode{key: key, kind: dataObject}
+		for _, k := range keys {
+			child, err := tomlToNode(v[k], k, joinTOMLPath(path, k), order, styles, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		return n, nil
+
+	case []map[string]any:
+		n := &dataNode{key: key, kind: dataArray}
+		for i, item := range v {
+			child, err := tomlToNode(item, strconv.Itoa(i), path, order, styles, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		return n, nil
+
+	case []any:
+		n := &dataNode{key: key, kind: dataArray}
+		for i, item := range v {
+			child, err := tomlToNode(item, strconv.Itoa(i), path, order, styles, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			n.addChild(child)
+		}
+		return n, nil
+
+	case time.Time:
+		return &dataNode{key: key, kind: dataScalar, value: styles.constant.Render(v.Format(time.RFC3339Nano))}, nil
+
+	case fmt.Stringer:
+		// Local dates and times.
+		return &dataNode{key: key, kind: dataScalar, value: styles.constant.Render(v.String())}, nil
+
+	default:
+		return newScalarNode(key, v, styles), nil
+	}
+}
+
+// joinTOMLPath joins a key onto a dotted TOML key path, quoting it the way
+// toml.Key.String does when needed.
+func joinTOMLPath(path, key string) string {
+	k := toml.Key{key}.String()
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cute/theming"
+)
+
+// billionLaughs returns a YAML document of a few hundred bytes whose aliases
+// expand to 9^levels nodes.
+func billionLaughs(levels int) string {
+	var b strings.Builder
+	b.WriteString("a0: &a0 [x, x, x, x, x, x, x, x, x]\n")
+	for i := 1; i <= levels; i++ {
+		prev := fmt.Sprintf("*a%d", i-1)
+		fmt.Fprintf(&b, "a%d: &a%d [%s]\n", i, i, strings.TrimSuffix(strings.Repeat(prev+", ", 9), ", "))
+	}
+	return b.String()
+}
+
+func TestRenderStructuredPreviewYAMLAliases(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		ok   bool
+	}{
+		{"shared mapping", "a: &a {x: 1}\nb: *a\n", true},
+		{"small expansion", billionLaughs(3), true},
+		{"billion laughs", billionLaughs(10), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "data.yaml")
+			if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			content, ok := renderStructuredPreview(context.Background(), path, theming.DefaultTheme())
+			if ok != tt.ok {
+				t.Fatalf("renderStructuredPreview() ok = %t, want %t", ok, tt.ok)
+			}
+			if ok && content.tree == nil {
+				t.Error("no data tree")
+			}
+		})
+	}
+}
+
+func TestParseYAMLTreeCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	styles := newDataStyles(theming.DefaultTheme().Syntax)
+	if _, err := parseYAMLTree(ctx, []byte(billionLaughs(5)), styles); err == nil {
+		t.Fatal("expected an error from a cancelled context")
+	}
+}
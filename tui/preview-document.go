@@ -0,0 +1,113 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+
+	"cute/filesystem"
+	"cute/theming"
+)
+
+// documentFormatNames are the names shown in the document preview header.
+var documentFormatNames = map[string]string{
+	filesystem.DocumentPDF:  "PDF document",
+	filesystem.DocumentDocx: "Word document",
+	filesystem.DocumentOdt:  "OpenDocument text",
+	filesystem.DocumentXlsx: "Excel workbook",
+}
+
+// renderDocumentPreview shows the page count, metadata and text of the first
+// pages of PDF, docx, odt and xlsx files, with paragraphs wrapped to the
+// inner width of the preview pane and spreadsheets laid out as tables. It
+// reports false for other files, and for office files that cannot be read,
+// which are then listed as the zip archives they are.
+func renderDocumentPreview(ctx context.Context, path string, width int, theme theming.Theme) (previewContent, bool) {
+	format := filesystem.DetectDocument(path)
+	if format == "" {
+		return previewContent{}, false
+	}
+
+	doc, err := filesystem.ReadDocument(ctx, path)
+	if ctx.Err() != nil {
+		return previewContent{}, false
+	}
+	if err != nil {
+		if format != filesystem.DocumentPDF || errors.Is(err, filesystem.ErrNotDocument) {
+			return previewContent{}, false
+		}
+		return newPreviewContent(formatPreviewError("Error reading document:\n" + err.Error())), true
+	}
+
+	heading := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Primary))
+	label := theming.StyleFromSpec(theme.Syntax.Keyword)
+	muted := theming.StyleFromSpec(theme.Syntax.Comment)
+	data := newDataStyles(theme.Syntax)
+
+	// Leave room for the preview border, as the directory preview does.
+	width = max(width-2, 10)
+
+	title := documentFormatNames[format]
+	switch {
+	case format == filesystem.DocumentXlsx && doc.Pages > 0:
+		title += " · " + plural(doc.Pages, "sheet")
+	case doc.Pages > 0:
+		title += " · " + plural(doc.Pages, "page")
+	}
+
+	lines := []string{heading.Render(title)}
+	for _, field := range doc.Metadata {
+		line := label.Render(field.Name+":") + " " + field.Value
+		lines = append(lines, strings.Split(ansi.Wrap(line, width, ""), "\n")...)
+	}
+	lines = append(lines, muted.Render(strings.Repeat("─", width)))
+
+	// Tabs separate table cells, and would otherwise have no width.
+	tabs := strings.NewReplacer("\t", "    ")
+	for _, line := range doc.Text {
+		if line == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, strings.Split(ansi.Wrap(tabs.Replace(line), width, ""), "\n")...)
+	}
+
+	for i, sheet := range doc.Sheets {
+		if i > 0 || len(doc.Text) > 0 {
+			lines = append(lines, "")
+		}
+		lines = append(lines, heading.Render(sheet.Name))
+		if len(sheet.Rows) == 0 {
+			lines = append(lines, muted.Render("(empty sheet)"))
+			continue
+		}
+		lines = append(lines, strings.Split(formatTable(sheet.Rows, data), "\n")...)
+		if sheet.MoreColumns > 0 {
+			lines = append(lines, muted.Render(fmt.Sprintf("… %d more columns", sheet.MoreColumns)))
+		}
+		if sheet.Truncated {
+			lines = append(lines, muted.Render(fmt.Sprintf("… showing the first %d rows", filesystem.MaxDocumentSheetRows)))
+		}
+	}
+
+	if len(doc.Text) == 0 && len(doc.Sheets) == 0 {
+		lines = append(lines, muted.Render("(no text found)"))
+	}
+
+	if doc.Truncated {
+		note := "… showing the beginning of the document"
+		switch {
+		case format == filesystem.DocumentPDF && doc.Pages > filesystem.MaxDocumentPages:
+			note = fmt.Sprintf("… showing the first %d of %d pages", filesystem.MaxDocumentPages, doc.Pages)
+		case format == filesystem.DocumentXlsx && doc.Pages > filesystem.MaxDocumentSheets:
+			note = fmt.Sprintf("… showing the first %d of %d sheets", filesystem.MaxDocumentSheets, doc.Pages)
+		}
+		lines = append(lines, "", muted.Render(note))
+	}
+
+	return previewContent{lines: lines}, true
+}
@@ -0,0 +1,108 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/h2non/bimg"
+
+	"cute/filesystem"
+)
+
+// renderImageMetadata renders the image section of the Metadata tab:
+// dimensions and color space from libvips, followed by the camera, lens,
+// exposure, GPS and orientation details from the EXIF data.
+func renderImageMetadata(path string) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "\nImage\n\n")
+
+	// libvips only needs the header, but bimg works on an in-memory buffer,
+	// so skip very large files as the image preview does.
+	if info, err := os.Stat(path); err == nil && info.Size() <= maxImagePreviewBytes {
+		if data, err := os.ReadFile(path); err == nil {
+			if md, err := bimg.Metadata(data); err == nil {
+				fmt.Fprintf(&b, "Format: %s\n", md.Type)
+				fmt.Fprintf(&b, "Dimensions: %d × %d\n", md.Size.Width, md.Size.Height)
+				fmt.Fprintf(&b, "Color space: %s\n", md.Space)
+				fmt.Fprintf(&b, "Channels: %d\n", md.Channels)
+				fmt.Fprintf(&b, "Alpha: %t\n", md.Alpha)
+				fmt.Fprintf(&b, "ICC profile: %t\n", md.Profile)
+			}
+		}
+	}
+
+	x, err := filesystem.ReadEXIF(path)
+	if err != nil {
+		fmt.Fprintf(&b, "\nNo EXIF data.\n")
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "\nEXIF\n\n")
+
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Fprintf(&b, "%s: %s\n", label, value)
+		}
+	}
+
+	field("Camera", strings.TrimSpace(cameraName(x.Make, x.Model)))
+	field("Lens", strings.TrimSpace(cameraName(x.LensMake, x.LensModel)))
+	field("Taken", x.DateTime)
+	field("Software", x.Software)
+
+	if x.Width > 0 && x.Height > 0 {
+		field("Pixel dimensions", fmt.Sprintf("%d × %d", x.Width, x.Height))
+	}
+	field("Color space", x.ColorSpace)
+
+	if x.ExposureTime != "" {
+		field("Exposure", x.ExposureTime+" s")
+	}
+	if x.FNumber > 0 {
+		field("Aperture", fmt.Sprintf("f/%.1f", x.FNumber))
+	}
+	if x.ISO > 0 {
+		field("ISO", fmt.Sprint(x.ISO))
+	}
+	if x.HasExposureBias {
+		field("Exposure bias", fmt.Sprintf("%+.1f EV", x.ExposureBias))
+	}
+	if x.FocalLength > 0 {
+		focal := fmt.Sprintf("%g mm", x.FocalLength)
+		if x.FocalLength35 > 0 {
+			focal += fmt.Sprintf(" (%d mm in 35 mm)", x.FocalLength35)
+		}
+		field("Focal length", focal)
+	}
+	if x.HasFlash {
+		flash := "Did not fire"
+		if x.FlashFired {
+			flash = "Fired"
+		}
+		field("Flash", flash)
+	}
+
+	if x.Orientation > 0 {
+		field("Orientation", fmt.Sprintf("%s (%d)", filesystem.OrientationName(x.Orientation), x.Orientation))
+	}
+
+	if x.HasGPS {
+		field("GPS", fmt.Sprintf("%.6f, %.6f", x.Latitude, x.Longitude))
+		if x.HasAltitude {
+			field("Altitude", fmt.Sprintf("%.1f m", x.Altitude))
+		}
+	}
+
+	return b.String()
+}
+
+// cameraName joins a maker and model, dropping the maker when the model
+// already starts with it (e.g. "Canon" and "Canon EOS R5").
+func cameraName(maker, model string) string {
+	if maker == "" || strings.HasPrefix(strings.ToLower(model), strings.ToLower(maker)) {
+		return model
+	}
+	return maker + " " + model
+}
@@ -0,0 +1,171 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+
+	"cute/theming"
+)
+
+// hexPreviewChunkBytes is how much of a binary file is dumped at once. Further
+// chunks are loaded as the preview is scrolled, so large files are never read
+// fully into memory.
+const hexPreviewChunkBytes = 64 * 1024
+
+// hexPreviewRowBytes lists the supported numbers of bytes per row, widest
+// first. The widest layout that fits the preview pane is used.
+var hexPreviewRowBytes = []int{16, 8, 4}
+
+// byteClass groups bytes by how they are colored in the hex dump.
+type byteClass int
+
+const (
+	byteNull byteClass = iota
+	bytePrintable
+	byteControl
+	byteExtended
+)
+
+func classifyByte(c byte) byteClass {
+	switch {
+	case c == 0:
+		return byteNull
+	case c >= 0x20 && c < 0x7f:
+		return bytePrintable
+	case c < 0x80:
+		return byteControl
+	default:
+		return byteExtended
+	}
+}
+
+// hexStyles resolves the theme's hex specs into lipgloss styles.
+type hexStyles struct {
+	offset  lipgloss.Style
+	classes [4]lipgloss.Style
+}
+
+func newHexStyles(hex theming.HexStyle) hexStyles {
+	return hexStyles{
+		offset: theming.StyleFromSpec(hex.Offset),
+		classes: [4]lipgloss.Style{
+			byteNull:      theming.StyleFromSpec(hex.Null),
+			bytePrintable: theming.StyleFromSpec(hex.Printable),
+			byteControl:   theming.StyleFromSpec(hex.Control),
+			byteExtended:  theming.StyleFromSpec(hex.Extended),
+		},
+	}
+}
+
+// hexRowWidth returns the width in cells of a hex dump row holding n bytes:
+// an 8 digit offset, the hex bytes in groups of eight and the ASCII column.
+func hexRowWidth(n int) int {
+	groups := (n + 7) / 8
+	return 8 + 2 + (n*3 - 1) + (groups - 1) + 2 + n + 1
+}
+
+// hexBytesPerRow picks the number of bytes per row for a preview pane width.
+func hexBytesPerRow(width int) int {
+	for _, n := range hexPreviewRowBytes {
+		if hexRowWidth(n) <= width {
+			return n
+		}
+	}
+	return hexPreviewRowBytes[len(hexPreviewRowBytes)-1]
+}
+
+// renderHexPreview dumps one chunk of a binary file starting at offset as
+// offset, hex and ASCII columns, colored by the theme's hex styles.
+func renderHexPreview(ctx context.Context, path string, offset int64, width int, theme theming.Theme) previewContent {
+	f, err := os.Open(path)
+	if err != nil {
+		return newPreviewContent(formatPreviewError("Error opening file:\n" + err.Error()))
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return newPreviewContent(formatPreviewError("Error reading file:\n" + err.Error()))
+	}
+
+	buf := make([]byte, min(hexPreviewChunkBytes, max(info.Size()-offset, 0)))
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return newPreviewContent(formatPreviewError("Error reading file:\n" + err.Error()))
+	}
+	buf = buf[:n]
+
+	styles := newHexStyles(theme.Hex)
+	perRow := hexBytesPerRow(width)
+
+	lines := make([]string, 0, (len(buf)+perRow-1)/perRow)
+	for start := 0; start < len(buf); start += perRow {
+		if ctx.Err() != nil {
+			return previewContent{}
+		}
+		end := min(start+perRow, len(buf))
+		lines = append(lines, formatHexRow(offset+int64(start), buf[start:end], perRow, styles))
+	}
+
+	next := offset + int64(len(buf))
+	return previewContent{lines: lines, offset: next, more: next < info.Size()}
+}
+
+// formatHexRow formats a single row of the hex dump. Runs of bytes of the
+// same class are styled together to keep the number of escape sequences low.
+func formatHexRow(offset int64, row []byte, perRow int, styles hexStyles) string {
+	var hexCol, asciiCol strings.Builder
+
+	for i := 0; i < len(row); {
+		class := classifyByte(row[i])
+		j := i
+		for j < len(row) && classifyByte(row[j]) == class {
+			j++
+		}
+
+		var hexRun, asciiRun strings.Builder
+		for k := i; k < j; k++ {
+			if k > i {
+				hexRun.WriteByte(' ')
+				if k%8 == 0 {
+					hexRun.WriteByte(' ')
+				}
+			}
+			fmt.Fprintf(&hexRun, "%02x", row[k])
+
+			if class == bytePrintable {
+				asciiRun.WriteByte(row[k])
+			} else {
+				asciiRun.WriteByte('.')
+			}
+		}
+
+		// The separator before a run stays unstyled.
+		if i > 0 {
+			hexCol.WriteByte(' ')
+			if i%8 == 0 {
+				hexCol.WriteByte(' ')
+			}
+		}
+		hexCol.WriteString(styles.classes[class].Render(hexRun.String()))
+		asciiCol.WriteString(styles.classes[class].Render(asciiRun.String()))
+
+		i = j
+	}
+
+	// Pad a short final row so the ASCII column stays aligned.
+	pad := hexRowWidth(perRow) - hexRowWidth(len(row))
+	pad -= perRow - len(row) // the ASCII column itself is not padded
+
+	return fmt.Sprintf("%s  %s%s |%s|",
+		styles.offset.Render(fmt.Sprintf("%08x", offset)),
+		hexCol.String(),
+		strings.Repeat(" ", pad),
+		asciiCol.String(),
+	)
+}
@@ -0,0 +1,311 @@
+package tui
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+
+	"cute/theming"
+)
+
+// modelineLines is how many lines at the start and end of a file are searched
+// for a vim or emacs modeline, matching vim's default 'modelines' setting.
+const modelineLines = 5
+
+// modelineHeadBytes and modelineTailBytes bound how much of the start and end
+// of a file is read when detecting its language.
+const (
+	modelineHeadBytes = 4096
+	modelineTailBytes = 2048
+)
+
+var (
+	// vimModeline matches "vim: set ft=go:", "vi: filetype=go" and
+	// "ex: syntax=go" style modelines.
+	vimModeline = regexp.MustCompile(`(?:^|\s)(?:vim?|ex):.*?\b(?:ft|filetype|syn|syntax)=([\w+-]+)`)
+
+	// emacsModeline matches "-*- mode: python; coding: utf-8 -*-".
+	emacsModeline = regexp.MustCompile(`-\*-.*?\bmode:\s*([\w+-]+).*?-\*-`)
+
+	// emacsShortModeline matches the short form "-*- python -*-".
+	emacsShortModeline = regexp.MustCompile(`-\*-\s*([\w+-]+)\s*-\*-`)
+)
+
+// shebangLanguages maps interpreters found in a shebang line to lexer names
+// when the interpreter is not itself a lexer alias.
+var shebangLanguages = map[string]string{
+	"ash":       "bash",
+	"dash":      "bash",
+	"ksh":       "bash",
+	"node":      "javascript",
+	"nodejs":    "javascript",
+	"deno":      "typescript",
+	"bun":       "javascript",
+	"pwsh":      "powershell",
+	"osascript": "applescript",
+	"runghc":    "haskell",
+	"tclsh":     "tcl",
+	"wish":      "tcl",
+}
+
+// detectLexer picks a lexer for the file at path. An explicit modeline takes
+// precedence, followed by the file name or extension, the shebang line, and
+// finally chroma's content analysis. It returns nil when the language cannot
+// be determined.
+func detectLexer(path string) chroma.Lexer {
+	head := readFileHead(path)
+
+	if lang := modelineLanguage(head, readFileTail(path)); lang != "" {
+		if l := lexers.Get(lang); l != nil {
+			return l
+		}
+	}
+
+	if l := lexers.Match(filepath.Base(path)); l != nil {
+		return l
+	}
+
+	if interp := shebangInterpreter(head); interp != "" {
+		if lang, ok := shebangLanguages[interp]; ok {
+			interp = lang
+		}
+		if l := lexers.Get(interp); l != nil {
+			return l
+		}
+	}
+
+	return lexers.Analyse(head)
+}
+
+// modelineLanguage returns the language named by a vim or emacs modeline in
+// the first or last few lines of a file.
+func modelineLanguage(head, tail string) string {
+	lines := firstLines(head, modelineLines)
+	lines = append(lines, lastLines(tail, modelineLines)...)
+
+	for _, line := range lines {
+		if match := vimModeline.FindStringSubmatch(line); match != nil {
+			return strings.ToLower(match[1])
+		}
+		if match := emacsModeline.FindStringSubmatch(line); match != nil {
+			return strings.ToLower(match[1])
+		}
+		if match := emacsShortModeline.FindStringSubmatch(line); match != nil {
+			return strings.ToLower(match[1])
+		}
+	}
+	return ""
+}
+
+// shebangInterpreter returns the interpreter named on a "#!" first line, with
+// any "env" indirection and trailing version number removed, e.g.
+// "#!/usr/bin/env python3" yields "python".
+func shebangInterpreter(head string) string {
+	if !strings.HasPrefix(head, "#!") {
+		return ""
+	}
+
+	line, _, _ := strings.Cut(head[2:], "\n")
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interp := filepath.Base(fields[0])
+	if interp == "env" {
+		interp = ""
+		for _, f := range fields[1:] {
+			// Skip env options such as "-S".
+			if strings.HasPrefix(f, "-") {
+				continue
+			}
+			interp = filepath.Base(f)
+			break
+		}
+	}
+
+	return strings.TrimRight(interp, "0123456789.")
+}
+
+// readFileHead returns up to modelineHeadBytes from the start of the file.
+func readFileHead(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	buf := make([]byte, modelineHeadBytes)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+// readFileTail returns up to modelineTailBytes from the end of the file. Errors
+// are ignored; a missing tail simply means no trailing modeline is found.
+func readFileTail(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ""
+	}
+
+	offset := info.Size() - modelineTailBytes
+	if offset < 0 {
+		offset = 0
+	}
+
+	buf := make([]byte, info.Size()-offset)
+	n, err := f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return ""
+	}
+	return string(buf[:n])
+}
+
+// firstLines returns at most n lines from the start of s.
+func firstLines(s string, n int) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	for scanner.Scan() && len(lines) < n {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+// lastLines returns at most n lines from the end of s.
+func lastLines(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// syntaxStyles resolves the theme's syntax specs into lipgloss styles.
+type syntaxStyles struct {
+	attribute   lipgloss.Style
+	builtin     lipgloss.Style
+	comment     lipgloss.Style
+	constant    lipgloss.Style
+	function    lipgloss.Style
+	keyword     lipgloss.Style
+	number      lipgloss.Style
+	operator    lipgloss.Style
+	punctuation lipgloss.Style
+	str         lipgloss.Style
+	tag         lipgloss.Style
+	typ         lipgloss.Style
+}
+
+func newSyntaxStyles(syntax theming.SyntaxStyle) syntaxStyles {
+	return syntaxStyles{
+		attribute:   theming.StyleFromSpec(syntax.Attribute),
+		builtin:     theming.StyleFromSpec(syntax.Builtin),
+		comment:     theming.StyleFromSpec(syntax.Comment),
+		constant:    theming.StyleFromSpec(syntax.Constant),
+		function:    theming.StyleFromSpec(syntax.Function),
+		keyword:     theming.StyleFromSpec(syntax.Keyword),
+		number:      theming.StyleFromSpec(syntax.Number),
+		operator:    theming.StyleFromSpec(syntax.Operator),
+		punctuation: theming.StyleFromSpec(syntax.Punctuation),
+		str:         theming.StyleFromSpec(syntax.String),
+		tag:         theming.StyleFromSpec(syntax.Tag),
+		typ:         theming.StyleFromSpec(syntax.Type),
+	}
+}
+
+// styleFor maps a chroma token type onto one of the theme's syntax styles.
+// The boolean is false for tokens that should be left unstyled.
+func (s syntaxStyles) styleFor(t chroma.TokenType) (lipgloss.Style, bool) {
+	switch {
+	case t == chroma.KeywordType:
+		return s.typ, true
+	case t == chroma.KeywordConstant:
+		return s.constant, true
+	case t.InCategory(chroma.Keyword):
+		return s.keyword, true
+	case t.InCategory(chroma.Comment):
+		return s.comment, true
+	case t.InSubCategory(chroma.LiteralString):
+		return s.str, true
+	case t.InSubCategory(chroma.LiteralNumber):
+		return s.number, true
+	case t.InCategory(chroma.Literal):
+		return s.constant, true
+	case t.InCategory(chroma.Operator):
+		return s.operator, true
+	case t == chroma.Punctuation:
+		return s.punctuation, true
+	case t == chroma.NameFunction, t == chroma.NameFunctionMagic:
+		return s.function, true
+	case t.InSubCategory(chroma.NameBuiltin):
+		return s.builtin, true
+	case t == chroma.NameClass, t == chroma.NameNamespace, t == chroma.NameException:
+		return s.typ, true
+	case t == chroma.NameConstant:
+		return s.constant, true
+	case t == chroma.NameTag:
+		return s.tag, true
+	case t == chroma.NameAttribute, t == chroma.NameDecorator:
+		return s.attribute, true
+	case t == chroma.GenericHeading, t == chroma.GenericSubheading:
+		return s.keyword, true
+	case t == chroma.GenericInserted:
+		return s.str, true
+	case t == chroma.GenericDeleted:
+		return s.tag, true
+	}
+	return lipgloss.Style{}, false
+}
+
+// highlightSource tokenises text with lexer and renders it using the theme's
+// syntax colors. Styling is applied line by line so every line carries its own
+// escape sequences and can be scrolled or cut independently.
+func highlightSource(ctx context.Context, text string, lexer chroma.Lexer, syntax theming.SyntaxStyle) (string, error) {
+	iter, err := chroma.Coalesce(lexer).Tokenise(nil, text)
+	if err != nil {
+		return "", err
+	}
+
+	styles := newSyntaxStyles(syntax)
+
+	var b strings.Builder
+	for _, line := range chroma.SplitTokensIntoLines(iter.Tokens()) {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		for _, tok := range line {
+			style, ok := styles.styleFor(tok.Type)
+			value := tok.Value
+			newline := strings.HasSuffix(value, "\n")
+			value = strings.TrimSuffix(value, "\n")
+
+			if ok && value != "" {
+				b.WriteString(style.Render(value))
+			} else {
+				b.WriteString(value)
+			}
+			if newline {
+				b.WriteByte('\n')
+			}
+		}
+	}
+
+	return b.String(), nil
+}
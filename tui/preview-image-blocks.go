@@ -0,0 +1,139 @@
+package tui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+)
+
+// blockCell describes how a block of pixels is drawn as one terminal cell
+// with a glyph and a foreground and background color.
+type blockCell struct {
+	// cols and rows are the number of image pixels covered by one cell.
+	cols, rows int
+
+	// draw picks the glyph and colors for the pixels of one cell, given
+	// row by row.
+	draw func(px []color.RGBA) (glyph rune, fg, bg color.RGBA)
+}
+
+var (
+	// halfBlockCell draws two vertically stacked pixels per cell using the
+	// upper half block with the foreground and background colors.
+	halfBlockCell = blockCell{cols: 1, rows: 2, draw: drawHalfBlock}
+
+	// brailleCell draws a 2×4 dot pattern per cell, with the brighter dots
+	// in the foreground color and the rest in the background color.
+	brailleCell = blockCell{cols: 2, rows: 4, draw: drawBraille}
+)
+
+// renderBlockImage draws the image with colored Unicode characters as lines
+// for the preview viewport, scaled to fit the preview rectangle.
+func renderBlockImage(req imageRequest, cell blockCell) ([]string, error) {
+	img, _, err := loadThumbnail(req.path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Size the image in cells, taking the shape of a cell into account so the
+	// aspect ratio is preserved. Thumbnails are small, so they are enlarged
+	// to use the whole pane.
+	b := img.Bounds()
+	scale := min(
+		float64(req.rect.width*req.cellWidth)/float64(b.Dx()),
+		float64(req.rect.height*req.cellHeight)/float64(b.Dy()),
+	)
+	cols := min(max(int(float64(b.Dx())*scale/float64(req.cellWidth)+0.5), 1), req.rect.width)
+	rows := min(max(int(float64(b.Dy())*scale/float64(req.cellHeight)+0.5), 1), req.rect.height)
+
+	return drawBlocks(scaleImage(img, cols*cell.cols, rows*cell.rows), cell), nil
+}
+
+// drawBlocks converts img, whose size is a multiple of the cell size, into
+// lines of colored glyphs.
+func drawBlocks(img *image.RGBA, cell blockCell) []string {
+	cols := img.Bounds().Dx() / cell.cols
+	rows := img.Bounds().Dy() / cell.rows
+
+	lines := make([]string, rows)
+	px := make([]color.RGBA, cell.cols*cell.rows)
+
+	for row := range rows {
+		var b strings.Builder
+		var lastFg, lastBg color.RGBA
+		for col := range cols {
+			for y := range cell.rows {
+				for x := range cell.cols {
+					px[y*cell.cols+x] = img.RGBAAt(col*cell.cols+x, row*cell.rows+y)
+				}
+			}
+
+			glyph, fg, bg := cell.draw(px)
+			if col == 0 || fg != lastFg || bg != lastBg {
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%d;48;2;%d;%d;%dm", fg.R, fg.G, fg.B, bg.R, bg.G, bg.B)
+				lastFg, lastBg = fg, bg
+			}
+			b.WriteRune(glyph)
+		}
+		b.WriteString("\x1b[m")
+		lines[row] = b.String()
+	}
+
+	return lines
+}
+
+// drawHalfBlock draws the top pixel in the foreground and the bottom pixel in
+// the background color.
+func drawHalfBlock(px []color.RGBA) (rune, color.RGBA, color.RGBA) {
+	return '▀', px[0], px[1]
+}
+
+// brailleDots maps the pixels of a 2×4 block, row by row, to the dots of a
+// braille pattern.
+var brailleDots = [8]rune{0x01, 0x08, 0x02, 0x10, 0x04, 0x20, 0x40, 0x80}
+
+// drawBraille raises the dots of the pixels brighter than the block's
+// average, in their average color, over the average color of the others.
+func drawBraille(px []color.RGBA) (rune, color.RGBA, color.RGBA) {
+	var mean float64
+	for _, p := range px {
+		mean += luminance(p)
+	}
+	mean /= float64(len(px))
+
+	glyph := rune(0x2800)
+	var on, off []color.RGBA
+	for i, p := range px {
+		if luminance(p) > mean {
+			glyph |= brailleDots[i]
+			on = append(on, p)
+		} else {
+			off = append(off, p)
+		}
+	}
+
+	if len(on) == 0 {
+		// A block of one color has no contrast to show.
+		c := averageColor(off)
+		return ' ', c, c
+	}
+	return glyph, averageColor(on), averageColor(off)
+}
+
+// luminance returns the relative brightness of c.
+func luminance(c color.RGBA) float64 {
+	return 0.2126*float64(c.R) + 0.7152*float64(c.G) + 0.0722*float64(c.B)
+}
+
+// averageColor returns the mean of colors, which must not be empty.
+func averageColor(colors []color.RGBA) color.RGBA {
+	var r, g, b int
+	for _, c := range colors {
+		r += int(c.R)
+		g += int(c.G)
+		b += int(c.B)
+	}
+	n := len(colors)
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 0xff}
+}
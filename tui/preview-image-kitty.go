@@ -0,0 +1,88 @@
+package tui
+
+import (
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/ansi/kitty"
+)
+
+// kittyQueryImageID is the image ID used to ask whether the terminal
+// supports the Kitty graphics protocol. Preview images are numbered after it.
+const kittyQueryImageID = 1
+
+// kittyGraphicsQuery asks the terminal to validate a one pixel image without
+// storing it. Terminals that speak the Kitty graphics protocol answer with
+// OK; the others ignore it.
+func kittyGraphicsQuery(tmux bool) string {
+	opts := kitty.Options{
+		Action:       kitty.Query,
+		ID:           kittyQueryImageID,
+		Format:       kitty.RGB,
+		ImageWidth:   1,
+		ImageHeight:  1,
+		Transmission: kitty.Direct,
+	}
+	return passthrough(ansi.KittyGraphics([]byte("AAAA"), opts.Options()...), tmux)
+}
+
+// renderKittyImage encodes the image with the Kitty graphics protocol,
+// transmitted and placed in one go under req.imageID so it can be deleted on
+// its own later.
+func renderKittyImage(req imageRequest) (string, error) {
+	img, _, err := loadThumbnail(req.path)
+	if err != nil {
+		return "", err
+	}
+
+	b := img.Bounds()
+	cols, rows := req.imageCells(img)
+	opts := kitty.Options{
+		Action:          kitty.TransmitAndPut,
+		Quite:           2,
+		ID:              req.imageID,
+		Format:          kitty.RGB,
+		Transmission:    kitty.Direct,
+		Compression:     kitty.Zlib,
+		ImageWidth:      b.Dx(),
+		ImageHeight:     b.Dy(),
+		Columns:         cols,
+		Rows:            rows,
+		DoNotMoveCursor: true,
+		Chunk:           true,
+	}
+
+	var seq strings.Builder
+	if err := kitty.EncodeGraphics(&chunkWriter{w: &seq, tmux: req.tmux}, img, &opts); err != nil {
+		return "", err
+	}
+	return placeImage(req.rect, seq.String()), nil
+}
+
+// kittyDeleteImage deletes the image with the given ID, along with its data,
+// from the terminal.
+func kittyDeleteImage(id int, tmux bool) string {
+	opts := kitty.Options{
+		Action:          kitty.Delete,
+		Quite:           2,
+		Delete:          kitty.DeleteID,
+		DeleteResources: true,
+		ID:              id,
+	}
+	return passthrough(ansi.KittyGraphics(nil, opts.Options()...), tmux)
+}
+
+// chunkWriter passes each chunk of a Kitty graphics transmission through tmux
+// on its own, which keeps every passthrough sequence small.
+type chunkWriter struct {
+	w    io.Writer
+	tmux bool
+}
+
+func (c *chunkWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, passthrough(string(p), c.tmux)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,189 @@
+package tui
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/ansi/iterm2"
+	"github.com/charmbracelet/x/ansi/sixel"
+)
+
+// imageRequest describes an image preview to render off the UI goroutine.
+type imageRequest struct {
+	seq      int
+	path     string
+	protocol ImageProtocol
+	rect     imageRect
+
+	// imageID identifies the image in the Kitty graphics protocol.
+	imageID int
+
+	// tmux wraps escape sequences so tmux passes them on to the terminal.
+	tmux bool
+
+	// cellWidth and cellHeight are the size of a terminal cell in pixels.
+	cellWidth  int
+	cellHeight int
+}
+
+// renderImagePreview renders the image preview described by req.
+func renderImagePreview(req imageRequest) imagePreviewMsg {
+	msg := imagePreviewMsg{seq: req.seq, path: req.path, protocol: req.protocol, imageID: req.imageID}
+
+	switch req.protocol {
+	case ImageProtocolKitty:
+		msg.sequence, msg.err = renderKittyImage(req)
+	case ImageProtocolSixel:
+		msg.sequence, msg.err = renderSixelImage(req)
+	case ImageProtocolITerm2:
+		msg.sequence, msg.err = renderITerm2Image(req)
+	case ImageProtocolBraille:
+		msg.lines, msg.err = renderBlockImage(req, brailleCell)
+	default:
+		msg.lines, msg.err = renderBlockImage(req, halfBlockCell)
+	}
+
+	return msg
+}
+
+// renderSixelImage encodes the image as Sixel graphics drawn at the top-left
+// corner of the preview rectangle.
+func renderSixelImage(req imageRequest) (string, error) {
+	img, _, err := loadThumbnail(req.path)
+	if err != nil {
+		return "", err
+	}
+
+	// Sixel images are drawn pixel for pixel, so shrink the image to the
+	// preview rectangle or it would spill over the rest of the screen.
+	b := img.Bounds()
+	w, h := fitImage(b.Dx(), b.Dy(), req.rect.width*req.cellWidth, req.rect.height*req.cellHeight)
+	if w != b.Dx() || h != b.Dy() {
+		img = scaleImage(img, w, h)
+	}
+
+	var payload bytes.Buffer
+	var enc sixel.Encoder
+	if err := enc.Encode(&payload, img); err != nil {
+		return "", err
+	}
+
+	// tmux draws Sixel images itself, so they are not passed through.
+	return placeImage(req.rect, ansi.SixelGraphics(0, 1, 0, payload.Bytes())), nil
+}
+
+// renderITerm2Image encodes the image with the iTerm2 inline image protocol,
+// sized in cells so that it fits inside the preview rectangle.
+func renderITerm2Image(req imageRequest) (string, error) {
+	img, data, err := loadThumbnail(req.path)
+	if err != nil {
+		return "", err
+	}
+
+	cols, rows := req.imageCells(img)
+	file := iterm2.File{
+		Size:            int64(len(data)),
+		Width:           iterm2.Cells(cols),
+		Height:          iterm2.Cells(rows),
+		Inline:          true,
+		DoNotMoveCursor: true,
+		Content:         []byte(base64.StdEncoding.EncodeToString(data)),
+	}
+
+	return placeImage(req.rect, passthrough(ansi.ITerm2(file), req.tmux)), nil
+}
+
+// imageCells returns the number of cells img covers once scaled down to fit
+// the preview rectangle.
+func (req imageRequest) imageCells(img image.Image) (cols, rows int) {
+	b := img.Bounds()
+	w, h := fitImage(b.Dx(), b.Dy(), req.rect.width*req.cellWidth, req.rect.height*req.cellHeight)
+	cols = max((w+req.cellWidth-1)/req.cellWidth, 1)
+	rows = max((h+req.cellHeight-1)/req.cellHeight, 1)
+	return min(cols, req.rect.width), min(rows, req.rect.height)
+}
+
+// placeImage wraps an image sequence so that it is drawn at the top-left
+// corner of rect without disturbing the renderer's cursor.
+func placeImage(rect imageRect, seq string) string {
+	return ansi.SaveCursor + ansi.CursorPosition(rect.x+1, rect.y+1) + seq + ansi.RestoreCursor
+}
+
+// passthrough wraps seq so that tmux forwards it to the outer terminal
+// instead of interpreting it. This requires tmux's allow-passthrough option.
+func passthrough(seq string, tmux bool) string {
+	if !tmux {
+		return seq
+	}
+	return ansi.TmuxPassthrough(seq)
+}
+
+// loadThumbnail returns a thumbnail of the image at path, decoded. The
+// encoded PNG is returned as well for protocols that send it as is.
+func loadThumbnail(path string) (image.Image, []byte, error) {
+	data, err := createThumbnailVips(path, thumbnailPixels)
+	if err != nil {
+		return nil, nil, fmt.Errorf("preparing image for preview: %w", err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("decoding thumbnail: %w", err)
+	}
+	return img, data, nil
+}
+
+// fitImage returns the size of a w×h image scaled down, preserving its
+// aspect ratio, to fit within maxW×maxH. Images are never enlarged.
+func fitImage(w, h, maxW, maxH int) (int, int) {
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+	if w <= maxW && h <= maxH {
+		return w, h
+	}
+
+	scale := min(float64(maxW)/float64(w), float64(maxH)/float64(h))
+	return max(int(float64(w)*scale), 1), max(int(float64(h)*scale), 1)
+}
+
+// scaleImage resizes img to w×h, averaging the source pixels that fall into
+// each destination pixel.
+func scaleImage(img image.Image, w, h int) *image.RGBA {
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/h, y0+1)
+		for x := range w {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(src.Min.X+(x+1)*src.Dx()/w, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r += uint64(pr)
+					g += uint64(pg)
+					b += uint64(pb)
+					a += uint64(pa)
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(b / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,272 @@
+package tui
+
+import (
+	"path/filepath"
+	"slices"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+
+	"cute/console"
+)
+
+const (
+	// defaultCellWidth and defaultCellHeight are the assumed size of a
+	// terminal cell in pixels until the terminal reports the real size.
+	defaultCellWidth  = 10
+	defaultCellHeight = 20
+
+	// sixelDeviceAttribute is the primary device attribute reported by
+	// terminals that can draw Sixel graphics.
+	sixelDeviceAttribute = 4
+)
+
+// imagePreviewTickMsg fires once the cursor has rested on an image for
+// imagePreviewDebounce. seq identifies the scheduling so ticks for an image
+// the cursor has already left are ignored.
+type imagePreviewTickMsg struct {
+	seq  int
+	path string
+}
+
+// imagePreviewMsg carries a rendered image preview back into the update loop.
+// Terminal graphics arrive as an escape sequence to write to the terminal,
+// Unicode renderings as preview lines.
+type imagePreviewMsg struct {
+	seq      int
+	path     string
+	protocol ImageProtocol
+	imageID  int
+	sequence string
+	lines    []string
+	err      error
+}
+
+// imageRect is a rectangle on the screen in terminal cells, with the origin
+// at the top-left corner.
+type imageRect struct {
+	x, y          int
+	width, height int
+}
+
+// imageProtocolQueries asks the terminal whether it supports the Kitty
+// graphics protocol, for its primary device attributes, which tell whether
+// Sixel is supported, and for the size of a cell in pixels, which is needed
+// to scale images to the preview pane. The device attributes are requested
+// last, so terminals answer them after the Kitty query.
+func imageProtocolQueries(tmux bool) tea.Cmd {
+	return tea.Raw(kittyGraphicsQuery(tmux) +
+		ansi.WindowOp(ansi.RequestCellSizeWinOp) +
+		ansi.RequestPrimaryDeviceAttributes)
+}
+
+// handleKittyGraphicsReply switches to the Kitty graphics protocol when the
+// image protocol is picked automatically and the terminal answered the
+// startup query.
+func (m *Model) handleKittyGraphicsReply(reply uv.KittyGraphicsEvent) tea.Cmd {
+	if reply.Options.ID != kittyQueryImageID || string(reply.Payload) != "OK" {
+		return nil
+	}
+	if m.imageProtocolSetting != ImageProtocolAuto || m.imageProtocol == ImageProtocolKitty {
+		return nil
+	}
+
+	console.Log("image preview: terminal supports the kitty graphics protocol")
+	m.imageProtocol = ImageProtocolKitty
+	return m.refreshImagePreview()
+}
+
+// handleDeviceAttributes switches to Sixel when the image protocol is picked
+// automatically, no better protocol was detected, and the terminal reports
+// Sixel support.
+func (m *Model) handleDeviceAttributes(attrs uv.PrimaryDeviceAttributesEvent) tea.Cmd {
+	if m.imageProtocolSetting != ImageProtocolAuto || m.imageProtocol != ImageProtocolHalfBlocks {
+		return nil
+	}
+	if !slices.Contains(attrs, sixelDeviceAttribute) {
+		return nil
+	}
+
+	console.Log("image preview: terminal supports sixel")
+	m.imageProtocol = ImageProtocolSixel
+	return m.refreshImagePreview()
+}
+
+// handleCellSize records the size of a terminal cell in pixels.
+func (m *Model) handleCellSize(size uv.CellSizeEvent) tea.Cmd {
+	if size.Width <= 0 || size.Height <= 0 {
+		return nil
+	}
+	if size.Width == m.cellWidth && size.Height == m.cellHeight {
+		return nil
+	}
+	m.cellWidth = size.Width
+	m.cellHeight = size.Height
+	return m.refreshImagePreview()
+}
+
+// refreshImagePreview redraws the preview if it shows an image, e.g. after
+// the image protocol or the cell size changed.
+func (m *Model) refreshImagePreview() tea.Cmd {
+	path, ok := m.selectedFilePath()
+	if !ok || !isImageFile(path) {
+		return nil
+	}
+	return m.UpdatePreview()
+}
+
+// selectedFilePath returns the full path of the entry under the cursor.
+func (m *Model) selectedFilePath() (string, bool) {
+	idx := m.fileList.Index()
+	if idx < 0 || idx >= len(m.files) {
+		return "", false
+	}
+
+	fi := m.files[idx]
+	if fi.Path != "" {
+		return fi.Path, true
+	}
+	return filepath.Join(m.currentDir, fi.Name), true
+}
+
+// scheduleImagePreview sets up a debounced image preview for the given path.
+// If the cursor moves away from this image before the debounce completes, the
+// pending preview is cancelled.
+func (m *Model) scheduleImagePreview(path string) tea.Cmd {
+	m.imagePreviewSeq++
+	m.pendingImagePath = path
+
+	seq := m.imagePreviewSeq
+	return tea.Tick(imagePreviewDebounce, func(time.Time) tea.Msg {
+		return imagePreviewTickMsg{seq: seq, path: path}
+	})
+}
+
+// handleImagePreviewTick renders the pending image preview if the selection
+// is still on the same image.
+func (m *Model) handleImagePreviewTick(msg imagePreviewTickMsg) tea.Cmd {
+	if msg.seq != m.imagePreviewSeq || msg.path != m.pendingImagePath {
+		return nil
+	}
+	m.pendingImagePath = ""
+
+	// If the selection moved to a different file, don't render this image.
+	if path, ok := m.selectedFilePath(); !ok || path != msg.path {
+		return nil
+	}
+
+	return m.previewImage(msg.path)
+}
+
+// cancelImagePreview drops any pending image preview and hides an image that
+// is currently shown. The returned command finishes removing the image from
+// the terminal.
+func (m *Model) cancelImagePreview() tea.Cmd {
+	m.imagePreviewSeq++
+	m.pendingImagePath = ""
+
+	if !m.imagePreviewActive {
+		return nil
+	}
+	m.imagePreviewActive = false
+	return m.clearImagePreview()
+}
+
+// previewImage renders an image into the preview pane in the background using
+// the current image protocol.
+func (m *Model) previewImage(path string) tea.Cmd {
+	if m.imageProtocol == ImageProtocolNone {
+		m.setPreviewText("Image previews are disabled.\nSet image_preview in config.lua to enable them.")
+		return nil
+	}
+
+	// Every Kitty image gets a new ID so that it can be deleted without
+	// touching any other image.
+	m.kittyImageID++
+
+	req := imageRequest{
+		seq:        m.imagePreviewSeq,
+		path:       path,
+		protocol:   m.imageProtocol,
+		rect:       m.imagePreviewRect(),
+		imageID:    kittyQueryImageID + m.kittyImageID,
+		tmux:       m.tmux,
+		cellWidth:  m.cellWidth,
+		cellHeight: m.cellHeight,
+	}
+	if req.cellWidth <= 0 || req.cellHeight <= 0 {
+		req.cellWidth = defaultCellWidth
+		req.cellHeight = defaultCellHeight
+	}
+
+	console.Log("previewImage: path=%s protocol=%s rect=%+v", path, req.protocol, req.rect)
+	return func() tea.Msg {
+		return renderImagePreview(req)
+	}
+}
+
+// handleImagePreviewMsg shows a rendered image preview, unless the selection
+// has moved on since it was requested.
+func (m *Model) handleImagePreviewMsg(msg imagePreviewMsg) tea.Cmd {
+	if msg.seq != m.imagePreviewSeq || msg.path != m.lastPreviewedPath {
+		return nil
+	}
+
+	if msg.err != nil {
+		console.Log("previewImage: %s error: %v", msg.protocol, msg.err)
+		m.setPreviewText(formatPreviewError("Error rendering image:\n" + msg.err.Error()))
+		return nil
+	}
+
+	if !msg.protocol.usesTerminalGraphics() {
+		m.setPreviewContent(previewContent{lines: msg.lines})
+		return nil
+	}
+
+	// Clear textual content so the image is not obscured by colored cells,
+	// and remember how it was drawn so it can be cleared again.
+	m.imagePreviewActive = true
+	m.imagePreviewProtocol = msg.protocol
+	m.imagePreviewID = msg.imageID
+	m.setPreviewText("")
+
+	// The image is written through the program so it is interleaved with
+	// the renderer's output rather than racing it.
+	return tea.Raw(msg.sequence)
+}
+
+// imagePreviewRect returns the cell rectangle inside the border of the right
+// preview viewport.
+//
+// Layout assumptions from calc-layout.go: the viewports row starts below the
+// two header rows and the search/tabs row, and the preview box occupies the
+// right half of the terminal.
+func (m *Model) imagePreviewRect() imageRect {
+	const headerRows = 2
+	const searchAndTabsRows = 1
+
+	return imageRect{
+		x:      m.viewportWidth + 1,
+		y:      headerRows + searchAndTabsRows + 1,
+		width:  max(m.viewportWidth-2, 1),
+		height: max(m.viewportHeight-2, 1),
+	}
+}
+
+// clearImagePreview removes a previously drawn image so that moving the file
+// selection or toggling previews off hides the old image immediately.
+func (m *Model) clearImagePreview() tea.Cmd {
+	switch m.imagePreviewProtocol {
+	case ImageProtocolKitty:
+		// Kitty images live on their own layer and are deleted by ID.
+		return tea.Raw(kittyDeleteImage(m.imagePreviewID, m.tmux))
+	case ImageProtocolSixel, ImageProtocolITerm2:
+		// Sixel and iTerm2 images live in the cells they were drawn over,
+		// which the renderer believes are unchanged. Repaint the screen.
+		return tea.ClearScreen
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,128 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+
+	"cute/command"
+	"cute/console"
+	"cute/filesystem"
+)
+
+// previewCommandTimeout is how long the command of a Lua previewer may run
+// before it is killed.
+const previewCommandTimeout = 10 * time.Second
+
+// previewCommandMaxBytes is how much of a previewer command's output is
+// shown.
+const previewCommandMaxBytes = 1024 * 1024
+
+// previewTextReplacer makes text from Lua previewers and their commands fit
+// the preview pane, where tabs and carriage returns have no width.
+var previewTextReplacer = strings.NewReplacer("\t", "    ", "\r\n", "\n", "\r", "")
+
+// requestLuaPreview previews the file with the Lua previewer config.lua
+// registered for it. It reports false when there is none, when the
+// previewer returned nil to leave the file to the built-in previews, or while
+// a Lua command runs in the background.
+func (m *Model) requestLuaPreview(fi filesystem.FileInfo, path string) (bool, tea.Cmd) {
+	if m.luaRunning() {
+		return false, nil
+	}
+
+	var mimeType string
+	detectMime := func() string {
+		if mimeType == "" {
+			mimeType = filesystem.MimeType(path)
+		}
+		return mimeType
+	}
+
+	fn := m.runtimeConfig.Previewer(path, detectMime)
+	if fn == nil {
+		return false, nil
+	}
+
+	env, cancel := m.boundedEnvironment(luaPreviewerTimeout)
+	defer cancel()
+	preview, ok, err := command.RunPreviewer(env, fn, command.PreviewArgs{
+		File:     fi,
+		MimeType: detectMime(),
+		Width:    max(m.viewportWidth-2, 0),
+		Height:   m.viewportHeight,
+	})
+	switch {
+	case err != nil:
+		console.Log("lua previewer error: %v", err)
+		m.cancelPreview()
+		m.setPreviewText(formatPreviewError("Lua previewer failed:\n" + err.Error()))
+		return true, nil
+	case !ok:
+		return false, nil
+	case !preview.IsCommand():
+		m.cancelPreview()
+		m.setPreviewText(previewTextReplacer.Replace(preview.Text))
+		return true, nil
+	}
+
+	return true, m.sendPreviewRequest(previewRequest{
+		path:    path,
+		width:   m.viewportWidth,
+		theme:   m.theme,
+		command: &preview,
+	})
+}
+
+// renderCommandPreview runs the command of a Lua previewer in the file's
+// directory and shows its output, stdout and stderr together.
+func renderCommandPreview(ctx context.Context, path string, preview command.Preview) previewContent {
+	ctx, cancel := context.WithTimeout(ctx, previewCommandTimeout)
+	defer cancel()
+
+	var out limitedBuffer
+	out.limit = previewCommandMaxBytes
+
+	cmd := preview.Cmd(ctx, filepath.Dir(path))
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+
+	text := previewTextReplacer.Replace(out.buf.String())
+	if out.truncated {
+		text += "\n…"
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		text = formatPreviewError(fmt.Sprintf("Previewer command timed out after %s.\n\n%s", previewCommandTimeout, text))
+	case ctx.Err() != nil:
+		// The selection moved on; the result is dropped.
+		return previewContent{}
+	case err != nil:
+		text = formatPreviewError(fmt.Sprintf("Previewer command failed: %v\n\n%s", err, text))
+	}
+	return newPreviewContent(strings.TrimRight(text, "\n"))
+}
+
+// limitedBuffer keeps the first limit bytes written to it and discards the
+// rest, so a chatty command cannot exhaust memory.
+type limitedBuffer struct {
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p)
+}
@@ -0,0 +1,342 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"cute/theming"
+)
+
+// markdownStyles resolves the theme colors used to render Markdown.
+type markdownStyles struct {
+	heading1 lipgloss.Style
+	heading2 lipgloss.Style
+	heading  lipgloss.Style
+	code     lipgloss.Style
+	link     lipgloss.Style
+	quote    lipgloss.Style
+	bullet   lipgloss.Style
+	muted    lipgloss.Style
+	data     dataStyles
+}
+
+func newMarkdownStyles(theme theming.Theme) markdownStyles {
+	return markdownStyles{
+		heading1: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Primary)),
+		heading2: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color(theme.Secondary)),
+		heading:  theming.StyleFromSpec(theme.Syntax.Keyword).Bold(true),
+		code:     theming.StyleFromSpec(theme.Syntax.String),
+		link:     theming.StyleFromSpec(theme.Syntax.Function).Underline(true),
+		quote:    theming.StyleFromSpec(theme.Syntax.Comment),
+		bullet:   lipgloss.NewStyle().Foreground(lipgloss.Color(theme.Primary)),
+		muted:    theming.StyleFromSpec(theme.Syntax.Comment),
+		data:     newDataStyles(theme.Syntax),
+	}
+}
+
+// isMarkdownFile reports whether path has a Markdown extension.
+func isMarkdownFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown", ".mdown", ".mkd", ".mkdn":
+		return true
+	default:
+		return false
+	}
+}
+
+// renderMarkdownPreview renders a Markdown file with headings, lists, code
+// blocks, tables and links styled with the theme, wrapped to the inner width
+// of the preview pane. It reports false for files that are not Markdown or are
+// too large, which are shown as plain text instead.
+func renderMarkdownPreview(ctx context.Context, path string, width int, theme theming.Theme) (previewContent, bool) {
+	if !isMarkdownFile(path) {
+		return previewContent{}, false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil || info.Size() > structuredPreviewMaxBytes {
+		return previewContent{}, false
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return previewContent{}, false
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader(src))
+
+	r := markdownRenderer{
+		ctx:    ctx,
+		src:    src,
+		theme:  theme,
+		styles: newMarkdownStyles(theme),
+	}
+
+	// Leave room for the preview border, as the directory preview does.
+	lines := r.blocks(doc, max(width-2, 10), false)
+	if ctx.Err() != nil {
+		return previewContent{}, false
+	}
+	return previewContent{lines: lines}, true
+}
+
+// markdownRenderer turns a goldmark syntax tree into styled preview lines.
+type markdownRenderer struct {
+	ctx    context.Context
+	src    []byte
+	theme  theming.Theme
+	styles markdownStyles
+}
+
+// blocks renders the block children of parent, separated by blank lines
+// unless tight is set (as for the items of a tight list).
+func (r *markdownRenderer) blocks(parent ast.Node, width int, tight bool) []string {
+	var lines []string
+	for n := parent.FirstChild(); n != nil; n = n.NextSibling() {
+		if r.ctx.Err() != nil {
+			return nil
+		}
+		block := r.block(n, width)
+		if len(block) == 0 {
+			continue
+		}
+		if len(lines) > 0 && !tight {
+			lines = append(lines, "")
+		}
+		lines = append(lines, block...)
+	}
+	return lines
+}
+
+// block renders a single block node.
+func (r *markdownRenderer) block(n ast.Node, width int) []string {
+	s := r.styles
+
+	switch n := n.(type) {
+	case *ast.Heading:
+		style := s.heading
+		switch n.Level {
+		case 1:
+			style = s.heading1
+		case 2:
+			style = s.heading2
+		}
+		lines := wrapMarkdown(style.Render(ansi.Strip(r.inline(n))), width)
+		if n.Level <= 2 {
+			rule := "─"
+			if n.Level == 1 {
+				rule = "═"
+			}
+			lines = append(lines, style.Render(strings.Repeat(rule, max(min(maxLineWidth(lines), width), 1))))
+		}
+		return lines
+
+	case *ast.Paragraph, *ast.TextBlock:
+		return wrapMarkdown(r.inline(n), width)
+
+	case *ast.List:
+		return r.list(n, width)
+
+	case *ast.Blockquote:
+		bar := s.quote.Render("│ ")
+		inner := r.blocks(n, max(width-2, 1), false)
+		for i, line := range inner {
+			inner[i] = bar + s.quote.Render(ansi.Strip(line))
+		}
+		return inner
+
+	case *ast.FencedCodeBlock:
+		return r.codeBlock(n, string(n.Language(r.src)))
+
+	case *ast.CodeBlock:
+		return r.codeBlock(n, "")
+
+	case *ast.ThematicBreak:
+		return []string{s.muted.Render(strings.Repeat("─", max(width, 1)))}
+
+	case *ast.HTMLBlock:
+		var lines []string
+		for i := 0; i < n.Lines().Len(); i++ {
+			seg := n.Lines().At(i)
+			lines = append(lines, s.muted.Render(strings.TrimRight(string(seg.Value(r.src)), "\n")))
+		}
+		return lines
+
+	case *east.Table:
+		var records [][]string
+		for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+			var record []string
+			for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+				record = append(record, r.inline(cell))
+			}
+			records = append(records, record)
+		}
+		if len(records) == 0 {
+			return nil
+		}
+		return strings.Split(formatTable(records, s.data), "\n")
+
+	default:
+		return r.blocks(n, width, false)
+	}
+}
+
+// list renders a bullet or ordered list, indenting item content under its
+// marker.
+func (r *markdownRenderer) list(n *ast.List, width int) []string {
+	var lines []string
+
+	number := n.Start
+	for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+		marker := "• "
+		if n.IsOrdered() {
+			marker = fmt.Sprintf("%d%c ", number, n.Marker)
+			number++
+		}
+		indent := strings.Repeat(" ", ansi.StringWidth(marker))
+
+		if len(lines) > 0 && !n.IsTight {
+			lines = append(lines, "")
+		}
+
+		body := r.blocks(item, max(width-len(indent), 1), n.IsTight)
+		if len(body) == 0 {
+			body = []string{""}
+		}
+		for i, line := range body {
+			if i == 0 {
+				lines = append(lines, r.styles.bullet.Render(marker)+line)
+			} else {
+				lines = append(lines, indent+line)
+			}
+		}
+	}
+
+	return lines
+}
+
+// codeBlock renders a code block indented and, when the language is known,
+// highlighted with the theme's syntax colors. Code is not wrapped.
+func (r *markdownRenderer) codeBlock(n ast.Node, lang string) []string {
+	var b bytes.Buffer
+	for i := 0; i < n.Lines().Len(); i++ {
+		seg := n.Lines().At(i)
+		b.Write(seg.Value(r.src))
+	}
+	code := strings.TrimRight(b.String(), "\n")
+
+	rendered := r.styles.code.Render(code)
+	if lang != "" {
+		if lexer := lexers.Get(lang); lexer != nil {
+			if highlighted, err := highlightSource(r.ctx, code, lexer, r.theme.Syntax); err == nil {
+				rendered = highlighted
+			}
+		}
+	}
+
+	lines := strings.Split(rendered, "\n")
+	for i, line := range lines {
+		lines[i] = "  " + line
+	}
+	return lines
+}
+
+// inline renders the inline children of n as a single styled string.
+func (r *markdownRenderer) inline(n ast.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		b.WriteString(r.inlineNode(c))
+	}
+	return b.String()
+}
+
+func (r *markdownRenderer) inlineNode(n ast.Node) string {
+	s := r.styles
+
+	switch n := n.(type) {
+	case *ast.Text:
+		value := string(n.Segment.Value(r.src))
+		switch {
+		case n.HardLineBreak():
+			value += "\n"
+		case n.SoftLineBreak():
+			value += " "
+		}
+		return value
+
+	case *ast.String:
+		return string(n.Value)
+
+	case *ast.CodeSpan:
+		return s.code.Render(ansi.Strip(r.inline(n)))
+
+	case *ast.Emphasis:
+		style := lipgloss.NewStyle().Italic(true)
+		if n.Level >= 2 {
+			style = lipgloss.NewStyle().Bold(true)
+		}
+		return style.Render(ansi.Strip(r.inline(n)))
+
+	case *east.Strikethrough:
+		return lipgloss.NewStyle().Strikethrough(true).Render(ansi.Strip(r.inline(n)))
+
+	case *ast.Link:
+		label := ansi.Strip(r.inline(n))
+		dest := string(n.Destination)
+		if label == "" || label == dest {
+			return s.link.Render(dest)
+		}
+		return s.link.Render(label) + s.muted.Render(" ("+dest+")")
+
+	case *ast.AutoLink:
+		return s.link.Render(string(n.URL(r.src)))
+
+	case *ast.Image:
+		return s.muted.Render("[image: " + ansi.Strip(r.inline(n)) + "]")
+
+	case *ast.RawHTML:
+		var b strings.Builder
+		for i := 0; i < n.Segments.Len(); i++ {
+			seg := n.Segments.At(i)
+			b.Write(seg.Value(r.src))
+		}
+		return s.muted.Render(b.String())
+
+	case *east.TaskCheckBox:
+		if n.IsChecked {
+			return s.bullet.Render("[x] ")
+		}
+		return s.bullet.Render("[ ] ")
+
+	default:
+		return r.inline(n)
+	}
+}
+
+// wrapMarkdown wraps styled text to width, keeping explicit line breaks.
+func wrapMarkdown(s string, width int) []string {
+	return strings.Split(ansi.Wrap(s, max(width, 1), ""), "\n")
+}
+
+// maxLineWidth returns the width in cells of the widest line.
+func maxLineWidth(lines []string) int {
+	widest := 0
+	for _, line := range lines {
+		widest = max(widest, ansi.StringWidth(line))
+	}
+	return widest
+}
@@ -0,0 +1,40 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cute/theming"
+)
+
+func TestRenderMarkdownPreviewDeepNesting(t *testing.T) {
+	tests := map[string]string{
+		"quotes":         "> > > > > > > ---\n",
+		"quoted heading": "> > > > > > > Title\n> > > > > > > =====\n",
+		"lists":          "- a\n  - b\n    - c\n      - d\n        - e\n          - f\n\n            ---\n",
+		"ordered lists":  "1. a\n   10. b\n       100. c\n            1000. d\n\n                  ---\n",
+		"mixed":          "> - > - > - > - ---\n",
+	}
+
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "deep.md")
+			if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, width := range []int{0, 4, 12, 40} {
+				content, ok := renderMarkdownPreview(context.Background(), path, width, theming.DefaultTheme())
+				if !ok {
+					t.Fatalf("width %d: not rendered as Markdown", width)
+				}
+				if strings.TrimSpace(strings.Join(content.lines, "")) == "" {
+					t.Errorf("width %d: empty preview", width)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package tui
+
+import (
+	"slices"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// previewLoadAhead is how many viewport heights from the end of the loaded
+// content the next chunk of a large file is requested.
+const previewLoadAhead = 2
+
+// previewContent is a rendered preview split into lines. Large files are
+// loaded in chunks: offset is how far into the source the loaded lines reach
+// and more reports whether another chunk can be loaded from there. Structured
+// data files carry the tree the lines were rendered from, so it can be folded.
+type previewContent struct {
+	lines  []string
+	offset int64
+	more   bool
+	tree   *dataTree
+}
+
+// previewMatch is a search hit in the preview, in cell columns of the line.
+type previewMatch struct {
+	line  int
+	start int
+	end   int
+}
+
+// newPreviewContent wraps a plain string as complete preview content.
+func newPreviewContent(s string) previewContent {
+	return previewContent{lines: strings.Split(s, "\n")}
+}
+
+// setPreviewText replaces the preview with plain text, e.g. an error or
+// command output.
+func (m *Model) setPreviewText(s string) {
+	m.setPreviewContent(newPreviewContent(s))
+}
+
+// setPreviewContent replaces the preview and scrolls back to the top. Any
+// active search is re-run against the new content.
+func (m *Model) setPreviewContent(c previewContent) {
+	if c.tree != nil {
+		// The tree may have been folded since it was cached.
+		c.lines = c.tree.render()
+	}
+	m.preview = c
+	m.outputJob = 0
+	m.previewMatches = findPreviewMatches(c.lines, m.previewSearch)
+	m.previewMatchIdx = 0
+	m.refreshPreviewViewport()
+	m.rightViewport.GotoTop()
+}
+
+// appendPreviewContent adds a newly loaded chunk to the end of the preview
+// while keeping the scroll position and the selected search match.
+func (m *Model) appendPreviewContent(c previewContent) {
+	m.preview = previewContent{
+		lines:  append(slices.Clip(m.preview.lines), c.lines...),
+		offset: c.offset,
+		more:   c.more,
+	}
+	m.previewMatches = findPreviewMatches(m.preview.lines, m.previewSearch)
+	if m.previewMatchIdx >= len(m.previewMatches) {
+		m.previewMatchIdx = 0
+	}
+
+	yOffset := m.rightViewport.YOffset()
+	m.refreshPreviewViewport()
+	m.rightViewport.SetYOffset(yOffset)
+}
+
+// refreshPreviewViewport pushes the preview lines, with search matches
+// highlighted, into the right viewport.
+func (m *Model) refreshPreviewViewport() {
+	// SetContentLines modifies the slice it is given, so always hand it a copy.
+	lines := slices.Clone(m.preview.lines)
+
+	if len(m.previewMatches) > 0 {
+		matchStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color(m.theme.SearchMatch.Background)).
+			Foreground(lipgloss.Color(m.theme.SearchMatch.Foreground))
+		currentStyle := lipgloss.NewStyle().
+			Background(lipgloss.Color(m.theme.SearchMatchCurrent.Background)).
+			Foreground(lipgloss.Color(m.theme.SearchMatchCurrent.Foreground))
+
+		ranges := map[int][]lipgloss.Range{}
+		for i, match := range m.previewMatches {
+			style := matchStyle
+			if i == m.previewMatchIdx {
+				style = currentStyle
+			}
+			ranges[match.line] = append(ranges[match.line], lipgloss.NewRange(match.start, match.end, style))
+		}
+		for line, rs := range ranges {
+			lines[line] = lipgloss.StyleRanges(lines[line], rs...)
+		}
+	}
+
+	// Highlight the tree cursor while the preview has focus.
+	if tree := m.preview.tree; tree != nil && m.IsPreviewFocused() && tree.cursor < len(lines) {
+		cursorStyle := lipgloss.NewStyle().Background(lipgloss.Color(m.theme.Selection.Background))
+		line := lines[tree.cursor]
+		lines[tree.cursor] = lipgloss.StyleRanges(line, lipgloss.NewRange(0, ansi.StringWidth(line), cursorStyle))
+	}
+
+	m.rightViewport.SetContentLines(lines)
+}
+
+// searchPreview highlights every case-insensitive occurrence of query in the
+// loaded preview and scrolls to the first match at or below the current
+// scroll position. An empty query clears the search.
+func (m *Model) searchPreview(query string) {
+	m.previewSearch = query
+	m.previewMatches = findPreviewMatches(m.preview.lines, query)
+	m.previewMatchIdx = 0
+
+	top := m.rightViewport.YOffset()
+	for i, match := range m.previewMatches {
+		if match.line >= top {
+			m.previewMatchIdx = i
+			break
+		}
+	}
+
+	m.refreshPreviewViewport()
+	m.showPreviewMatch()
+}
+
+// nextPreviewMatch moves the selected search match by delta, wrapping around
+// at either end.
+func (m *Model) nextPreviewMatch(delta int) {
+	n := len(m.previewMatches)
+	if n == 0 {
+		return
+	}
+	m.previewMatchIdx = ((m.previewMatchIdx+delta)%n + n) % n
+	m.refreshPreviewViewport()
+	m.showPreviewMatch()
+}
+
+// showPreviewMatch scrolls the preview so the selected match is visible.
+func (m *Model) showPreviewMatch() {
+	if m.previewMatchIdx < 0 || m.previewMatchIdx >= len(m.previewMatches) {
+		return
+	}
+	match := m.previewMatches[m.previewMatchIdx]
+	m.rightViewport.EnsureVisible(match.line, match.start, match.end)
+}
+
+// findPreviewMatches returns the positions of query in lines, ignoring case
+// and any styling escape sequences.
+func findPreviewMatches(lines []string, query string) []previewMatch {
+	if query == "" {
+		return nil
+	}
+	query = strings.ToLower(query)
+
+	var matches []previewMatch
+	for i, line := range lines {
+		plain := strings.ToLower(ansi.Strip(line))
+		from := 0
+		for {
+			idx := strings.Index(plain[from:], query)
+			if idx < 0 {
+				break
+			}
+			start := from + idx
+			end := start + len(query)
+			matches = append(matches, previewMatch{
+				line:  i,
+				start: ansi.StringWidth(plain[:start]),
+				end:   ansi.StringWidth(plain[:end]),
+			})
+			from = end
+		}
+	}
+	return matches
+}
+
+// updatePreviewTree re-renders a folded or unfolded data tree and keeps the
+// cursor on screen.
+func (m *Model) updatePreviewTree() {
+	tree := m.preview.tree
+	if tree == nil {
+		return
+	}
+	m.preview.lines = tree.render()
+	m.previewMatches = findPreviewMatches(m.preview.lines, m.previewSearch)
+	if m.previewMatchIdx >= len(m.previewMatches) {
+		m.previewMatchIdx = 0
+	}
+	m.refreshPreviewViewport()
+	m.rightViewport.EnsureVisible(tree.cursor, 0, 0)
+}
+
+// previewNeedsMore reports whether the preview is scrolled close enough to
+// the end of the loaded content that the next chunk should be loaded.
+func (m *Model) previewNeedsMore() bool {
+	if !m.preview.more {
+		return false
+	}
+	bottom := m.rightViewport.YOffset() + previewLoadAhead*m.rightViewport.Height()
+	return bottom >= m.rightViewport.TotalLineCount()
+}
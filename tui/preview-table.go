@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+const (
+	// tablePreviewMaxRows bounds how many CSV/TSV records are shown.
+	tablePreviewMaxRows = 5000
+
+	// tablePreviewMaxCellWidth is the widest a table column may grow; longer
+	// cells are truncated.
+	tablePreviewMaxCellWidth = 40
+)
+
+// renderDataTable renders CSV or TSV data as a table with aligned columns. The
+// first record is treated as the header row.
+func renderDataTable(ctx context.Context, data []byte, comma rune, styles dataStyles) (string, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	// Keep each record on one line.
+	flatten := strings.NewReplacer("\r\n", " ", "\n", " ", "\t", " ")
+
+	var records [][]string
+	truncated := false
+	for {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(records) == tablePreviewMaxRows {
+			truncated = true
+			break
+		}
+		for i, cell := range record {
+			record[i] = flatten.Replace(cell)
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return "", errors.New("no records")
+	}
+
+	table := formatTable(records, styles)
+	if truncated {
+		table += styles.comment.Render(fmt.Sprintf("\n\n… showing the first %d rows", tablePreviewMaxRows))
+	}
+	return table, nil
+}
+
+// formatTable lays out records as a table with aligned columns, treating the
+// first record as the header row. Cells may contain styling.
+func formatTable(records [][]string, styles dataStyles) string {
+	var widths []int
+	for _, record := range records {
+		for i, cell := range record {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = min(max(widths[i], ansi.StringWidth(cell)), tablePreviewMaxCellWidth)
+		}
+	}
+
+	separator := styles.punctuation.Render(" │ ")
+
+	formatRow := func(record []string, header bool) string {
+		cells := make([]string, len(widths))
+		for i, width := range widths {
+			cell := ""
+			if i < len(record) {
+				cell = record[i]
+			}
+			cell = ansi.Truncate(cell, width, "…")
+			cell += strings.Repeat(" ", width-ansi.StringWidth(cell))
+			if header {
+				cell = styles.header.Render(cell)
+			}
+			cells[i] = cell
+		}
+		return strings.TrimRight(strings.Join(cells, separator), " ")
+	}
+
+	var b strings.Builder
+
+	b.WriteString(formatRow(records[0], true))
+	b.WriteByte('\n')
+
+	rule := make([]string, len(widths))
+	for i, width := range widths {
+		rule[i] = strings.Repeat("─", width)
+	}
+	b.WriteString(styles.punctuation.Render(strings.Join(rule, "─┼─")))
+	b.WriteByte('\n')
+
+	for _, record := range records[1:] {
+		b.WriteString(formatRow(record, false))
+		b.WriteByte('\n')
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
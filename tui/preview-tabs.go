@@ -0,0 +1,258 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/lipgloss/v2"
+
+	"cute/command"
+	"cute/filesystem"
+)
+
+// PreviewTab identifies one of the tabs above the preview pane.
+type PreviewTab string
+
+const (
+	PreviewTabContent     PreviewTab = "Content"
+	PreviewTabInfo        PreviewTab = "Info"
+	PreviewTabMetadata    PreviewTab = "Metadata"
+	PreviewTabPermissions PreviewTab = "Permissions"
+)
+
+// PreviewTabOrder is the order in which preview tabs are shown and cycled.
+var PreviewTabOrder = []PreviewTab{
+	PreviewTabContent,
+	PreviewTabInfo,
+	PreviewTabMetadata,
+	PreviewTabPermissions,
+}
+
+// Rows of the permissions editor. The first three rows are the owner, group
+// and other permission bits; the last two edit the file's ownership.
+const (
+	permRowOwnerBits = iota
+	permRowGroupBits
+	permRowOtherBits
+	permRowOwner
+	permRowGroup
+	permRowCount
+)
+
+// cyclePreviewTab switches to the next (delta > 0) or previous preview tab and
+// refreshes the preview.
+func (m *Model) cyclePreviewTab(delta int) tea.Cmd {
+	idx := 0
+	for i, tab := range PreviewTabOrder {
+		if tab == m.previewTab {
+			idx = i
+			break
+		}
+	}
+
+	n := len(PreviewTabOrder)
+	m.previewTab = PreviewTabOrder[((idx+delta)%n+n)%n]
+	m.permStatus = ""
+
+	return m.UpdatePreview()
+}
+
+// renderPreviewTab renders the content of the Info and Permissions tabs. The
+// Metadata tab is rendered in the background by renderPreview.
+func (m *Model) renderPreviewTab(fi filesystem.FileInfo, path string) string {
+	switch m.previewTab {
+	case PreviewTabInfo:
+		return renderFileInfoPanel(fi)
+	case PreviewTabPermissions:
+		return m.renderPermissionsEditor(path)
+	default:
+		return ""
+	}
+}
+
+// renderMetadataPanel renders extended metadata for path, such as inode,
+// link count, timestamps and MIME type.
+func renderMetadataPanel(path string) string {
+	md, err := filesystem.ReadMetadata(path)
+	if err != nil {
+		return formatPreviewError("Error reading metadata:\n" + err.Error())
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Metadata\n\n")
+	fmt.Fprintf(&b, "Path: %s\n", md.Path)
+	if md.LinkTarget != "" {
+		fmt.Fprintf(&b, "Link target: %s\n", md.LinkTarget)
+	}
+	if md.MimeType != "" {
+		fmt.Fprintf(&b, "MIME type: %s\n", md.MimeType)
+	}
+	fmt.Fprintf(&b, "Size: %d bytes\n", md.Size)
+	fmt.Fprintf(&b, "Blocks: %d (block size %d)\n", md.Blocks, md.BlockSize)
+	fmt.Fprintf(&b, "Mode: %s (%04o)\n", md.Mode, md.Mode.Perm())
+	fmt.Fprintf(&b, "Owner: %s (%d)\n", md.User, md.UID)
+	fmt.Fprintf(&b, "Group: %s (%d)\n", md.Group, md.GID)
+	fmt.Fprintf(&b, "Inode: %d\n", md.Inode)
+	fmt.Fprintf(&b, "Links: %d\n", md.Links)
+	fmt.Fprintf(&b, "Device: %d\n", md.Device)
+
+	fmt.Fprintf(&b, "\nModified: %s\n", formatMetadataTime(md.ModTime))
+	fmt.Fprintf(&b, "Accessed: %s\n", formatMetadataTime(md.AccessTime))
+	fmt.Fprintf(&b, "Changed: %s\n", formatMetadataTime(md.ChangeTime))
+
+	if isImageFile(path) {
+		b.WriteString(renderImageMetadata(path))
+	}
+
+	return b.String()
+}
+
+// formatMetadataTime formats a timestamp for the metadata panel.
+func formatMetadataTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Format("2006-01-02 15:04:05 MST")
+}
+
+// renderPermissionsEditor renders the interactive permissions and ownership
+// editor for path, with the editor cursor highlighted.
+func (m *Model) renderPermissionsEditor(path string) string {
+	md, err := filesystem.ReadMetadata(path)
+	if err != nil {
+		return formatPreviewError("Error reading permissions:\n" + err.Error())
+	}
+
+	cursor := lipgloss.NewStyle().
+		Background(lipgloss.Color(m.theme.Selection.Background)).
+		Foreground(lipgloss.Color(m.theme.Foreground))
+
+	cell := func(row, col int, text string) string {
+		if row == m.permRow && (row >= permRowOwner || col == m.permCol) {
+			return cursor.Render(text)
+		}
+		return text
+	}
+
+	execLabel := "Execute"
+	if md.Mode.IsDir() {
+		execLabel = "Traverse"
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Permissions: %s (%04o)\n\n", md.Mode, md.Mode.Perm())
+	fmt.Fprintf(&b, "%-8s %-6s %-6s %s\n", "", "Read", "Write", execLabel)
+
+	for row, heading := range []string{"Owner", "Group", "Other"} {
+		fmt.Fprintf(&b, "%-8s", heading)
+		for col := 0; col < 3; col++ {
+			mark := "[ ]"
+			if md.Mode.Perm()&permissionBit(row, col) != 0 {
+				mark = "[x]"
+			}
+			fmt.Fprintf(&b, " %s   ", cell(row, col, mark))
+		}
+		b.WriteByte('\n')
+	}
+
+	fmt.Fprintf(&b, "\n%s\n", cell(permRowOwner, 0, "Owner: "+md.User))
+	fmt.Fprintf(&b, "%s\n", cell(permRowGroup, 0, "Group: "+md.Group))
+
+	if m.permStatus != "" {
+		fmt.Fprintf(&b, "\n%s\n", m.permStatus)
+	}
+
+	if !m.IsPreviewFocused() {
+		fmt.Fprintf(&b, "\nPress tab to edit.\n")
+	} else {
+		fmt.Fprintf(&b, "\nMove with h/j/k/l, press enter to toggle or change.\n")
+	}
+
+	return b.String()
+}
+
+// permissionBit returns the permission bit edited by the given editor cell,
+// e.g. row 0 (owner), column 1 (write) is 0o200.
+func permissionBit(row, col int) os.FileMode {
+	return os.FileMode(0o400) >> uint(row*3+col)
+}
+
+// handlePermissionsKey handles editor keys while the Permissions tab has
+// focus. It reports whether the key was consumed.
+func (m *Model) handlePermissionsKey(key string) (bool, tea.Cmd) {
+	bindings := m.keymap.Bindings(TuiModePreview)
+
+	switch {
+	case bindings.ScrollDown.Matches(key):
+		m.permRow = min(m.permRow+1, permRowCount-1)
+	case bindings.ScrollUp.Matches(key):
+		m.permRow = max(m.permRow-1, 0)
+	case bindings.Left.Matches(key):
+		m.permCol = max(m.permCol-1, 0)
+	case bindings.Right.Matches(key):
+		m.permCol = min(m.permCol+1, 2)
+	case bindings.Enter.Matches(key):
+		path := m.lastPreviewedPath
+		if path == "" {
+			return true, nil
+		}
+
+		if m.permRow >= permRowOwner {
+			// Prompt for a new owner; see OwnerMode.
+			md, err := filesystem.ReadMetadata(path)
+			if err != nil {
+				m.permStatus = "Error: " + err.Error()
+				break
+			}
+			PreviousTuiMode = ActiveTuiMode
+			ActiveTuiMode = TuiModeOwner
+
+			m.commandInput.SetValue(md.User + ":" + md.Group)
+			m.commandInput.CursorEnd()
+			m.commandInput.Focus()
+			return true, nil
+		}
+
+		err := filesystem.TogglePermission(path, permissionBit(m.permRow, m.permCol))
+		if err != nil {
+			m.permStatus = "Error: " + err.Error()
+		} else {
+			m.permStatus = ""
+			m.refreshSelectedFile()
+		}
+		hooks := m.runFileOpHooks([]command.FileOp{{Name: "chmod", Paths: []string{path}, Err: err}})
+		return true, tea.Batch(m.UpdatePreview(), hooks)
+	default:
+		return false, nil
+	}
+
+	return true, m.UpdatePreview()
+}
+
+// refreshSelectedFile re-reads the selected entry from disk so the file list
+// reflects changes such as new permissions, without reloading the directory
+// and losing the selection.
+func (m *Model) refreshSelectedFile() {
+	idx := m.fileList.Index()
+	if idx < 0 || idx >= len(m.files) {
+		return
+	}
+
+	fi, err := filesystem.Stat(m.files[idx].Path)
+	if err != nil {
+		return
+	}
+
+	m.files[idx] = fi
+	for i := range m.allFiles {
+		if m.allFiles[i].Path == fi.Path {
+			m.allFiles[i] = fi
+		}
+	}
+	m.fileList.SetItem(idx, FileItem{Info: fi})
+}
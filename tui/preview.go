@@ -3,23 +3,25 @@ package tui
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 	"time"
 
+	tea "charm.land/bubbletea/v2"
 	"github.com/h2non/bimg"
 
 	"cute/console"
 	"cute/filesystem"
+	"cute/theming"
 )
 
 const (
 	// imagePreviewDebounce controls how long we wait with the cursor on an image
-	// before actually invoking kitty icat. This keeps the UI snappy when moving
+	// before actually rendering it. This keeps the UI snappy when moving
 	// quickly through many images.
 	imagePreviewDebounce = 200 * time.Millisecond
 
@@ -28,129 +30,121 @@ const (
 	// out the Kitty graphics protocol, so we skip previews above this size.
 	maxImagePreviewBytes int64 = 20 * 1024 * 1024 // ~20 MiB
 
-	// maxThumbnailWidth and maxThumbnailHeight control the maximum pixel
-	// dimensions for generated thumbnails. Images are resized to fit within
-	// this box while preserving aspect ratio. This ensures the preview image
-	// is at most ~300px wide on screen.
-	maxThumbnailWidth  = 300
-	maxThumbnailHeight = 300
+	// thumbnailPixels is the size of the thumbnails used for image previews.
+	// Images are resized to fit within a square of this size while preserving
+	// aspect ratio. It matches the freedesktop "large" bucket, which most file
+	// managers fill, so their cached thumbnails are reused.
+	thumbnailPixels = 256
+
+	// textPreviewChunkLines and textPreviewChunkBytes bound how much of a text
+	// file is loaded at once. Further chunks are loaded as the preview is
+	// scrolled.
+	textPreviewChunkLines       = 1000
+	textPreviewChunkBytes int64 = 256 * 1024
+
+	// textPreviewMaxLineBytes is the longest line read in one piece; longer
+	// lines are wrapped onto several preview lines.
+	textPreviewMaxLineBytes = 16 * 1024
 )
 
 // UpdatePreview recomputes the right-hand preview panel based on the currently
-// selected file. It handles text files (via bat when available), directories,
-// and image files (with special handling for Kitty).
-func (m *Model) UpdatePreview() {
+// selected file. It handles text files (with built-in syntax highlighting),
+// directories, and image files (drawn with the detected image protocol).
+//
+// Text, directory and image previews are rendered in the background; the
+// returned command delivers the result as a previewMsg or imagePreviewMsg.
+// Cached previews are applied immediately. When the selection has changed,
+// the on_select Lua hook runs as well.
+func (m *Model) UpdatePreview() tea.Cmd {
+	cmd := m.updatePreview()
+	return tea.Batch(cmd, m.runSelectionHooks())
+}
+
+// updatePreview does the work of UpdatePreview, without the selection hook.
+func (m *Model) updatePreview() tea.Cmd {
 	// If there are no files, clear the preview.
 	if len(m.files) == 0 {
-		// If there is a pending image preview timer, cancel it.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
+		// Cancel any pending image preview and clear an active one from the
+		// terminal so we don't leave a stale image behind.
+		clearImage := m.cancelImagePreview()
 
-		// If an image preview was active, clear it from the terminal first so we
-		// don't leave a stale image behind.
-		if m.imagePreviewActive {
-			m.clearImagePreview()
-			m.imagePreviewActive = false
-		}
-
-		m.rightViewport.SetContent("")
+		m.cancelPreview()
+		m.setPreviewText("")
 		m.lastPreviewedPath = ""
-		return
+		return clearImage
 	}
 
-	idx := m.fileList.Index()
-	if idx < 0 || idx >= len(m.files) {
-		// If there is a pending image preview timer, cancel it.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
+	path, ok := m.selectedFilePath()
+	if !ok {
+		clearImage := m.cancelImagePreview()
 
-		// If an image preview was active, clear it from the terminal first.
-		if m.imagePreviewActive {
-			m.clearImagePreview()
-			m.imagePreviewActive = false
-		}
-
-		m.rightViewport.SetContent("")
+		m.cancelPreview()
+		m.setPreviewText("")
 		m.lastPreviewedPath = ""
-		return
+		return clearImage
 	}
+	fi := m.files[m.fileList.Index()]
+
+	// Tabs other than Content show details about the file rather than its
+	// contents, so any image preview is hidden while they are active. The
+	// Metadata tab reads image headers and EXIF data, so like the contents
+	// it is rendered in the background.
+	if m.previewTab != PreviewTabContent {
+		clearImage := m.cancelImagePreview()
 
-	fi := m.files[idx]
-	path := fi.Path
-	if path == "" {
-		path = filepath.Join(m.currentDir, fi.Name)
+		m.lastPreviewedPath = path
+		if m.previewTab == PreviewTabMetadata {
+			return tea.Batch(clearImage, m.sendPreviewRequest(previewRequest{
+				path:  path,
+				tab:   PreviewTabMetadata,
+				width: m.viewportWidth,
+				theme: m.theme,
+			}))
+		}
+		m.cancelPreview()
+		m.setPreviewText(m.renderPreviewTab(fi, path))
+		return clearImage
 	}
 
 	// When previews are disabled, always show simple file info/properties in
 	// the right-hand panel instead of rich text/image previews. This also
-	// avoids calling out to external tools like kitty icat or bat.
+	// avoids decoding and sending images to the terminal.
 	if !m.previewEnabled {
-		// Cancel any pending image preview timers and clear any active image.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
-		if m.imagePreviewActive {
-			m.clearImagePreview()
-			m.imagePreviewActive = false
-		}
+		clearImage := m.cancelImagePreview()
 
-		m.rightViewport.SetContent(renderFileInfoPanel(fi))
+		m.cancelPreview()
+		m.setPreviewText(renderFileInfoPanel(fi))
 		m.lastPreviewedPath = path
-		return
+		return clearImage
 	}
 
-	// When the selected file changes, cancel any pending image preview and hide
-	// any previously rendered image preview so we don't show a stale image
+	// Any pending image preview is for the previous state of the preview, and
+	// a previously rendered image is hidden so we don't show a stale image
 	// while the new preview is loading.
-	if path != m.lastPreviewedPath {
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
-		if m.imagePreviewActive {
-			m.clearImagePreview()
-			m.imagePreviewActive = false
+	clearImage := m.cancelImagePreview()
+
+	// Previewers from config.lua take precedence over the built-in ones.
+	if !fi.IsDir {
+		if ok, cmd := m.requestLuaPreview(fi, path); ok {
+			m.lastPreviewedPath = path
+			return tea.Batch(clearImage, cmd, m.runPreviewHooks(path))
 		}
 	}
 
+	var cmd tea.Cmd
+
 	switch {
 	case fi.IsDir:
-		// Cancel any pending image preview when switching to a directory.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
-
-		m.imagePreviewActive = false
-		content := m.previewDirectory(path)
-		m.rightViewport.SetContent(content)
+		cmd = m.requestPreview(path, true)
 	case isImageFile(path):
-		// Cancel any previous pending image preview; we'll schedule a new one
-		// for this path below.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
+		// Images are rendered separately, so drop any text preview that is
+		// still being generated.
+		m.cancelPreview()
 
 		// Skip previews for very large images to avoid blocking the terminal
-		// with slow or timing-out Kitty graphics operations.
+		// with slow or timing-out graphics operations.
 		if !canPreviewImage(path) {
-			if m.imagePreviewActive {
-				m.clearImagePreview()
-				m.imagePreviewActive = false
-			}
-			m.rightViewport.SetContent(
+			m.setPreviewText(
 				"Image too large to preview (limit ~20MiB).\nOpen the file directly if you want to view it.",
 			)
 			break
@@ -158,42 +152,23 @@ func (m *Model) UpdatePreview() {
 
 		// Clear textual content so the preview area appears empty while we wait
 		// for the debounced image preview to fire.
-		m.rightViewport.SetContent("")
-
-		// Mark the image preview as pending; the actual kitty icat invocation is
-		// performed after a short debounce delay, if the selection is still on
-		// this image.
-		m.imagePreviewActive = false
-		console.Log("UpdatePreview: scheduling image preview path=%s term=%s vw=%d vh=%d h=%d", path, m.terminalType, m.viewportWidth, m.viewportHeight, m.height)
-		m.scheduleImagePreview(path)
-	default:
-		// Cancel any pending image preview when switching to a non-image file.
-		if m.imagePreviewTimer != nil {
-			m.imagePreviewTimer.Stop()
-			m.imagePreviewTimer = nil
-			m.pendingImagePath = ""
-		}
+		m.setPreviewText("")
 
-		m.imagePreviewActive = false
-		if isTextFile(path) {
-			// Use the viewport height as a soft cap for the number of lines.
-			maxLines := m.viewportHeight
-			if maxLines <= 0 {
-				maxLines = 40
-			}
-			content := renderTextPreview(path, maxLines)
-			m.rightViewport.SetContent(content)
-		} else {
-			m.rightViewport.SetContent("No preview available for this file type.")
-		}
+		// The image is rendered after a short debounce delay, if the selection
+		// is still on this image.
+		console.Log("UpdatePreview: scheduling image preview path=%s protocol=%s vw=%d vh=%d h=%d", path, m.imageProtocol, m.viewportWidth, m.viewportHeight, m.height)
+		cmd = m.scheduleImagePreview(path)
+	default:
+		cmd = m.requestPreview(path, false)
 	}
 
 	m.lastPreviewedPath = path
+	return tea.Batch(clearImage, cmd, m.runPreviewHooks(path))
 }
 
 // previewDirectory renders a directory listing similar to `ls -lh` using the
 // same formatting as the main file list.
-func (m *Model) previewDirectory(path string) string {
+func previewDirectory(ctx context.Context, path string, theme theming.Theme, columns []fileColumn, width int) string {
 	entries, err := filesystem.ListDirectory(path)
 	if err != nil {
 		return formatPreviewError("Error reading directory:\n" + err.Error())
@@ -203,10 +178,14 @@ func (m *Model) previewDirectory(path string) string {
 	}
 
 	// Reuse the file-list delegate so the preview matches list styling.
-	delegate := NewFileItemDelegate(m.theme, m.viewportWidth-2)
+	delegate := NewFileItemDelegate(theme, columns, entries, width-2, nil)
 
 	var b strings.Builder
 	for _, entry := range entries {
+		// Stop early if the cursor has already moved on.
+		if ctx.Err() != nil {
+			return ""
+		}
 		line := delegate.renderFileRow(entry, false)
 		b.WriteString(line)
 		b.WriteByte('\n')
@@ -254,51 +233,67 @@ func isTextFile(path string) bool {
 	return true
 }
 
-// renderTextPreview tries to use `bat` for syntax-highlighted previews, and
-// falls back to a simple line-based preview if bat is unavailable.
-func renderTextPreview(path string, maxLines int) string {
-	if maxLines <= 0 {
-		maxLines = 40
-	}
-
-	// Prefer bat if available.
-	if _, err := exec.LookPath("bat"); err == nil {
-		lineRange := fmt.Sprintf("1:%d", maxLines)
-		cmd := exec.Command("bat",
-			"--color=always",
-			"--style=plain",
-			"--paging=never",
-			"--line-range", lineRange,
-			path,
-		)
-		out, err := cmd.Output()
-		if err == nil {
-			return string(out)
-		}
-	}
-
-	// Fallback: read the first maxLines lines directly.
+// renderTextPreview reads one chunk of a text file starting at offset and
+// highlights it with the built-in syntax highlighter, using the theme's syntax
+// colors. Files whose language cannot be detected are shown as plain text.
+// Each chunk is highlighted on its own, so a construct spanning a chunk
+// boundary (such as a long block comment) may be colored imperfectly.
+func renderTextPreview(ctx context.Context, path string, offset int64, theme theming.Theme) previewContent {
 	f, err := os.Open(path)
 	if err != nil {
-		return formatPreviewError("Error opening file:\n" + err.Error())
+		return newPreviewContent(formatPreviewError("Error opening file:\n" + err.Error()))
 	}
 	defer f.Close()
 
-	scanner := bufio.NewScanner(f)
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return newPreviewContent(formatPreviewError("Error reading file:\n" + err.Error()))
+	}
+
+	// Lines longer than the reader's buffer are split rather than read in
+	// full, which keeps minified or single-line files bounded.
+	reader := bufio.NewReaderSize(f, textPreviewMaxLineBytes)
+
 	var b strings.Builder
+	next := offset
 	lineCount := 0
-	for scanner.Scan() {
-		b.WriteString(scanner.Text())
-		b.WriteByte('\n')
-		lineCount++
-		if lineCount >= maxLines {
+	more := true
+	for lineCount < textPreviewChunkLines && next-offset < textPreviewChunkBytes {
+		line, err := reader.ReadSlice('\n')
+		next += int64(len(line))
+		if len(line) > 0 {
+			b.Write(bytes.TrimRight(line, "\r\n"))
+			b.WriteByte('\n')
+			lineCount++
+		}
+		if err == io.EOF {
+			more = false
 			break
 		}
+		if err != nil && err != bufio.ErrBufferFull {
+			return newPreviewContent(formatPreviewError("Error reading file:\n" + err.Error()))
+		}
 	}
-	if err := scanner.Err(); err != nil {
-		return formatPreviewError("Error reading file:\n" + err.Error())
+
+	// Peek so a file ending exactly on a chunk boundary is not reported as
+	// having more content.
+	if more {
+		if _, err := reader.Peek(1); err != nil {
+			more = false
+		}
 	}
-	return b.String()
+
+	text := strings.TrimSuffix(b.String(), "\n")
+	content := previewContent{offset: next, more: more}
+
+	lexer := detectLexer(path)
+	if lexer != nil {
+		if highlighted, err := highlightSource(ctx, text, lexer, theme.Syntax); err == nil {
+			text = highlighted
+		}
+	}
+
+	content.lines = strings.Split(text, "\n")
+	return content
 }
 
 // formatPreviewError wraps an error message in a simple "modal-like" block
@@ -310,177 +305,6 @@ func formatPreviewError(msg string) string {
 	return "Error\n\n" + msg
 }
 
-// previewImage renders an image in the right viewport when running under
-// Kitty; for other terminals it falls back to a simple message.
-func (m *Model) previewImage(path string) {
-	if m.terminalType != string(TerminalKitty) {
-		m.rightViewport.SetContent("No image preview available in this terminal.\nImage previews are currently supported only in Kitty.")
-		return
-	}
-
-	// Generate a resized thumbnail of the image using libvips via bimg. This
-	// keeps the bytes sent through the Kitty graphics protocol reasonably
-	// small, which improves responsiveness when previewing large images.
-	thumbPath, err := createThumbnailVips(path, maxThumbnailWidth, maxThumbnailHeight)
-	if err != nil {
-		m.rightViewport.SetContent(formatPreviewError("Error preparing image for preview:\n" + err.Error()))
-		return
-	}
-
-	// Mark the image preview as active so the view layer can hide textual
-	// content and we know to clear the Kitty graphics layer when changing
-	// selection.
-	m.imagePreviewActive = true
-
-	// Clear textual content so the image is not obscured by colored cells.
-	m.rightViewport.SetContent("")
-
-	// Determine the cell rectangle for the right preview viewport. For Kitty's
-	// --place, coordinates are in terminal cells with origin at the top-left
-	// of the screen. We approximate the right panel as the right half of the
-	// terminal, aligned with the main viewport row.
-	widthCells := m.viewportWidth
-	if widthCells < 1 {
-		widthCells = 1
-	}
-	heightCells := m.viewportHeight
-	if heightCells < 1 {
-		heightCells = 1
-	}
-
-	// Layout assumptions from calc-layout.go:
-	//   - headerRows = 2
-	//   - statusRows = 2
-	//   - commandRows = 2
-	// The combined "viewports" row (file list + preview) starts after:
-	//   headerRows + 1 line for the search/tabs row.
-	const headerRows = 2
-	const searchAndTabsRows = 1
-
-	left := m.viewportWidth
-	top := headerRows + searchAndTabsRows
-
-	place := fmt.Sprintf("%dx%d@%dx%d", widthCells, heightCells, left, top)
-
-	// Run kitty icat as a background command, attached to our TTY, placing the
-	// image inside the right preview viewport rectangle. If icat fails, show
-	// the error text inside the right viewport instead of printing to the
-	// main terminal.
-	go func(imagePath, placeArg string, model *Model) {
-		// Ensure the temporary thumbnail is cleaned up when we're done.
-		defer os.Remove(imagePath)
-
-		console.Log("previewImage: starting kitty icat path=%s place=%s", imagePath, placeArg)
-		var stderr bytes.Buffer
-		cmd := exec.Command("kitty", "+kitten", "icat",
-			"--silent",
-			"--stdin=no",
-			"--place", placeArg,
-			imagePath,
-		)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = &stderr
-		if err := cmd.Run(); err != nil {
-			errMsg := strings.TrimSpace(stderr.String())
-			if errMsg == "" {
-				errMsg = err.Error()
-			}
-			console.Log("previewImage: kitty icat error: %s", errMsg)
-
-			// If the terminal reports that it does not support the Kitty
-			// graphics protocol (or repeatedly times out), disable image
-			// previews for the rest of this session so the TUI remains
-			// responsive and the layout stable.
-			if strings.Contains(errMsg, "does not support the graphics protocol") ||
-				strings.Contains(errMsg, "i/o timeout") {
-				model.terminalType = string(TerminalUnknown)
-				model.imagePreviewActive = false
-				model.rightViewport.SetContent(
-					"Image preview disabled because this terminal does not support\n" +
-						"the Kitty graphics protocol or is too slow to respond.\n\n" +
-						"Run cute-fm directly in Kitty/WezTerm/Konsole (without tmux)\n" +
-						"to enable graphical image previews.",
-				)
-				return
-			}
-
-			model.rightViewport.SetContent(formatPreviewError("Error rendering image:\n" + errMsg))
-		}
-	}(thumbPath, place, m)
-}
-
-// clearImagePreview clears any previously rendered image from the Kitty
-// graphics layer so that moving the file selection or toggling previews off
-// hides the old image preview immediately.
-func (m *Model) clearImagePreview() {
-	go func() {
-		console.Log("clearImagePreview: clearing kitty icat (global)")
-		var stderr bytes.Buffer
-		cmd := exec.Command("kitty", "+kitten", "icat",
-			"--silent",
-			"--stdin=no",
-			"--clear",
-		)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = &stderr
-		if err := cmd.Run(); err != nil {
-			errMsg := strings.TrimSpace(stderr.String())
-			if errMsg == "" {
-				errMsg = err.Error()
-			}
-			console.Log("clearImagePreview: kitty icat --clear error: %s", errMsg)
-		}
-	}()
-}
-
-// scheduleImagePreview sets up a debounced image preview for the given path.
-// If the cursor moves away from this image before the debounce completes, the
-// pending preview is cancelled.
-func (m *Model) scheduleImagePreview(path string) {
-	m.pendingImagePath = path
-
-	m.imagePreviewTimer = time.AfterFunc(imagePreviewDebounce, func() {
-		m.runDebouncedImagePreview(path)
-	})
-}
-
-// runDebouncedImagePreview is invoked after the debounce delay. It verifies
-// that the selection is still on the same image before actually calling
-// previewImage.
-func (m *Model) runDebouncedImagePreview(path string) {
-	// Clear the timer reference so future calls can schedule new previews.
-	m.imagePreviewTimer = nil
-
-	// If the pending path has changed, the user moved the selection; skip.
-	if m.pendingImagePath != "" && m.pendingImagePath != path {
-		return
-	}
-
-	// Ensure we still have files and a valid selection.
-	if len(m.files) == 0 {
-		return
-	}
-
-	idx := m.fileList.Index()
-	if idx < 0 || idx >= len(m.files) {
-		return
-	}
-
-	fi := m.files[idx]
-	currentPath := fi.Path
-	if currentPath == "" {
-		currentPath = filepath.Join(m.currentDir, fi.Name)
-	}
-
-	// If the selection moved to a different file, don't render this image.
-	if currentPath != path {
-		return
-	}
-
-	// Finally, render the image preview.
-	m.previewImage(path)
-}
-
 // canPreviewImage returns true if the given file is small enough to be safely
 // previewed as an image without likely causing terminal slowdowns.
 func canPreviewImage(path string) bool {
@@ -565,46 +389,50 @@ func renderFileInfoPanel(fi filesystem.FileInfo) string {
 	return b.String()
 }
 
-// createThumbnailVips uses libvips via the bimg library to generate a resized
-// thumbnail for the given image. The resulting thumbnail is written to a
-// temporary file whose path is returned.
-func createThumbnailVips(srcPath string, maxW, maxH int) (string, error) {
-	if maxW <= 0 {
-		maxW = maxThumbnailWidth
+// createThumbnailVips returns a PNG thumbnail of the given image that fits
+// within maxSize pixels. Thumbnails are shared with other applications
+// through the freedesktop thumbnail cache: a valid cached thumbnail is used
+// as is, otherwise one is generated with libvips via the bimg library and
+// stored in the cache.
+func createThumbnailVips(srcPath string, maxSize int) ([]byte, error) {
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return nil, err
 	}
-	if maxH <= 0 {
-		maxH = maxThumbnailHeight
+
+	size := filesystem.ThumbnailSizeFor(maxSize)
+	if thumb, ok := filesystem.LookupThumbnail(srcPath, info, size); ok {
+		return thumb, nil
 	}
 
 	data, err := os.ReadFile(srcPath)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	image := bimg.NewImage(data)
 
+	// Setting both dimensions fits the image inside the square without
+	// cropping it.
 	options := bimg.Options{
-		Width:   maxW,
-		Height:  maxH,
+		Width:   size.Pixels,
+		Height:  size.Pixels,
 		Enlarge: false,
-		Quality: 80,
-		Type:    bimg.JPEG,
+		Type:    bimg.PNG,
 	}
 
 	thumb, err := image.Process(options)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	tmpFile, err := os.CreateTemp("", "cute-fm-thumb-*.jpg")
+	// A cache that cannot be written to only costs speed.
+	saved, err := filesystem.SaveThumbnail(srcPath, info, size, thumb)
 	if err != nil {
-		return "", err
+		console.Log("createThumbnailVips: caching thumbnail for %s: %v", srcPath, err)
 	}
-	defer tmpFile.Close()
-
-	if _, err := tmpFile.Write(thumb); err != nil {
-		return "", err
+	if saved != nil {
+		thumb = saved
 	}
-
-	return tmpFile.Name(), nil
+	return thumb, nil
 }
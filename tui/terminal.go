@@ -2,6 +2,7 @@ package tui
 
 import (
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -10,11 +11,14 @@ type TerminalType string
 const (
 	TerminalUnknown TerminalType = ""
 	TerminalKitty   TerminalType = "kitty"
+	TerminalITerm2  TerminalType = "iterm2"
+	TerminalWezTerm TerminalType = "wezterm"
 )
 
 // detectTerminalType inspects environment variables to determine the
-// current terminal. For now we only care about Kitty, but this can be
-// extended in the future.
+// current terminal. Only terminals whose image support cannot be discovered
+// with a query are recognised here; everything else is probed at startup
+// (see imageProtocolQueries).
 func detectTerminalType() TerminalType {
 	// Kitty sets KITTY_WINDOW_ID and usually TERM=xterm-kitty.
 	if os.Getenv("KITTY_WINDOW_ID") != "" {
@@ -23,5 +27,80 @@ func detectTerminalType() TerminalType {
 	if strings.Contains(strings.ToLower(os.Getenv("TERM")), "kitty") {
 		return TerminalKitty
 	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app":
+		return TerminalITerm2
+	case "WezTerm":
+		return TerminalWezTerm
+	}
 	return TerminalUnknown
 }
+
+// ImageProtocol selects how image previews are drawn.
+type ImageProtocol string
+
+const (
+	// ImageProtocolAuto picks a protocol from the terminal type and the
+	// answers to the startup queries.
+	ImageProtocolAuto ImageProtocol = "auto"
+
+	ImageProtocolKitty  ImageProtocol = "kitty"
+	ImageProtocolSixel  ImageProtocol = "sixel"
+	ImageProtocolITerm2 ImageProtocol = "iterm2"
+
+	// ImageProtocolHalfBlocks and ImageProtocolBraille draw the image with
+	// colored Unicode characters, which works in any truecolor terminal.
+	ImageProtocolHalfBlocks ImageProtocol = "halfblocks"
+	ImageProtocolBraille    ImageProtocol = "braille"
+
+	// ImageProtocolNone disables image previews.
+	ImageProtocolNone ImageProtocol = "none"
+)
+
+// ImageProtocols lists the accepted values of the image_preview setting.
+var ImageProtocols = []ImageProtocol{
+	ImageProtocolAuto,
+	ImageProtocolKitty,
+	ImageProtocolSixel,
+	ImageProtocolITerm2,
+	ImageProtocolHalfBlocks,
+	ImageProtocolBraille,
+	ImageProtocolNone,
+}
+
+// parseImageProtocol converts the image_preview setting into an
+// ImageProtocol. Empty and unrecognised values mean auto.
+func parseImageProtocol(s string) ImageProtocol {
+	p := ImageProtocol(strings.ToLower(strings.TrimSpace(s)))
+	if slices.Contains(ImageProtocols, p) {
+		return p
+	}
+	return ImageProtocolAuto
+}
+
+// detectImageProtocol returns the protocol to use before any query has been
+// answered. Terminals that are not recognised start with half blocks, which
+// is upgraded to Sixel if the terminal reports Sixel support.
+func detectImageProtocol(term TerminalType) ImageProtocol {
+	switch term {
+	case TerminalKitty:
+		return ImageProtocolKitty
+	case TerminalITerm2, TerminalWezTerm:
+		return ImageProtocolITerm2
+	default:
+		return ImageProtocolHalfBlocks
+	}
+}
+
+// usesTerminalGraphics reports whether the protocol draws the image with
+// escape sequences outside the normal rendering, as opposed to text lines in
+// the preview viewport.
+func (p ImageProtocol) usesTerminalGraphics() bool {
+	switch p {
+	case ImageProtocolKitty, ImageProtocolSixel, ImageProtocolITerm2:
+		return true
+	default:
+		return false
+	}
+}
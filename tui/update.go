@@ -5,8 +5,10 @@ import (
 	"strings"
 
 	tea "charm.land/bubbletea/v2"
+	uv "github.com/charmbracelet/ultraviolet"
 
 	"cute/command"
+	"cute/config"
 	"cute/filesystem"
 )
 
@@ -17,8 +19,17 @@ func SetQuitMode() {
 	}
 }
 
-// Update handles messages and updates the model
+// Update handles messages and updates the model, then computes the custom
+// column values the file list is missing.
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := m.update(msg)
+	if m, ok := model.(Model); ok {
+		return m, tea.Batch(cmd, m.fillColumnValues())
+	}
+	return model, cmd
+}
+
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
 		// Handle window resize
@@ -36,9 +47,67 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		m.CalcLayout()
 
+		// The preview depends on the viewport size, so re-render it. This also
+		// produces the initial preview once the terminal size is known.
+		return m, m.UpdatePreview()
+
+	case previewMsg:
+		return m, m.handlePreviewMsg(msg)
+
+	case imagePreviewTickMsg:
+		return m, m.handleImagePreviewTick(msg)
+
+	case imagePreviewMsg:
+		return m, m.handleImagePreviewMsg(msg)
+
+	case uv.KittyGraphicsEvent:
+		return m, m.handleKittyGraphicsReply(msg)
+
+	case uv.PrimaryDeviceAttributesEvent:
+		return m, m.handleDeviceAttributes(msg)
+
+	case uv.CellSizeEvent:
+		return m, m.handleCellSize(msg)
+
+	case hookResultMsg:
+		// Hook results may change directory or run Lua again, so they wait
+		// for a running command.
+		if m.luaRunning() {
+			m.luaRun.hookResults = append(m.luaRun.hookResults, msg)
+			return m, nil
+		}
+		return m, m.handleHookResult(msg)
+
+	case luaDoneMsg:
+		return m, m.handleLuaDone(msg)
+
+	case jobEventMsg:
+		return m, m.handleJobEvent(msg)
+
+	case luaRunNoticeMsg:
+		return m, m.handleLuaRunNotice(msg)
+
+	case columnValuesMsg:
+		// fillColumnValues runs after every update.
+		return m, nil
+
+	case notificationExpiredMsg:
+		m.handleNotificationExpired(msg)
+		return m, nil
+
+	case configWatchMsg:
+		return m, m.handleConfigWatch()
+
+	case configErrorsMsg:
+		m.handleConfigErrors()
 		return m, nil
 
 	case tea.KeyMsg:
+		msg, cmd := m.resolveKey(msg)
+		if msg == nil {
+			return m, cmd
+		}
+
 		if ActiveTuiMode == TuiModeQuit {
 			return m.QuitMode(msg)
 		}
@@ -70,18 +139,56 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if ActiveTuiMode == TuiModeMkdir {
 			return m.MkdirMode(msg)
 		}
+
+		if ActiveTuiMode == TuiModePreview {
+			return m.PreviewMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModePreviewSearch {
+			return m.PreviewSearchMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModeOwner {
+			return m.OwnerMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModePrompt {
+			return m.PromptMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModeConfigErrors {
+			return m.ConfigErrorsMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModeRunning {
+			return m.RunningMode(msg)
+		}
+
+		if ActiveTuiMode == TuiModeThemes {
+			return m.ThemesMode(msg)
+		}
 	}
 
 	return m, nil
 }
 
 func (m *Model) ExecuteCommand(line string) (command.Result, error) {
+	m.recordCommand(line)
+	return command.Execute(m.commandEnvironment(), line)
+}
+
+// recordCommand adds a command line to the history.
+func (m *Model) recordCommand(line string) {
 	if line != "" {
 		m.AppendCommandHistory(line)
 		// Reload history to include the new command
 		m.commandHistory = m.LoadCommandHistory()
 	}
+}
 
+// commandEnvironment describes the current directory, selection and file list
+// to commands.
+func (m *Model) commandEnvironment() command.Environment {
 	var selected *command.SelectedEntry
 	selectedIdx := m.fileList.Index()
 	if selectedIdx >= 0 && selectedIdx < len(m.files) {
@@ -94,26 +201,24 @@ func (m *Model) ExecuteCommand(line string) (command.Result, error) {
 		}
 	}
 
-	env := command.Environment{
+	return command.Environment{
 		Cwd:      m.currentDir,
 		Config:   m.runtimeConfig,
 		Selected: selected,
+		Files:    m.files,
 	}
-
-	res, err := command.Execute(env, line)
-
-	return res, err
 }
 
 // ApplyFilter recomputes the visible file list based on the current value of
 // the text input. The filter is a case-insensitive substring match on the file
-// name. When the filter changes, the list is updated with the new items.
-func (m *Model) ApplyFilter() {
+// name. When the filter changes, the list is updated with the new items. The
+// returned command renders the preview for the new selection.
+func (m *Model) ApplyFilter() tea.Cmd {
 	query := strings.TrimSpace(m.searchInput.Value())
 
 	// If there is no backing data yet, nothing to do.
 	if len(m.allFiles) == 0 {
-		return
+		return nil
 	}
 
 	base := filterByViewMode(m.allFiles)
@@ -142,22 +247,30 @@ func (m *Model) ApplyFilter() {
 	}
 
 	// Update preview for the new selection after filtering.
-	m.UpdatePreview()
+	return m.UpdatePreview()
 }
 
 // ChangeDirectory updates the model to point at a new current directory and
-// reloads the file list.
-func (m *Model) ChangeDirectory(dir string) {
+// reloads the file list. The returned command renders the preview for the new
+// selection.
+func (m *Model) ChangeDirectory(dir string) tea.Cmd {
 	files, err := filesystem.ListDirectory(dir)
 	if err != nil {
-		m.rightViewport.SetContent("Error reading directory:\n" + err.Error())
-		return
+		m.cancelPreview()
+		m.setPreviewText("Error reading directory:\n" + err.Error())
+		return nil
 	}
 
+	changed := dir != m.currentDir
 	m.currentDir = dir
 	m.allFiles = files
 	m.files = files
 
+	// Custom column values are computed again, which also picks up changes
+	// on refresh, and the columns are fitted to the new files.
+	m.columnValues = newColumnValues(m.runtimeConfig, dir)
+	m.UpdateFileListDelegate(m.fileList.Width())
+
 	// Update the list with new items.
 	items := FileInfosToItems(files)
 	m.fileList.SetItems(items)
@@ -167,11 +280,19 @@ func (m *Model) ChangeDirectory(dir string) {
 		m.fileList.Select(0)
 	}
 
-	// Re-apply search/view filters for the new directory.
-	m.ApplyFilter()
+	var hook tea.Cmd
+	if changed {
+		hook = m.runHook(config.HookCd)
+	}
+
+	// Re-apply search/view filters for the new directory. ApplyFilter leaves
+	// the preview alone for an empty directory, so fall back to UpdatePreview.
+	if cmd := m.ApplyFilter(); cmd != nil {
+		return tea.Batch(hook, cmd)
+	}
 
 	// And recompute the preview for the new directory/selection.
-	m.UpdatePreview()
+	return tea.Batch(hook, m.UpdatePreview())
 }
 
 // filterByViewMode filters the given file list according to the current view
@@ -48,6 +48,10 @@ func (m Model) View() tea.View {
 			Height: 1,
 		})
 
+	notification := m.Notification(m, ComponentArgs{
+		Height: 1,
+	})
+
 	statusBar := m.StatusBar(
 		m, ComponentArgs{
 			Width:  m.width,
@@ -56,6 +60,7 @@ func (m Model) View() tea.View {
 		tuiMode,
 		viewModeText,
 		currentDir,
+		notification,
 	)
 
 	filePanelRows := []string{
@@ -113,6 +118,26 @@ func (m Model) View() tea.View {
 		})
 		canvas = lipgloss.NewCanvas(baseLayer, commandLayer)
 
+	case TuiModePreviewSearch:
+		commandLayer := m.CommandModal(m, CommandModalArgs{
+			Title:       "Search Preview",
+			Placeholder: "Enter search text...",
+		})
+		canvas = lipgloss.NewCanvas(baseLayer, commandLayer)
+
+	case TuiModeOwner:
+		commandLayer := m.CommandModal(m, CommandModalArgs{
+			Title:       "Change Owner",
+			Placeholder: "user:group",
+		})
+		canvas = lipgloss.NewCanvas(baseLayer, commandLayer)
+
+	case TuiModePrompt:
+		commandLayer := m.CommandModal(m, CommandModalArgs{
+			Title: m.GetPrompt(),
+		})
+		canvas = lipgloss.NewCanvas(baseLayer, commandLayer)
+
 	case TuiModeHelp:
 		modalLayer := m.HelpModal(m)
 		canvas = lipgloss.NewCanvas(baseLayer, modalLayer)
@@ -121,6 +146,14 @@ func (m Model) View() tea.View {
 		modalLayer := m.QuitModal(m)
 		canvas = lipgloss.NewCanvas(baseLayer, modalLayer)
 
+	case TuiModeConfigErrors:
+		modalLayer := m.ConfigErrorsModal(m)
+		canvas = lipgloss.NewCanvas(baseLayer, modalLayer)
+
+	case TuiModeThemes:
+		modalLayer := m.ThemePickerModal(m)
+		canvas = lipgloss.NewCanvas(baseLayer, modalLayer)
+
 	default:
 		canvas = lipgloss.NewCanvas(baseLayer)
 	}